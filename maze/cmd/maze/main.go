@@ -7,15 +7,19 @@ import (
 	"github.com/avanticaTest/maze/pkg/config"
 	"github.com/avanticaTest/maze/pkg/db"
 	"github.com/avanticaTest/maze/pkg/endpoints"
+	"github.com/avanticaTest/maze/pkg/index"
+	"github.com/avanticaTest/maze/pkg/models"
+	"github.com/avanticaTest/maze/pkg/service/maze"
 	"github.com/avanticaTest/maze/pkg/service/path"
 	"github.com/avanticaTest/maze/pkg/service/quadrant"
 	"github.com/avanticaTest/maze/pkg/service/spot"
+	"github.com/avanticaTest/maze/pkg/service/zone"
 	"github.com/go-kit/kit/log"
 	"net/http"
 
 	mazehttp "github.com/avanticaTest/maze/pkg/http"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"os"
 	"time"
@@ -23,28 +27,17 @@ import (
 
 func main() {
 	var (
-		consulAddr = flag.String("consul.addr", "localhost:8500", "Consul agent address")
+		consulAddr  = flag.String("consul.addr", "localhost:8500", "Consul agent address")
+		spotIndex   = flag.Bool("spots.index", false, "Serve spatial queries from an in-memory index of the spots")
+		storage     = flag.String("storage", "", "Where the maze is stored, overriding the config: mongo, memory or bolt, both running without MongoDB nor Consul")
+		storageFile = flag.String("storage.file", "", "File the bolt storage is kept in, maze.db by default")
+		spotDelete  = flag.String("spots.delete", "", "What happens to the paths of a deleted spot, overriding the config: restrict, cascade or detach")
 	)
 	flag.Parse()
 
 	fmt.Println("Starting the application...")
 	ctx, _ := context.WithTimeout(context.Background(), 15*time.Second)
 
-	conf := config.NewConfig("maze_config")
-	err := conf.Load(*consulAddr)
-	if err != nil {
-		panic(err)
-	}
-	connectionURI := conf.DBURI
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionURI))
-	if err != nil {
-		panic(err)
-	}
-	defer client.Disconnect(ctx)
-	err = client.Ping(ctx, readpref.Primary())
-	if err != nil {
-		panic(err)
-	}
 	// Prepare logging
 	var logger log.Logger
 	{
@@ -54,11 +47,147 @@ func main() {
 		logger = log.With(logger, "svc", "maze")
 	}
 
-	spot := spot.New(logger, db.New(client, logger))
-	path := path.New(logger, db.New(client, logger))
-	quadrant := quadrant.New(logger, db.New(client, logger))
+	// The storage can be chosen on the command line, so memory and bolt can run without Consul
+	conf := config.NewConfig("maze_config")
+	if *storage == "" || *storage == config.MongoStorage {
+		err := conf.Load(*consulAddr)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if *storage == "" {
+		*storage = conf.Storage
+	}
+	if *storageFile != "" {
+		conf.StorageFile = *storageFile
+	}
+	if *spotDelete != "" {
+		conf.SpotDeletion = *spotDelete
+	}
+	switch conf.SpotDeletion {
+	case "", models.RestrictDeletion, models.CascadeDeletion, models.DetachDeletion:
+	default:
+		panic("unknown spot deletion " + conf.SpotDeletion + ": it must be either restrict, cascade or detach")
+	}
+
+	var (
+		spots   db.SpotRepository
+		paths   db.PathRepository
+		origins db.OriginRepository
+		zones   db.ZoneRepository
+		mazes   db.MazeRepository
+		legacy  db.LegacyRepository
+		tx      db.Transactor
+	)
+	switch *storage {
+	case config.MemoryStorage:
+		// Nothing survives the process, which suits local runs and tests
+		store := db.NewMemoryStore()
+		spots = db.NewMemorySpotRepository(store)
+		paths = db.NewMemoryPathRepository(store)
+		origins = db.NewMemoryOriginRepository(store)
+		zones = db.NewMemoryZoneRepository(store)
+		mazes = db.NewMemoryMazeRepository(store)
+		legacy = db.NewMemoryLegacyRepository(store)
+		tx = db.NoTransactions()
+	case config.BoltStorage:
+		// A single file, for deployments of one instance without a database server
+		file := conf.StorageFile
+		if file == "" {
+			file = "maze.db"
+		}
+		store, err := db.OpenBoltStore(file)
+		if err != nil {
+			panic(err)
+		}
+		defer store.Close()
+		spots = db.NewBoltSpotRepository(store)
+		paths = db.NewBoltPathRepository(store)
+		origins = db.NewBoltOriginRepository(store)
+		zones = db.NewBoltZoneRepository(store)
+		mazes = db.NewBoltMazeRepository(store)
+		legacy = db.NewBoltLegacyRepository(store)
+		tx = db.NewBoltTransactor(store)
+	case "", config.MongoStorage:
+		settings, err := conf.Mongo()
+		if err != nil {
+			panic(err)
+		}
+		opts, err := settings.ClientOptions()
+		if err != nil {
+			panic(err)
+		}
+		client, err := mongo.Connect(ctx, opts)
+		if err != nil {
+			panic(err)
+		}
+		defer client.Disconnect(ctx)
+		err = client.Ping(ctx, readpref.Primary())
+		if err != nil {
+			panic(err)
+		}
+
+		manager := db.New(client, logger)
+		spots = db.NewSpotRepository(manager, settings.Database, settings.Collections.Spots)
+		paths = db.NewPathRepository(manager, settings.Database, settings.Collections.Paths)
+		origins = db.NewOriginRepository(manager, settings.Database, settings.Collections.Origin)
+		zones = db.NewZoneRepository(manager, settings.Database, settings.Collections.Zones)
+		mazes = db.NewMazeRepository(manager, settings.Database, settings.Collections.Mazes)
+		legacy = db.NewLegacyRepository(manager, settings.Database, settings.Collections)
+		// Multi-step operations run in transactions when the server is a replica set or a sharded cluster
+		tx, err = db.NewMongoTransactor(ctx, client)
+		if err != nil {
+			panic(err)
+		}
+
+		// Compound indexes on the maze and coordinates let the database serve the box, radius, zone and quadrant filters
+		for _, keys := range []bson.D{
+			{{Key: "maze_id", Value: 1}, {Key: "x_coordinate", Value: 1}, {Key: "y_coordinate", Value: 1}},
+			{{Key: "maze_id", Value: 1}, {Key: "y_coordinate", Value: 1}, {Key: "x_coordinate", Value: 1}},
+		} {
+			if _, err := manager.CreateIndex(ctx, settings.Database, settings.Collections.Spots, keys); err != nil {
+				panic(err)
+			}
+		}
+		// No two paths of a maze may join the same spots, the ones missing an end aside
+		ends := bson.D{{Key: "maze_id", Value: 1}, {Key: "ends", Value: 1}}
+		partial := bson.M{"ends": bson.M{"$exists": true}}
+		if _, err := manager.CreateUniqueIndex(ctx, settings.Database, settings.Collections.Paths, ends, partial); err != nil {
+			panic(err)
+		}
+	default:
+		panic("unknown storage " + *storage + ": it must be either mongo, memory or bolt")
+	}
+
+	// What was stored before there were mazes is moved into a maze of its own, so it's still reachable
+	adopted, err := maze.Adopt(ctx, legacy, mazes, tx)
+	if err != nil {
+		panic(err)
+	}
+	if adopted != "" {
+		logger.Log("msg", "documents stored before mazes adopted", "maze", adopted)
+	}
+
+	// The spot index lives in this process, so it only stays in sync while this is the only instance writing spots.
+	// Every maze gets an index of its own
+	var ix *index.Mazes
+	if *spotIndex {
+		all, err := spots.List(ctx)
+		if err != nil {
+			panic(err)
+		}
+		ix = index.NewMazes()
+		ix.Load(all)
+		logger.Log("msg", "spots indexed", "count", ix.Len())
+	}
+
+	maze := maze.New(logger, mazes, origins, spots, paths, zones, ix, tx)
+	zone := zone.New(logger, zones, spots, origins, ix, maze)
+	spot := spot.New(logger, spots, paths, conf.SpotDeletion, zone, ix, maze, tx)
+	path := path.New(logger, paths, spots, maze, tx)
+	quadrant := quadrant.New(logger, origins, spots, zone, ix, maze, tx)
 
-	eps := endpoints.New(spot, path, quadrant, logger)
+	eps := endpoints.New(spot, path, quadrant, zone, maze, logger)
 	handler := mazehttp.NewHTTPHandler(eps, logger)
 
 	http.ListenAndServe(":8080", handler)
@@ -8,6 +8,7 @@ require (
 	github.com/hashicorp/consul/api v1.3.0
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.6.1
+	go.etcd.io/bbolt v1.3.6
 	go.mongodb.org/mongo-driver v1.4.3
 	gotest.tools/v3 v3.0.3
 )
@@ -0,0 +1,220 @@
+package cluster
+
+import (
+	mazeerrors "github.com/avanticaTest/maze/pkg/errors"
+	"github.com/avanticaTest/maze/pkg/index"
+	"github.com/avanticaTest/maze/pkg/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"math/rand"
+)
+
+//Noise is the label of the spots DBSCAN leaves out of every cluster
+const Noise = -1
+
+//MaxIterations bounds the refinement rounds of k-means
+const MaxIterations = 100
+
+//KMeans splits the spots into k clusters around their centroids. The initial centroids are picked with k-means++ from
+//a fixed seed, so the same spots always give the same clusters. It returns the label of every spot, in order
+func KMeans(spots []models.Spot, k int) (labels []int, centroids []models.Point, err error) {
+	if k < 1 {
+		return nil, nil, mazeerrors.NewValidation("Invalid k: it must be at least one")
+	}
+	if k > len(spots) {
+		return nil, nil, mazeerrors.NewValidation("Invalid k: there are fewer spots than clusters")
+	}
+
+	r := rand.New(rand.NewSource(1))
+	centroids = seed(spots, k, r)
+	labels = make([]int, len(spots))
+	for i := range labels {
+		labels[i] = -1
+	}
+
+	for iteration := 0; iteration < MaxIterations; iteration++ {
+		changed := false
+		for i, v := range spots {
+			closest := nearestCentroid(v, centroids)
+			if closest != labels[i] {
+				labels[i] = closest
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		sums := make([]models.Point, k)
+		counts := make([]int, k)
+		for i, v := range spots {
+			sums[labels[i]].X += v.XCoordinate
+			sums[labels[i]].Y += v.YCoordinate
+			counts[labels[i]]++
+		}
+		for c := range centroids {
+			if counts[c] == 0 {
+				//an emptied cluster takes over the spot farthest from its centroid
+				far := farthest(spots, labels, centroids)
+				centroids[c] = models.Point{X: spots[far].XCoordinate, Y: spots[far].YCoordinate}
+				continue
+			}
+			centroids[c] = models.Point{X: sums[c].X / float64(counts[c]), Y: sums[c].Y / float64(counts[c])}
+		}
+	}
+
+	return labels, centroids, nil
+}
+
+//DBSCAN groups the spots having at least minPoints spots (themselves included) within eps, along with the spots
+//reachable from them. The rest are labelled as Noise
+func DBSCAN(spots []models.Spot, eps float64, minPoints int) (labels []int, err error) {
+	if !(eps > 0) {
+		return nil, mazeerrors.NewValidation("Invalid eps: it must be positive")
+	}
+	if minPoints < 1 {
+		return nil, mazeerrors.NewValidation("Invalid min points: it must be at least one")
+	}
+
+	//the neighbourhood queries go through a k-d tree, positions in the spots slice being used as IDs
+	ix := index.New()
+	positions := make(map[primitive.ObjectID]int, len(spots))
+	keyed := make([]models.Spot, len(spots))
+	for i, v := range spots {
+		keyed[i] = v
+		keyed[i].ID = positionID(i)
+		positions[keyed[i].ID] = i
+	}
+	ix.Load(keyed)
+	neighbours := func(i int) []int {
+		var result []int
+		for _, v := range ix.InRadius(keyed[i].XCoordinate, keyed[i].YCoordinate, eps) {
+			result = append(result, positions[v.ID])
+		}
+		return result
+	}
+
+	const unvisited = -2
+	labels = make([]int, len(spots))
+	for i := range labels {
+		labels[i] = unvisited
+	}
+	cluster := 0
+	for i := range spots {
+		if labels[i] != unvisited {
+			continue
+		}
+		seeds := neighbours(i)
+		if len(seeds) < minPoints {
+			labels[i] = Noise
+			continue
+		}
+		labels[i] = cluster
+		for j := 0; j < len(seeds); j++ {
+			n := seeds[j]
+			if labels[n] == Noise {
+				//a border spot: reachable, but not dense enough to expand the cluster
+				labels[n] = cluster
+			}
+			if labels[n] != unvisited {
+				continue
+			}
+			labels[n] = cluster
+			if more := neighbours(n); len(more) >= minPoints {
+				seeds = append(seeds, more...)
+			}
+		}
+		cluster++
+	}
+
+	return labels, nil
+}
+
+//Centroids returns the centroid of every cluster, noise excluded
+func Centroids(spots []models.Spot, labels []int) []models.Point {
+	clusters := 0
+	for _, l := range labels {
+		if l+1 > clusters {
+			clusters = l + 1
+		}
+	}
+	sums := make([]models.Point, clusters)
+	counts := make([]int, clusters)
+	for i, l := range labels {
+		if l == Noise {
+			continue
+		}
+		sums[l].X += spots[i].XCoordinate
+		sums[l].Y += spots[i].YCoordinate
+		counts[l]++
+	}
+	for c := range sums {
+		sums[c].X /= float64(counts[c])
+		sums[c].Y /= float64(counts[c])
+	}
+	return sums
+}
+
+//seed picks the initial centroids with k-means++: each one is drawn with a probability proportional to the squared
+//distance to the closest centroid already picked
+func seed(spots []models.Spot, k int, r *rand.Rand) []models.Point {
+	first := spots[r.Intn(len(spots))]
+	centroids := []models.Point{{X: first.XCoordinate, Y: first.YCoordinate}}
+	weights := make([]float64, len(spots))
+	for len(centroids) < k {
+		total := 0.0
+		for i, v := range spots {
+			d := distance(v, centroids[nearestCentroid(v, centroids)])
+			weights[i] = d * d
+			total += weights[i]
+		}
+		pick := len(spots) - 1
+		target := r.Float64() * total
+		for i, w := range weights {
+			if target < w {
+				pick = i
+				break
+			}
+			target -= w
+		}
+		//every spot sits on a centroid already, duplicate coordinates will make some clusters empty
+		if total == 0 {
+			pick = len(centroids) % len(spots)
+		}
+		centroids = append(centroids, models.Point{X: spots[pick].XCoordinate, Y: spots[pick].YCoordinate})
+	}
+	return centroids
+}
+
+func nearestCentroid(spot models.Spot, centroids []models.Point) int {
+	best, bestDistance := 0, math.Inf(1)
+	for c, p := range centroids {
+		if d := distance(spot, p); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+func farthest(spots []models.Spot, labels []int, centroids []models.Point) int {
+	best, bestDistance := 0, -1.0
+	for i, v := range spots {
+		if d := distance(v, centroids[labels[i]]); d > bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return best
+}
+
+func distance(spot models.Spot, p models.Point) float64 {
+	return math.Hypot(spot.XCoordinate-p.X, spot.YCoordinate-p.Y)
+}
+
+//positionID builds an ObjectID out of a position in a slice
+func positionID(i int) primitive.ObjectID {
+	var id primitive.ObjectID
+	for b := 0; b < 8; b++ {
+		id[11-b] = byte(i >> (8 * uint(b)))
+	}
+	return id
+}
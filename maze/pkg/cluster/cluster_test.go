@@ -0,0 +1,44 @@
+package cluster
+
+import (
+	"github.com/avanticaTest/maze/pkg/models"
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+//two tight groups far from each other, plus a lonely spot
+var spots = []models.Spot{
+	{XCoordinate: 0, YCoordinate: 0},
+	{XCoordinate: 1, YCoordinate: 0},
+	{XCoordinate: 0, YCoordinate: 1},
+	{XCoordinate: 100, YCoordinate: 100},
+	{XCoordinate: 101, YCoordinate: 100},
+	{XCoordinate: 100, YCoordinate: 101},
+	{XCoordinate: 50, YCoordinate: -60},
+}
+
+func TestKMeans(t *testing.T) {
+
+	labels, centroids, err := KMeans(spots[:6], 2)
+
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(centroids))
+	assert.Equal(t, labels[0], labels[1])
+	assert.Equal(t, labels[0], labels[2])
+	assert.Equal(t, labels[3], labels[4])
+	assert.Equal(t, labels[3], labels[5])
+	assert.Assert(t, labels[0] != labels[3])
+	assert.DeepEqual(t, models.Point{X: 1.0 / 3, Y: 1.0 / 3}, centroids[labels[0]])
+
+	_, _, err = KMeans(spots[:2], 3)
+	assert.Error(t, err, "Invalid k: there are fewer spots than clusters")
+}
+
+func TestDBSCAN(t *testing.T) {
+
+	labels, err := DBSCAN(spots, 1.5, 3)
+
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []int{0, 0, 0, 1, 1, 1, Noise}, labels)
+	assert.DeepEqual(t, []models.Point{{X: 1.0 / 3, Y: 1.0 / 3}, {X: 100 + 1.0/3, Y: 100 + 1.0/3}}, Centroids(spots, labels))
+}
@@ -2,13 +2,43 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/avanticaTest/maze/pkg/consul"
+	"github.com/avanticaTest/maze/pkg/db"
+	"time"
+)
+
+//The storages the maze can be kept in
+const (
+	MongoStorage  = "mongo"
+	MemoryStorage = "memory"
+	BoltStorage   = "bolt"
 )
 
 type Config struct {
 	KeyConfig string `json:"-"`
 	DBName    string `json:"db_name"`
 	DBURI     string `json:"db_URI"`
+	//Collections renames the MongoDB collections, the ones left empty keeping their default name
+	Collections db.Collections `json:"collections"`
+	//MinPoolSize and MaxPoolSize bound the connections kept open to MongoDB
+	MinPoolSize uint64 `json:"min_pool_size"`
+	MaxPoolSize uint64 `json:"max_pool_size"`
+	//The MongoDB timeouts are durations such as "10s"
+	ConnectTimeout         string `json:"connect_timeout"`
+	ServerSelectionTimeout string `json:"server_selection_timeout"`
+	SocketTimeout          string `json:"socket_timeout"`
+	//ReadConcern is either local, available, majority, linearizable or snapshot
+	ReadConcern string `json:"read_concern"`
+	//WriteConcern is either majority or the amount of members that must acknowledge a write, which WriteTimeout bounds
+	WriteConcern string `json:"write_concern"`
+	WriteTimeout string `json:"write_timeout"`
+	//Storage is where the maze is kept: mongo, the default, memory or bolt
+	Storage string `json:"storage"`
+	//StorageFile is the file the bolt storage is kept in
+	StorageFile string `json:"storage_file"`
+	//SpotDeletion is what happens to the paths of a deleted spot: restrict, the default, cascade or detach
+	SpotDeletion string `json:"spot_deletion"`
 }
 
 func NewConfig(keyConfig string) Config {
@@ -34,3 +64,42 @@ func (c *Config) Load(consulAddr string) error {
 
 	return nil
 }
+
+//Mongo returns the settings of the MongoDB storage, the driver's defaults applying to everything left out of the config
+func (c Config) Mongo() (db.MongoSettings, error) {
+
+	settings := db.MongoSettings{
+		URI:          c.DBURI,
+		Database:     c.DBName,
+		Collections:  c.Collections.WithDefaults(),
+		MinPoolSize:  c.MinPoolSize,
+		MaxPoolSize:  c.MaxPoolSize,
+		ReadConcern:  c.ReadConcern,
+		WriteConcern: c.WriteConcern,
+	}
+	if settings.Database == "" {
+		settings.Database = db.DefaultDatabase
+	}
+
+	for _, timeout := range []struct {
+		name  string
+		value string
+		into  *time.Duration
+	}{
+		{"connect_timeout", c.ConnectTimeout, &settings.ConnectTimeout},
+		{"server_selection_timeout", c.ServerSelectionTimeout, &settings.ServerSelectionTimeout},
+		{"socket_timeout", c.SocketTimeout, &settings.SocketTimeout},
+		{"write_timeout", c.WriteTimeout, &settings.WriteTimeout},
+	} {
+		if timeout.value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(timeout.value)
+		if err != nil {
+			return db.MongoSettings{}, fmt.Errorf("invalid %s: %v", timeout.name, err)
+		}
+		*timeout.into = d
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,840 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/avanticaTest/maze/pkg/models"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+//BoltStore keeps every collection in an embedded bbolt file, for single node deployments. Each collection is a
+//bucket of BSON documents keyed by their ObjectID, so IDs look the same as with MongoDB and the documents are read,
+//and filtered, as MongoDB would. Every change is a transaction of its own, unless it's made within the unit of work
+//of the store's Transactor. The documents of every maze share the buckets, each one being told apart by its maze_id
+type BoltStore struct {
+	db *bbolt.DB
+}
+
+//OpenBoltStore opens the store in the file, creating it when it doesn't exist. Only one process can have it open
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range []string{SpotsCollection, PathsCollection, OriginCollection, ZonesCollection, MazesCollection} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+//Close closes the file
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+type boltTxKey struct{}
+
+//boltTx is the transaction a unit of work runs in, along with the store it belongs to
+type boltTx struct {
+	store *BoltStore
+	tx    *bbolt.Tx
+}
+
+//current returns the transaction of the store the context runs in, if any
+func (s *BoltStore) current(ctx context.Context) *bbolt.Tx {
+	if v, ok := ctx.Value(boltTxKey{}).(boltTx); ok && v.store == s {
+		return v.tx
+	}
+	return nil
+}
+
+//update runs fn in the transaction of the context, or in a read-write transaction of its own
+func (s *BoltStore) update(ctx context.Context, fn func(tx *bbolt.Tx) error) error {
+	if tx := s.current(ctx); tx != nil {
+		return fn(tx)
+	}
+	return s.db.Update(fn)
+}
+
+//view runs fn in the transaction of the context, so it sees what the unit of work wrote, or in a read-only
+//transaction of its own
+func (s *BoltStore) view(ctx context.Context, fn func(tx *bbolt.Tx) error) error {
+	if tx := s.current(ctx); tx != nil {
+		return fn(tx)
+	}
+	return s.db.View(fn)
+}
+
+//insert stores a new document, giving it an ID when it has none. No other document of its maze may share the values
+//of the unique fields
+func (s *BoltStore) insert(ctx context.Context, collection string, id primitive.ObjectID, doc interface{}, unique ...string) (string, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	if id.IsZero() {
+		id = primitive.NewObjectID()
+		if raw, err = withID(raw, id); err != nil {
+			return "", err
+		}
+	}
+	err = s.update(ctx, func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(collection))
+		if b.Get(id[:]) != nil {
+			return ErrDuplicateID
+		}
+		if err := taken(b, id[:], raw, unique); err != nil {
+			return err
+		}
+		return b.Put(id[:], raw)
+	})
+	if err != nil {
+		return "", err
+	}
+	return id.Hex(), nil
+}
+
+//withID adds the ID to a document that has none
+func withID(raw []byte, id primitive.ObjectID) ([]byte, error) {
+	doc := bson.D{{Key: "_id", Value: id}}
+	var rest bson.D
+	if err := bson.Unmarshal(raw, &rest); err != nil {
+		return nil, err
+	}
+	return bson.Marshal(append(doc, rest...))
+}
+
+//errDuplicateKey is returned when a write is turned away by the unique fields, for the repositories to tell why
+var errDuplicateKey = errors.New("duplicate key")
+
+//taken returns errDuplicateKey when another document of the bucket, in the same maze, has the same values in the unique
+//fields. As with a partial index in MongoDB, the documents missing any of them aren't compared
+func taken(b *bbolt.Bucket, key, raw []byte, unique []string) error {
+	if len(unique) == 0 {
+		return nil
+	}
+	fields := append([]string{"maze_id"}, unique...)
+	values := make([]bson.RawValue, len(fields))
+	for i, field := range fields {
+		v, err := bson.Raw(raw).LookupErr(field)
+		if err != nil && field != "maze_id" {
+			return nil
+		}
+		values[i] = v
+	}
+	return b.ForEach(func(other, doc []byte) error {
+		if bytes.Equal(other, key) {
+			return nil
+		}
+		for i, field := range fields {
+			v, _ := bson.Raw(doc).LookupErr(field)
+			if v.Type != values[i].Type || !bytes.Equal(v.Value, values[i].Value) {
+				return nil
+			}
+		}
+		return errDuplicateKey
+	})
+}
+
+//visible tells whether the document can be seen with the context. Mazes are seen whatever the context
+func visible(ctx context.Context, collection string, raw []byte) bool {
+	if collection == MazesCollection {
+		return true
+	}
+	var maze primitive.ObjectID
+	if v, err := bson.Raw(raw).LookupErr("maze_id"); err == nil {
+		maze, _ = v.ObjectIDOK()
+	}
+	return within(ctx, maze)
+}
+
+//get decodes the document with the given key
+func (s *BoltStore) get(ctx context.Context, collection string, key []byte, out interface{}) error {
+	return s.view(ctx, func(tx *bbolt.Tx) error {
+		raw := tx.Bucket([]byte(collection)).Get(key)
+		if raw == nil || !visible(ctx, collection, raw) {
+			return mongo.ErrNoDocuments
+		}
+		return bson.Unmarshal(raw, out)
+	})
+}
+
+//find returns the documents matching the filter, in the order of their keys
+func (s *BoltStore) find(ctx context.Context, collection string, filter interface{}) ([][]byte, error) {
+	var result [][]byte
+	err := s.view(ctx, func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(collection)).ForEach(func(_, raw []byte) error {
+			var doc bson.M
+			if err := bson.Unmarshal(raw, &doc); err != nil {
+				return err
+			}
+			if matches(doc, filter) {
+				//the value is only valid during the transaction
+				result = append(result, append([]byte(nil), raw...))
+			}
+			return nil
+		})
+	})
+	return result, err
+}
+
+//modify changes the document with the given key in a single transaction. It returns 1 when the document changed and
+//0 when it doesn't exist or stays the same. As in insert, no other document of its maze may share the values of the
+//unique fields
+func (s *BoltStore) modify(ctx context.Context, collection string, key []byte, doc interface{}, change func(), unique ...string) (int, error) {
+	modified := 0
+	err := s.update(ctx, func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(collection))
+		raw := b.Get(key)
+		if raw == nil || !visible(ctx, collection, raw) {
+			return nil
+		}
+		if err := bson.Unmarshal(raw, doc); err != nil {
+			return err
+		}
+		change()
+		updated, err := bson.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(raw, updated) {
+			return nil
+		}
+		if err := taken(b, key, updated, unique); err != nil {
+			return err
+		}
+		modified = 1
+		return b.Put(key, updated)
+	})
+	return modified, err
+}
+
+//remove deletes the document with the given key
+func (s *BoltStore) remove(ctx context.Context, collection string, key []byte) (int, error) {
+	deleted := 0
+	err := s.update(ctx, func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(collection))
+		raw := b.Get(key)
+		if raw == nil || !visible(ctx, collection, raw) {
+			return nil
+		}
+		deleted = 1
+		return b.Delete(key)
+	})
+	return deleted, err
+}
+
+//removeMany deletes the documents matching the filter, in a single transaction
+func (s *BoltStore) removeMany(ctx context.Context, collection string, filter interface{}) (int, error) {
+	deleted := 0
+	err := s.update(ctx, func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(collection))
+		var keys [][]byte
+		err := b.ForEach(func(key, raw []byte) error {
+			var doc bson.M
+			if err := bson.Unmarshal(raw, &doc); err != nil {
+				return err
+			}
+			if matches(doc, filter) {
+				keys = append(keys, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := b.Delete(key); err != nil {
+				return err
+			}
+		}
+		deleted = len(keys)
+		return nil
+	})
+	return deleted, err
+}
+
+type boltSpotRepository struct {
+	store *BoltStore
+}
+
+//NewBoltSpotRepository creates the spot repository kept in the store
+func NewBoltSpotRepository(store *BoltStore) SpotRepository {
+	return boltSpotRepository{store: store}
+}
+
+//Create inserts the spot and returns its ID
+func (r boltSpotRepository) Create(ctx context.Context, spot models.Spot) (string, error) {
+	spot.MazeID = owner(ctx, spot.MazeID)
+	return r.store.insert(ctx, SpotsCollection, spot.ID, spot)
+}
+
+//GetByID returns the spot with the given ID
+func (r boltSpotRepository) GetByID(ctx context.Context, id primitive.ObjectID) (models.Spot, error) {
+	var spot models.Spot
+	if err := r.store.get(ctx, SpotsCollection, id[:], &spot); err != nil {
+		return models.Spot{}, err
+	}
+	return spot, nil
+}
+
+//GetByIDs returns the spots with the given IDs, leaving out the ones that don't exist
+func (r boltSpotRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Spot, error) {
+	return r.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+//List returns every spot
+func (r boltSpotRepository) List(ctx context.Context) ([]models.Spot, error) {
+	return r.Find(ctx, bson.M{})
+}
+
+//Find returns the spots matching the filter
+func (r boltSpotRepository) Find(ctx context.Context, filter interface{}) ([]models.Spot, error) {
+	var result []models.Spot
+	err := r.Stream(ctx, filter, func(spot models.Spot) error {
+		result = append(result, spot)
+		return nil
+	})
+	return result, err
+}
+
+//Stream hands the spots matching the filter to fn one at a time, stopping at the first error it returns. The spots
+//are read before fn is called, so it may change them
+func (r boltSpotRepository) Stream(ctx context.Context, filter interface{}, fn func(models.Spot) error) error {
+	found, err := r.store.find(ctx, SpotsCollection, scope(ctx, filter))
+	if err != nil {
+		return err
+	}
+	for _, raw := range found {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var spot models.Spot
+		if err := bson.Unmarshal(raw, &spot); err != nil {
+			return err
+		}
+		if err := fn(spot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Update replaces the spot's coordinates, name and number, returning whether anything changed
+func (r boltSpotRepository) Update(ctx context.Context, id primitive.ObjectID, spot models.Spot) (int, error) {
+	var updated models.Spot
+	return r.store.modify(ctx, SpotsCollection, id[:], &updated, func() {
+		updated.XCoordinate, updated.YCoordinate, updated.ZCoordinate = spot.XCoordinate, spot.YCoordinate, spot.ZCoordinate
+		updated.Name, updated.Number = spot.Name, spot.Number
+	})
+}
+
+//Delete deletes the spot with the given ID
+func (r boltSpotRepository) Delete(ctx context.Context, id primitive.ObjectID) (int, error) {
+	return r.store.remove(ctx, SpotsCollection, id[:])
+}
+
+//DeleteMany deletes the spots matching the filter, in a single transaction
+func (r boltSpotRepository) DeleteMany(ctx context.Context, filter interface{}) (int, error) {
+	return r.store.removeMany(ctx, SpotsCollection, scope(ctx, filter))
+}
+
+//ClearClusters removes the cluster label of every spot, in a single transaction
+func (r boltSpotRepository) ClearClusters(ctx context.Context) error {
+	return r.store.update(ctx, func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(SpotsCollection))
+		var labelled [][]byte
+		err := b.ForEach(func(key, raw []byte) error {
+			if _, err := bson.Raw(raw).LookupErr("cluster"); err == nil && visible(ctx, SpotsCollection, raw) {
+				labelled = append(labelled, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range labelled {
+			var spot models.Spot
+			if err := bson.Unmarshal(b.Get(key), &spot); err != nil {
+				return err
+			}
+			spot.Cluster = nil
+			raw, err := bson.Marshal(spot)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(key, raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//SetCluster labels the spots with the given IDs with the cluster, in a single transaction
+func (r boltSpotRepository) SetCluster(ctx context.Context, ids []primitive.ObjectID, label int) error {
+	return r.store.update(ctx, func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(SpotsCollection))
+		for _, id := range ids {
+			current := b.Get(id[:])
+			if current == nil || !visible(ctx, SpotsCollection, current) {
+				continue
+			}
+			var spot models.Spot
+			if err := bson.Unmarshal(current, &spot); err != nil {
+				return err
+			}
+			spot.Cluster = &label
+			raw, err := bson.Marshal(spot)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(id[:], raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//Touch bumps the revision of the spots, in a single transaction
+func (r boltSpotRepository) Touch(ctx context.Context, ids ...primitive.ObjectID) (int, error) {
+	touched := 0
+	err := r.store.update(ctx, func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(SpotsCollection))
+		for _, id := range ids {
+			current := b.Get(id[:])
+			if current == nil || !visible(ctx, SpotsCollection, current) {
+				continue
+			}
+			var spot models.Spot
+			if err := bson.Unmarshal(current, &spot); err != nil {
+				return err
+			}
+			spot.Revision++
+			raw, err := bson.Marshal(spot)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(id[:], raw); err != nil {
+				return err
+			}
+			touched++
+		}
+		return nil
+	})
+	return touched, err
+}
+
+type boltPathRepository struct {
+	store *BoltStore
+}
+
+//NewBoltPathRepository creates the path repository kept in the store
+func NewBoltPathRepository(store *BoltStore) PathRepository {
+	return boltPathRepository{store: store}
+}
+
+//Create inserts the path and returns its ID
+func (r boltPathRepository) Create(ctx context.Context, path models.Path) (string, error) {
+	path.MazeID = owner(ctx, path.MazeID)
+	path.Ends = pathEnds(path)
+	id, err := r.store.insert(ctx, PathsCollection, path.ID, path, "ends")
+	if err == errDuplicateKey {
+		return "", ErrPathExists
+	}
+	return id, err
+}
+
+//GetByID returns the path with the given ID
+func (r boltPathRepository) GetByID(ctx context.Context, id primitive.ObjectID) (models.Path, error) {
+	var path models.Path
+	if err := r.store.get(ctx, PathsCollection, id[:], &path); err != nil {
+		return models.Path{}, err
+	}
+	return path, nil
+}
+
+//List returns every path
+func (r boltPathRepository) List(ctx context.Context) ([]models.Path, error) {
+	return r.Find(ctx, bson.M{})
+}
+
+//Find returns the paths matching the filter
+func (r boltPathRepository) Find(ctx context.Context, filter interface{}) ([]models.Path, error) {
+	found, err := r.store.find(ctx, PathsCollection, scope(ctx, filter))
+	if err != nil {
+		return nil, err
+	}
+	var result []models.Path
+	for _, raw := range found {
+		var path models.Path
+		if err := bson.Unmarshal(raw, &path); err != nil {
+			return nil, err
+		}
+		result = append(result, path)
+	}
+	return result, nil
+}
+
+//Update replaces the spots the path joins and its distance, returning whether anything changed
+func (r boltPathRepository) Update(ctx context.Context, id primitive.ObjectID, path models.Path) (int, error) {
+	var updated models.Path
+	modified, err := r.store.modify(ctx, PathsCollection, id[:], &updated, func() {
+		updated.PointA, updated.PointB, updated.Distance = path.PointA, path.PointB, path.Distance
+		updated.Ends = pathEnds(updated)
+	}, "ends")
+	if err == errDuplicateKey {
+		return 0, ErrPathExists
+	}
+	return modified, err
+}
+
+//Delete deletes the path with the given ID
+func (r boltPathRepository) Delete(ctx context.Context, id primitive.ObjectID) (int, error) {
+	return r.store.remove(ctx, PathsCollection, id[:])
+}
+
+//DeleteMany deletes the paths matching the filter, in a single transaction
+func (r boltPathRepository) DeleteMany(ctx context.Context, filter interface{}) (int, error) {
+	return r.store.removeMany(ctx, PathsCollection, scope(ctx, filter))
+}
+
+//Detach unsets the spot from the paths it ends, along with their distance, in a single transaction
+func (r boltPathRepository) Detach(ctx context.Context, spot primitive.ObjectID) (int, error) {
+	detached := 0
+	err := r.store.update(ctx, func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(PathsCollection))
+		var changed []models.Path
+		err := b.ForEach(func(_, raw []byte) error {
+			if !visible(ctx, PathsCollection, raw) {
+				return nil
+			}
+			var path models.Path
+			if err := bson.Unmarshal(raw, &path); err != nil {
+				return err
+			}
+			ends := 0
+			if path.PointA == spot {
+				path.PointA = primitive.NilObjectID
+				ends++
+			}
+			if path.PointB == spot {
+				path.PointB = primitive.NilObjectID
+				ends++
+			}
+			if ends > 0 {
+				path.Distance, path.Ends = 0, ""
+				changed = append(changed, path)
+				detached += ends
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, path := range changed {
+			raw, err := bson.Marshal(path)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(path.ID[:], raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return detached, err
+}
+
+//SetDistances stores the distance of every path given, in a single transaction
+func (r boltPathRepository) SetDistances(ctx context.Context, distances map[primitive.ObjectID]float64) (int, error) {
+	modified := 0
+	err := r.store.update(ctx, func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(PathsCollection))
+		for id, distance := range distances {
+			current := b.Get(id[:])
+			if current == nil || !visible(ctx, PathsCollection, current) {
+				continue
+			}
+			var path models.Path
+			if err := bson.Unmarshal(current, &path); err != nil {
+				return err
+			}
+			if path.Distance == distance {
+				continue
+			}
+			path.Distance = distance
+			raw, err := bson.Marshal(path)
+			if err != nil {
+				return err
+			}
+			if err := b.Put(id[:], raw); err != nil {
+				return err
+			}
+			modified++
+		}
+		return nil
+	})
+	return modified, err
+}
+
+type boltOriginRepository struct {
+	store *BoltStore
+}
+
+//NewBoltOriginRepository creates the origin repository kept in the store
+func NewBoltOriginRepository(store *BoltStore) OriginRepository {
+	return boltOriginRepository{store: store}
+}
+
+//Count returns how many origins there are
+func (r boltOriginRepository) Count(ctx context.Context) (int, error) {
+	found, err := r.store.find(ctx, OriginCollection, scope(ctx, bson.M{}))
+	return len(found), err
+}
+
+//Create inserts the origin under the ID of its maze and returns it, unless the maze already has one. The check and the
+//insert share a single transaction
+func (r boltOriginRepository) Create(ctx context.Context, origin models.Origin) (string, error) {
+	origin.MazeID = owner(ctx, origin.MazeID)
+	key := originKey(origin)
+	raw, err := bson.Marshal(origin)
+	if err != nil {
+		return "", err
+	}
+	if raw, err = withID(raw, key); err != nil {
+		return "", err
+	}
+
+	filter := scope(ctx, bson.M{})
+	err = r.store.update(ctx, func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(OriginCollection))
+		if b.Get(key[:]) != nil {
+			return ErrOriginExists
+		}
+		err := b.ForEach(func(_, stored []byte) error {
+			var doc bson.M
+			if err := bson.Unmarshal(stored, &doc); err != nil {
+				return err
+			}
+			if matches(doc, filter) {
+				return ErrOriginExists
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return b.Put(key[:], raw)
+	})
+	if err != nil {
+		return "", err
+	}
+	return key.Hex(), nil
+}
+
+//List returns every origin stored
+func (r boltOriginRepository) List(ctx context.Context) ([]models.Origin, error) {
+	found, err := r.store.find(ctx, OriginCollection, scope(ctx, bson.M{}))
+	if err != nil {
+		return nil, err
+	}
+	var result []models.Origin
+	for _, raw := range found {
+		var origin models.Origin
+		if err := bson.Unmarshal(raw, &origin); err != nil {
+			return nil, err
+		}
+		result = append(result, origin)
+	}
+	return result, nil
+}
+
+//Update replaces the origin's coordinates, rotation and scales
+func (r boltOriginRepository) Update(ctx context.Context, origin models.Origin) (int, error) {
+	return r.replace(ctx, scope(ctx, bson.M{}), origin)
+}
+
+//Replace replaces the origin's coordinates, rotation and scales as long as they're still the current ones
+func (r boltOriginRepository) Replace(ctx context.Context, current, origin models.Origin) (int, error) {
+	return r.replace(ctx, scope(ctx, OriginFilter(current)), origin)
+}
+
+//replace updates the first origin matching the filter, in a single transaction
+func (r boltOriginRepository) replace(ctx context.Context, filter interface{}, origin models.Origin) (int, error) {
+	modified := 0
+	err := r.store.update(ctx, func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(OriginCollection))
+		var key []byte
+		err := b.ForEach(func(k, raw []byte) error {
+			var doc bson.M
+			if err := bson.Unmarshal(raw, &doc); err != nil {
+				return err
+			}
+			if key == nil && matches(doc, filter) {
+				key = append([]byte(nil), k...)
+			}
+			return nil
+		})
+		if err != nil || key == nil {
+			return err
+		}
+		var stored models.Origin
+		if err := bson.Unmarshal(b.Get(key), &stored); err != nil {
+			return err
+		}
+		updated := stored
+		origin.MazeID = stored.MazeID
+		updated.XOrigin, updated.YOrigin, updated.Rotation = origin.XOrigin, origin.YOrigin, origin.Rotation
+		updated.XScale, updated.YScale = origin.XScale, origin.YScale
+		if updated == stored {
+			return nil
+		}
+		raw, err := bson.Marshal(updated)
+		if err != nil {
+			return err
+		}
+		//the origin has no ID field of its own, it's kept along with the document as MongoDB does
+		var id primitive.ObjectID
+		copy(id[:], key)
+		if raw, err = withID(raw, id); err != nil {
+			return err
+		}
+		modified = 1
+		return b.Put(key, raw)
+	})
+	return modified, err
+}
+
+//Delete deletes the origin
+func (r boltOriginRepository) Delete(ctx context.Context) (int, error) {
+	return r.store.removeMany(ctx, OriginCollection, scope(ctx, bson.M{}))
+}
+
+type boltZoneRepository struct {
+	store *BoltStore
+}
+
+//NewBoltZoneRepository creates the zone repository kept in the store
+func NewBoltZoneRepository(store *BoltStore) ZoneRepository {
+	return boltZoneRepository{store: store}
+}
+
+//Create inserts the zone and returns its ID
+func (r boltZoneRepository) Create(ctx context.Context, zone models.Zone) (string, error) {
+	zone.MazeID = owner(ctx, zone.MazeID)
+	return r.store.insert(ctx, ZonesCollection, zone.ID, zone)
+}
+
+//GetByID returns the zone with the given ID
+func (r boltZoneRepository) GetByID(ctx context.Context, id primitive.ObjectID) (models.Zone, error) {
+	var zone models.Zone
+	if err := r.store.get(ctx, ZonesCollection, id[:], &zone); err != nil {
+		return models.Zone{}, err
+	}
+	return zone, nil
+}
+
+//List returns every zone stored
+func (r boltZoneRepository) List(ctx context.Context) ([]models.Zone, error) {
+	found, err := r.store.find(ctx, ZonesCollection, scope(ctx, bson.M{}))
+	if err != nil {
+		return nil, err
+	}
+	var result []models.Zone
+	for _, raw := range found {
+		var zone models.Zone
+		if err := bson.Unmarshal(raw, &zone); err != nil {
+			return nil, err
+		}
+		result = append(result, zone)
+	}
+	return result, nil
+}
+
+//Update replaces the zone's name, polygon and frame, returning whether anything changed
+func (r boltZoneRepository) Update(ctx context.Context, id primitive.ObjectID, zone models.Zone) (int, error) {
+	var updated models.Zone
+	return r.store.modify(ctx, ZonesCollection, id[:], &updated, func() {
+		updated.Name, updated.Polygon, updated.OriginRelative = zone.Name, zone.Polygon, zone.OriginRelative
+	})
+}
+
+//Delete deletes the zone with the given ID
+func (r boltZoneRepository) Delete(ctx context.Context, id primitive.ObjectID) (int, error) {
+	return r.store.remove(ctx, ZonesCollection, id[:])
+}
+
+//DeleteMany deletes the zones matching the filter, in a single transaction
+func (r boltZoneRepository) DeleteMany(ctx context.Context, filter interface{}) (int, error) {
+	return r.store.removeMany(ctx, ZonesCollection, scope(ctx, filter))
+}
+
+type boltMazeRepository struct {
+	store *BoltStore
+}
+
+//NewBoltMazeRepository creates the maze repository kept in the store
+func NewBoltMazeRepository(store *BoltStore) MazeRepository {
+	return boltMazeRepository{store: store}
+}
+
+//Create inserts the maze and returns its ID
+func (r boltMazeRepository) Create(ctx context.Context, maze models.Maze) (string, error) {
+	return r.store.insert(ctx, MazesCollection, maze.ID, maze)
+}
+
+//GetByID returns the maze with the given ID
+func (r boltMazeRepository) GetByID(ctx context.Context, id primitive.ObjectID) (models.Maze, error) {
+	var maze models.Maze
+	if err := r.store.get(ctx, MazesCollection, id[:], &maze); err != nil {
+		return models.Maze{}, err
+	}
+	return maze, nil
+}
+
+//List returns every maze stored
+func (r boltMazeRepository) List(ctx context.Context) ([]models.Maze, error) {
+	found, err := r.store.find(ctx, MazesCollection, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var result []models.Maze
+	for _, raw := range found {
+		var maze models.Maze
+		if err := bson.Unmarshal(raw, &maze); err != nil {
+			return nil, err
+		}
+		result = append(result, maze)
+	}
+	return result, nil
+}
+
+//Update replaces the maze's name, description and settings, returning whether anything changed
+func (r boltMazeRepository) Update(ctx context.Context, id primitive.ObjectID, maze models.Maze) (int, error) {
+	var updated models.Maze
+	return r.store.modify(ctx, MazesCollection, id[:], &updated, func() {
+		updated.Name, updated.Description = maze.Name, maze.Description
+		updated.Coordinates, updated.Metric = maze.Coordinates, maze.Metric
+	})
+}
+
+//Delete deletes the maze with the given ID
+func (r boltMazeRepository) Delete(ctx context.Context, id primitive.ObjectID) (int, error) {
+	return r.store.remove(ctx, MazesCollection, id[:])
+}
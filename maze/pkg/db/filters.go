@@ -0,0 +1,173 @@
+package db
+
+import (
+	"github.com/avanticaTest/maze/pkg/geometry"
+	"github.com/avanticaTest/maze/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+)
+
+//BoxFilter builds the filter matching the spots inside the given rectangle, borders included
+func BoxFilter(minX, minY, maxX, maxY float64) bson.M {
+	return bson.M{"$and": bson.A{
+		CoordinateRange("x_coordinate", minX, maxX),
+		CoordinateRange("y_coordinate", minY, maxY),
+	}}
+}
+
+//CoordinateRange matches a coordinate field between min and max. Zero coordinates are never stored because of the
+//omitempty tag, so a missing field has to match as well whenever zero is inside the range
+func CoordinateRange(field string, min, max float64) bson.M {
+	inRange := bson.M{field: bson.M{"$gte": min, "$lte": max}}
+	if min <= 0 && max >= 0 {
+		return bson.M{"$or": bson.A{inRange, bson.M{field: bson.M{"$exists": false}}}}
+	}
+	return inRange
+}
+
+//OnFloor restricts the filter to the spots on the given floor, leaving it as it is when there's no floor
+func OnFloor(filter bson.M, floor *float64) bson.M {
+	if floor == nil {
+		return filter
+	}
+	return bson.M{"$and": bson.A{filter, CoordinateRange("z_coordinate", *floor, *floor)}}
+}
+
+//CoordinateExpr reads a coordinate field inside an aggregation expression, defaulting to zero when it's missing
+func CoordinateExpr(field string) bson.M {
+	return bson.M{"$ifNull": bson.A{"$" + field, 0}}
+}
+
+//QuadrantFilter builds the filter matching the spots in one of the origin's quadrants, borders included. Each quadrant
+//is the intersection of two half-planes bounded by the origin's axes. The axes of an unrotated origin become plain
+//coordinate ranges, which the coordinate indexes can serve. Geographic origins are never rotated, but their east and
+//west halves wrap around the antimeridian
+func QuadrantFilter(origin models.Origin, quadrant string) bson.M {
+	xAxis, yAxis := geometry.Axes(origin)
+	xSign, ySign := geometry.QuadrantSigns(quadrant)
+	o := models.Point{X: origin.XOrigin, Y: origin.YOrigin}
+	if origin.Geographic {
+		return bson.M{"$and": bson.A{
+			longitudeFilter(origin.XOrigin, xSign),
+			halfPlane(models.Point{Y: ySign}, o),
+		}}
+	}
+	//a local coordinate has the sign of the projection on its axis, whatever the scale
+	return bson.M{"$and": bson.A{
+		halfPlane(models.Point{X: xSign * xAxis.X, Y: xSign * xAxis.Y}, o),
+		halfPlane(models.Point{X: ySign * yAxis.X, Y: ySign * yAxis.Y}, o),
+	}}
+}
+
+//SectorFilter builds the filter matching the spots in the sector of a partition around the origin, both boundaries
+//included: the tie-breaking is left to the caller. Each boundary is a half-plane, turned from the origin's scaled frame
+//into world coordinates. Sectors wider than a half-plane, and geographic ones, aren't narrowed at all
+func SectorFilter(origin models.Origin, n, sector int) bson.M {
+	if n < 2 || origin.Geographic {
+		return bson.M{}
+	}
+	span := 360 / float64(n)
+	fromX, fromY := geometry.Ray(float64(sector) * span)
+	toX, toY := geometry.Ray(float64(sector+1) * span)
+	o := models.Point{X: origin.XOrigin, Y: origin.YOrigin}
+	//the local normals point inside the sector: left of the ray starting it, right of the one ending it
+	afterFrom := halfPlane(worldNormal(origin, -fromY, fromX), o)
+	if span >= 180 {
+		return afterFrom
+	}
+	return bson.M{"$and": bson.A{afterFrom, halfPlane(worldNormal(origin, toY, -toX), o)}}
+}
+
+//worldNormal turns the normal of a half-plane bounded by a line through the origin, given in the origin's frame, into
+//world coordinates
+func worldNormal(origin models.Origin, x, y float64) models.Point {
+	xAxis, yAxis := geometry.Axes(origin)
+	xScale, yScale := geometry.Scales(origin)
+	x, y = x/xScale, y/yScale
+	return models.Point{X: x*xAxis.X + y*yAxis.X, Y: x*xAxis.Y + y*yAxis.Y}
+}
+
+//longitudeFilter matches the spots east (sign > 0) or west of the meridian, borders included. As in
+//geometry.WrapLongitude, the opposite meridian is east of it. Longitudes are in (-180, 180], so a half crossing the
+//antimeridian is split in two ranges
+func longitudeFilter(meridian, sign float64) bson.M {
+	var ranges bson.A
+	if sign > 0 {
+		end := meridian + 180
+		ranges = append(ranges, CoordinateRange("x_coordinate", meridian, math.Min(end, 180)))
+		if end > 180 {
+			ranges = append(ranges, CoordinateRange("x_coordinate", math.Inf(-1), end-360))
+		}
+	} else {
+		//the opposite meridian is left out
+		start := meridian - 180
+		if start > -180 {
+			ranges = append(ranges, CoordinateRange("x_coordinate", math.Nextafter(start, math.Inf(1)), meridian))
+		} else {
+			ranges = append(ranges, CoordinateRange("x_coordinate", math.Inf(-1), meridian))
+			if start+360 < 180 {
+				ranges = append(ranges, CoordinateRange("x_coordinate", math.Nextafter(start+360, math.Inf(1)), math.Inf(1)))
+			}
+		}
+	}
+	if len(ranges) == 1 {
+		return ranges[0].(bson.M)
+	}
+	return bson.M{"$or": ranges}
+}
+
+//halfPlane matches the spots p where (p - o) · normal >= 0
+func halfPlane(normal, o models.Point) bson.M {
+	offset := normal.X*o.X + normal.Y*o.Y
+	switch {
+	case normal.Y == 0 && normal.X > 0:
+		return CoordinateRange("x_coordinate", offset/normal.X, math.Inf(1))
+	case normal.Y == 0 && normal.X < 0:
+		return CoordinateRange("x_coordinate", math.Inf(-1), offset/normal.X)
+	case normal.X == 0 && normal.Y > 0:
+		return CoordinateRange("y_coordinate", offset/normal.Y, math.Inf(1))
+	case normal.X == 0 && normal.Y < 0:
+		return CoordinateRange("y_coordinate", math.Inf(-1), offset/normal.Y)
+	}
+	return bson.M{"$expr": bson.M{"$gte": bson.A{
+		bson.M{"$add": bson.A{
+			bson.M{"$multiply": bson.A{normal.X, CoordinateExpr("x_coordinate")}},
+			bson.M{"$multiply": bson.A{normal.Y, CoordinateExpr("y_coordinate")}},
+		}},
+		offset,
+	}}}
+}
+
+//OriginFilter matches the origin with exactly the coordinates, rotation and scales given. The fields are left out of
+//the stored origin when zero, so a zero matches a missing field too
+func OriginFilter(origin models.Origin) bson.M {
+	filter := bson.M{}
+	for field, value := range map[string]float64{
+		"x_origin": origin.XOrigin,
+		"y_origin": origin.YOrigin,
+		"rotation": origin.Rotation,
+		"x_scale":  origin.XScale,
+		"y_scale":  origin.YScale,
+	} {
+		if value == 0 {
+			filter[field] = bson.M{"$in": bson.A{0, nil}}
+		} else {
+			filter[field] = value
+		}
+	}
+	return filter
+}
+
+//PathsOf matches the paths ending at the spot
+func PathsOf(spot primitive.ObjectID) bson.M {
+	return bson.M{"$or": bson.A{bson.M{"point_a": spot}, bson.M{"point_b": spot}}}
+}
+
+//PathBetween matches the paths joining both spots, whichever end each one is
+func PathBetween(a, b primitive.ObjectID) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{"point_a": a, "point_b": b},
+		bson.M{"point_a": b, "point_b": a},
+	}}
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"github.com/avanticaTest/maze/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"gotest.tools/v3/assert"
+	"math"
+	"testing"
+)
+
+func TestQuadrantFilter(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		origin   models.Origin
+		quadrant string
+		expected bson.M
+	}{
+		{
+			name:     "Unrotated origin uses ranges",
+			origin:   models.Origin{XOrigin: 2, YOrigin: -3},
+			quadrant: "upper_left",
+			expected: bson.M{"$and": bson.A{
+				CoordinateRange("x_coordinate", math.Inf(-1), 2),
+				CoordinateRange("y_coordinate", -3, math.Inf(1)),
+			}},
+		},
+		{
+			name:     "Right angles use ranges",
+			origin:   models.Origin{XOrigin: 2, YOrigin: -3, Rotation: 90, XScale: 4},
+			quadrant: "upper_right",
+			expected: bson.M{"$and": bson.A{
+				CoordinateRange("y_coordinate", -3, math.Inf(1)),
+				CoordinateRange("x_coordinate", math.Inf(-1), 2),
+			}},
+		},
+		{
+			name:     "Other angles use expressions",
+			origin:   models.Origin{Rotation: 45},
+			quadrant: "bottom_right",
+			expected: bson.M{"$and": bson.A{
+				halfPlane(models.Point{X: math.Cos(math.Pi / 4), Y: math.Sin(math.Pi / 4)}, models.Point{}),
+				halfPlane(models.Point{X: math.Sin(math.Pi / 4), Y: -math.Cos(math.Pi / 4)}, models.Point{}),
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.DeepEqual(t, tt.expected, QuadrantFilter(tt.origin, tt.quadrant))
+		})
+	}
+}
+
+func TestCoordinateRangeMatchesMissingZero(t *testing.T) {
+
+	assert.DeepEqual(t, bson.M{"x_coordinate": bson.M{"$gte": 1.0, "$lte": 3.0}}, CoordinateRange("x_coordinate", 1, 3))
+	assert.DeepEqual(t, bson.M{"$or": bson.A{
+		bson.M{"x_coordinate": bson.M{"$gte": -1.0, "$lte": 3.0}},
+		bson.M{"x_coordinate": bson.M{"$exists": false}},
+	}}, CoordinateRange("x_coordinate", -1, 3))
+}
+
+func TestOnFloor(t *testing.T) {
+
+	filter := BoxFilter(0, 0, 1, 1)
+	assert.DeepEqual(t, filter, OnFloor(filter, nil))
+
+	//the ground floor holds the spots without a z coordinate as well
+	ground := 0.0
+	assert.DeepEqual(t, bson.M{"$and": bson.A{filter, bson.M{"$or": bson.A{
+		bson.M{"z_coordinate": bson.M{"$gte": 0.0, "$lte": 0.0}},
+		bson.M{"z_coordinate": bson.M{"$exists": false}},
+	}}}}, OnFloor(filter, &ground))
+}
+
+func TestGeographicQuadrantFilter(t *testing.T) {
+
+	origin := models.Origin{XOrigin: 170, YOrigin: -10, Geographic: true}
+
+	tests := []struct {
+		name     string
+		quadrant string
+		expected bson.M
+	}{
+		{
+			name:     "East crosses the antimeridian",
+			quadrant: "upper_right",
+			expected: bson.M{"$and": bson.A{
+				bson.M{"$or": bson.A{
+					CoordinateRange("x_coordinate", 170, 180),
+					CoordinateRange("x_coordinate", math.Inf(-1), -10),
+				}},
+				CoordinateRange("y_coordinate", -10, math.Inf(1)),
+			}},
+		},
+		{
+			name:     "West leaves the opposite meridian out",
+			quadrant: "bottom_left",
+			expected: bson.M{"$and": bson.A{
+				CoordinateRange("x_coordinate", math.Nextafter(-10, 0), 170),
+				CoordinateRange("y_coordinate", math.Inf(-1), -10),
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.DeepEqual(t, tt.expected, QuadrantFilter(origin, tt.quadrant))
+		})
+	}
+}
+
+func TestSectorFilter(t *testing.T) {
+
+	//the second octant of a shifted origin: above the diagonal, right of the y axis
+	origin := models.Origin{XOrigin: 1, YOrigin: 2}
+	h := math.Sqrt2 / 2
+	assert.DeepEqual(t, bson.M{"$and": bson.A{
+		halfPlane(models.Point{X: -h, Y: h}, models.Point{X: 1, Y: 2}),
+		CoordinateRange("x_coordinate", 1, math.Inf(1)),
+	}}, SectorFilter(origin, 8, 1))
+
+	//a half-plane needs a single range, and a single sector nothing at all
+	assert.DeepEqual(t, CoordinateRange("y_coordinate", math.Inf(-1), 2), SectorFilter(origin, 2, 1))
+	assert.DeepEqual(t, bson.M{}, SectorFilter(origin, 1, 0))
+}
+
+func TestOriginFilter(t *testing.T) {
+
+	//zero fields aren't stored, so they have to match a missing field as well
+	missing := bson.M{"$in": bson.A{0, nil}}
+	assert.DeepEqual(t, bson.M{
+		"x_origin": 4.0,
+		"y_origin": missing,
+		"rotation": 30.0,
+		"x_scale":  missing,
+		"y_scale":  missing,
+	}, OriginFilter(models.Origin{XOrigin: 4, Rotation: 30}))
+}
@@ -0,0 +1,277 @@
+package db
+
+import (
+	"context"
+	"github.com/avanticaTest/maze/pkg/models"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//LegacyMazeCollection kept the settings of the single maze there was before mazes were stored in MazesCollection
+const LegacyMazeCollection = "maze"
+
+//legacyMazeKey is the key the bolt storage kept the single maze's settings under
+var legacyMazeKey = []byte("maze")
+
+//LegacyRepository reaches what was stored before there were mazes: the spots, paths, origin and zones without a
+//maze_id, and the settings of the single maze of back then. It isn't scoped, so its context must not be
+type LegacyRepository interface {
+	//Settings returns the settings of the single maze, and whether there are any
+	Settings(ctx context.Context) (models.Maze, bool, error)
+	//Unscoped returns how many documents have no maze
+	Unscoped(ctx context.Context) (int, error)
+	//Adopt gives the documents without a maze to the maze, the origin being stored again under the maze's ID, and
+	//drops the single maze's settings
+	Adopt(ctx context.Context, maze primitive.ObjectID) error
+}
+
+//unscoped matches the documents stored before there were mazes
+func unscoped() bson.M {
+	return bson.M{"maze_id": bson.M{"$exists": false}}
+}
+
+type mongoLegacyRepository struct {
+	db          DBManager
+	database    string
+	collections Collections
+}
+
+//NewLegacyRepository creates the legacy repository of the given MongoDB database and collections
+func NewLegacyRepository(db DBManager, database string, collections Collections) LegacyRepository {
+	return mongoLegacyRepository{db: db, database: database, collections: collections}
+}
+
+//Settings returns the document of the single maze's collection
+func (r mongoLegacyRepository) Settings(ctx context.Context) (models.Maze, bool, error) {
+	found, err := r.db.FindMazes(ctx, r.database, LegacyMazeCollection, bson.M{})
+	if err != nil || len(found) == 0 {
+		return models.Maze{}, false, err
+	}
+	return found[0], true, nil
+}
+
+//Unscoped counts the documents without a maze in every collection
+func (r mongoLegacyRepository) Unscoped(ctx context.Context) (int, error) {
+	count := 0
+	for _, collection := range []string{r.collections.Spots, r.collections.Paths, r.collections.Origin, r.collections.Zones} {
+		n, err := r.db.CountDocuments(ctx, r.database, collection, unscoped())
+		if err != nil {
+			return count, err
+		}
+		count += n
+	}
+	return count, nil
+}
+
+//Adopt sets the maze of the spots, paths and zones without one in a single update each. The origin can't change its
+//ID, so it's inserted again under the maze's and the old one deleted
+func (r mongoLegacyRepository) Adopt(ctx context.Context, maze primitive.ObjectID) error {
+	for _, collection := range []string{r.collections.Spots, r.collections.Paths, r.collections.Zones} {
+		update := bson.M{"$set": bson.M{"maze_id": maze}}
+		if _, err := r.db.UpdateMany(ctx, unscoped(), update, r.database, collection); err != nil {
+			return err
+		}
+	}
+
+	origins, err := r.db.FindOrigin(ctx, r.database, r.collections.Origin, unscoped())
+	if err != nil {
+		return err
+	}
+	if len(origins) > 0 {
+		origin := origins[0]
+		origin.MazeID = maze
+		doc := document(origin)
+		doc["_id"] = originKey(origin)
+		if _, err := r.db.InsertOne(ctx, r.database, r.collections.Origin, doc); err != nil {
+			return err
+		}
+		if _, err := r.db.DeleteMany(ctx, unscoped(), r.database, r.collections.Origin); err != nil {
+			return err
+		}
+	}
+
+	_, err = r.db.DeleteMany(ctx, bson.M{}, r.database, LegacyMazeCollection)
+	return err
+}
+
+type memoryLegacyRepository struct {
+	store *MemoryStore
+}
+
+//NewMemoryLegacyRepository creates the legacy repository of the store. Nothing outlives the process, so there are
+//never settings of a single maze, but the documents created without a maze can still be adopted
+func NewMemoryLegacyRepository(store *MemoryStore) LegacyRepository {
+	return memoryLegacyRepository{store: store}
+}
+
+//Settings returns none
+func (r memoryLegacyRepository) Settings(_ context.Context) (models.Maze, bool, error) {
+	return models.Maze{}, false, nil
+}
+
+//Unscoped counts the documents without a maze
+func (r memoryLegacyRepository) Unscoped(_ context.Context) (int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	count := 0
+	for _, v := range r.store.spots {
+		if v.spot.MazeID.IsZero() {
+			count++
+		}
+	}
+	for _, v := range r.store.paths {
+		if v.MazeID.IsZero() {
+			count++
+		}
+	}
+	for _, v := range r.store.origins {
+		if v.MazeID.IsZero() {
+			count++
+		}
+	}
+	for _, v := range r.store.zones {
+		if v.MazeID.IsZero() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+//Adopt sets the maze of every document without one
+func (r memoryLegacyRepository) Adopt(_ context.Context, maze primitive.ObjectID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, v := range r.store.spots {
+		if v.spot.MazeID.IsZero() {
+			v.spot.MazeID = maze
+			r.store.spots[i] = newSpot(v.spot)
+		}
+	}
+	for i, v := range r.store.paths {
+		if v.MazeID.IsZero() {
+			r.store.paths[i].MazeID = maze
+		}
+	}
+	for i, v := range r.store.origins {
+		if v.MazeID.IsZero() {
+			r.store.origins[i].MazeID = maze
+		}
+	}
+	for i, v := range r.store.zones {
+		if v.MazeID.IsZero() {
+			r.store.zones[i].MazeID = maze
+		}
+	}
+	return nil
+}
+
+type boltLegacyRepository struct {
+	store *BoltStore
+}
+
+//NewBoltLegacyRepository creates the legacy repository of the store
+func NewBoltLegacyRepository(store *BoltStore) LegacyRepository {
+	return boltLegacyRepository{store: store}
+}
+
+//Settings returns the single maze's settings, kept in a bucket of their own
+func (r boltLegacyRepository) Settings(ctx context.Context) (models.Maze, bool, error) {
+	var maze models.Maze
+	found := false
+	err := r.store.view(ctx, func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(LegacyMazeCollection))
+		if b == nil {
+			return nil
+		}
+		raw := b.Get(legacyMazeKey)
+		if raw == nil {
+			return nil
+		}
+		found = true
+		return bson.Unmarshal(raw, &maze)
+	})
+	return maze, found, err
+}
+
+//Unscoped counts the documents without a maze
+func (r boltLegacyRepository) Unscoped(ctx context.Context) (int, error) {
+	count := 0
+	err := r.store.view(ctx, func(tx *bbolt.Tx) error {
+		for _, collection := range []string{SpotsCollection, PathsCollection, OriginCollection, ZonesCollection} {
+			err := tx.Bucket([]byte(collection)).ForEach(func(_, raw []byte) error {
+				if _, err := bson.Raw(raw).LookupErr("maze_id"); err != nil {
+					count++
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+//Adopt sets the maze of every document without one, re-keying the origin under the maze's ID, and drops the bucket of
+//the single maze's settings, in a single transaction
+func (r boltLegacyRepository) Adopt(ctx context.Context, maze primitive.ObjectID) error {
+	return r.store.update(ctx, func(tx *bbolt.Tx) error {
+		for _, collection := range []string{SpotsCollection, PathsCollection, OriginCollection, ZonesCollection} {
+			b := tx.Bucket([]byte(collection))
+			adopted := map[string][]byte{}
+			err := b.ForEach(func(key, raw []byte) error {
+				if _, err := bson.Raw(raw).LookupErr("maze_id"); err == nil {
+					return nil
+				}
+				var doc bson.D
+				if err := bson.Unmarshal(raw, &doc); err != nil {
+					return err
+				}
+				doc = append(doc, bson.E{Key: "maze_id", Value: maze})
+				if collection == OriginCollection {
+					//the origin is kept under the ID of its maze
+					doc = rekey(doc, maze)
+				}
+				updated, err := bson.Marshal(doc)
+				if err != nil {
+					return err
+				}
+				adopted[string(key)] = updated
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for key, raw := range adopted {
+				if collection == OriginCollection {
+					if err := b.Delete([]byte(key)); err != nil {
+						return err
+					}
+					key = string(maze[:])
+				}
+				if err := b.Put([]byte(key), raw); err != nil {
+					return err
+				}
+			}
+		}
+		if tx.Bucket([]byte(LegacyMazeCollection)) == nil {
+			return nil
+		}
+		return tx.DeleteBucket([]byte(LegacyMazeCollection))
+	})
+}
+
+//rekey replaces the ID of the document
+func rekey(doc bson.D, id primitive.ObjectID) bson.D {
+	result := bson.D{{Key: "_id", Value: id}}
+	for _, v := range doc {
+		if v.Key != "_id" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,333 @@
+package db
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"math"
+	"reflect"
+	"strings"
+)
+
+//document turns a value into the document MongoDB would store for it, so the omitempty fields are missing as they
+//would be in the database
+func document(v interface{}) bson.M {
+	switch d := v.(type) {
+	case bson.M:
+		return d
+	case map[string]interface{}:
+		return d
+	case bson.D:
+		return d.Map()
+	}
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var result bson.M
+	if err := bson.Unmarshal(raw, &result); err != nil {
+		return nil
+	}
+	return result
+}
+
+//array reads an array of a filter or expression, nil when the value isn't one
+func array(v interface{}) []interface{} {
+	switch a := v.(type) {
+	case bson.A:
+		return a
+	case []interface{}:
+		return a
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil
+	}
+	result := make([]interface{}, rv.Len())
+	for i := range result {
+		result[i] = rv.Index(i).Interface()
+	}
+	return result
+}
+
+//matches tells whether the document satisfies the filter the way MongoDB would, for the part of the query language
+//the filters of this package are written in: equality, comparisons, $in, $exists, $and, $or and $expr with arithmetic
+func matches(doc bson.M, filter interface{}) bool {
+	for key, condition := range document(filter) {
+		switch key {
+		case "$and":
+			for _, f := range array(condition) {
+				if !matches(doc, f) {
+					return false
+				}
+			}
+		case "$or":
+			any := false
+			for _, f := range array(condition) {
+				if matches(doc, f) {
+					any = true
+					break
+				}
+			}
+			if !any {
+				return false
+			}
+		case "$expr":
+			if !truthy(evaluate(doc, condition)) {
+				return false
+			}
+		default:
+			value, present := lookup(doc, key)
+			if !matchField(value, present, condition) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+//lookup reads a field of the document, following the dots into embedded documents
+func lookup(doc bson.M, key string) (interface{}, bool) {
+	var value interface{} = doc
+	for _, part := range strings.Split(key, ".") {
+		d := document(value)
+		if d == nil {
+			return nil, false
+		}
+		v, ok := d[part]
+		if !ok {
+			return nil, false
+		}
+		value = v
+	}
+	return value, true
+}
+
+//operators tells whether the condition on a field is made of query operators rather than a value to be equal to
+func operators(condition interface{}) (bson.M, bool) {
+	switch condition.(type) {
+	case bson.M, map[string]interface{}, bson.D:
+	default:
+		return nil, false
+	}
+	ops := document(condition)
+	for op := range ops {
+		if !strings.HasPrefix(op, "$") {
+			return nil, false
+		}
+	}
+	return ops, len(ops) > 0
+}
+
+func matchField(value interface{}, present bool, condition interface{}) bool {
+	ops, ok := operators(condition)
+	if !ok {
+		return equal(value, condition)
+	}
+	for op, arg := range ops {
+		var result bool
+		switch op {
+		case "$eq":
+			result = equal(value, arg)
+		case "$ne":
+			result = !equal(value, arg)
+		case "$gt", "$gte", "$lt", "$lte":
+			//like MongoDB, comparisons only match values of the same kind
+			c, comparable := compare(value, arg)
+			result = comparable && present && value != nil && holds(op, c)
+		case "$in":
+			for _, v := range array(arg) {
+				if equal(value, v) {
+					result = true
+					break
+				}
+			}
+		case "$nin":
+			result = true
+			for _, v := range array(arg) {
+				if equal(value, v) {
+					result = false
+					break
+				}
+			}
+		case "$exists":
+			result = present == truthy(arg)
+		}
+		if !result {
+			return false
+		}
+	}
+	return true
+}
+
+//holds tells whether the comparison operator holds for the result of compare
+func holds(op string, c int) bool {
+	switch op {
+	case "$gt":
+		return c > 0
+	case "$gte":
+		return c >= 0
+	case "$lt":
+		return c < 0
+	}
+	return c <= 0
+}
+
+//number reads any numeric value as a float
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case *int:
+		if n != nil {
+			return float64(*n), true
+		}
+	}
+	return 0, false
+}
+
+//equal compares two values, numbers being equal whatever their type. A nil value matches a missing field
+func equal(a, b interface{}) bool {
+	x, okA := number(a)
+	y, okB := number(b)
+	if okA && okB {
+		return x == y
+	}
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+//compare orders two values of the same kind. Null sorts before numbers, and numbers before strings, as in MongoDB
+func compare(a, b interface{}) (int, bool) {
+	x, okA := number(a)
+	y, okB := number(b)
+	switch {
+	case okA && okB:
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case a == nil && b == nil:
+		return 0, true
+	case a == nil:
+		return -1, okB
+	case b == nil:
+		return 1, okA
+	}
+	s, okA := a.(string)
+	t, okB := b.(string)
+	if okA && okB {
+		return strings.Compare(s, t), true
+	}
+	return 0, false
+}
+
+func truthy(v interface{}) bool {
+	if n, ok := number(v); ok {
+		return n != 0
+	}
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return v != nil
+}
+
+//evaluate computes an aggregation expression over the document: field paths, $ifNull, arithmetic and comparisons.
+//Arithmetic over a missing value is null, as in MongoDB
+func evaluate(doc bson.M, expr interface{}) interface{} {
+	if path, ok := expr.(string); ok && strings.HasPrefix(path, "$") {
+		value, _ := lookup(doc, path[1:])
+		return value
+	}
+	ops, ok := operators(expr)
+	if !ok || len(ops) != 1 {
+		return expr
+	}
+	for op, arg := range ops {
+		args := array(arg)
+		values := make([]interface{}, len(args))
+		for i, a := range args {
+			values[i] = evaluate(doc, a)
+		}
+		switch op {
+		case "$ifNull":
+			for _, v := range values {
+				if v != nil {
+					return v
+				}
+			}
+			return nil
+		case "$add", "$multiply":
+			result := 0.0
+			if op == "$multiply" {
+				result = 1
+			}
+			for _, v := range values {
+				n, ok := number(v)
+				if !ok {
+					return nil
+				}
+				if op == "$add" {
+					result += n
+				} else {
+					result *= n
+				}
+			}
+			return result
+		case "$subtract", "$divide", "$pow":
+			if len(values) != 2 {
+				return nil
+			}
+			x, okX := number(values[0])
+			y, okY := number(values[1])
+			if !okX || !okY {
+				return nil
+			}
+			switch op {
+			case "$subtract":
+				return x - y
+			case "$divide":
+				return x / y
+			}
+			return math.Pow(x, y)
+		case "$gt", "$gte", "$lt", "$lte", "$eq", "$ne":
+			if len(values) != 2 {
+				return nil
+			}
+			c, ok := compare(values[0], values[1])
+			switch op {
+			case "$eq":
+				return ok && c == 0
+			case "$ne":
+				return !ok || c != 0
+			}
+			return ok && holds(op, c)
+		case "$and":
+			for _, v := range values {
+				if !truthy(v) {
+					return false
+				}
+			}
+			return true
+		case "$or":
+			for _, v := range values {
+				if truthy(v) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return nil
+}
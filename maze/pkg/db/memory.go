@@ -0,0 +1,746 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/avanticaTest/maze/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"reflect"
+	"sync"
+)
+
+//ErrDuplicateID is returned when inserting a document with the ID of one already stored
+var ErrDuplicateID = errors.New("There's already a document with that ID")
+
+//MemoryStore keeps every collection in the process, for local runs and tests. Documents go through the same BSON
+//encoding as in MongoDB, so what's read back, and what the filters match, is what the database would give. It's safe
+//for concurrent use, and nothing survives the process
+type MemoryStore struct {
+	mu      sync.RWMutex
+	spots   []memorySpot
+	paths   []models.Path
+	origins []models.Origin
+	zones   []models.Zone
+	mazes   []models.Maze
+}
+
+//memorySpot is a spot along with its document, which the filters are matched against
+type memorySpot struct {
+	spot models.Spot
+	doc  bson.M
+}
+
+//NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+//stored returns the value as it would be read back from MongoDB, leaving out whatever isn't stored
+func stored(v, out interface{}) {
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return
+	}
+	bson.Unmarshal(raw, out)
+}
+
+func newSpot(spot models.Spot) memorySpot {
+	var s models.Spot
+	stored(spot, &s)
+	return memorySpot{spot: s, doc: document(s)}
+}
+
+type memorySpotRepository struct {
+	store *MemoryStore
+}
+
+//NewMemorySpotRepository creates the spot repository kept in the store
+func NewMemorySpotRepository(store *MemoryStore) SpotRepository {
+	return memorySpotRepository{store: store}
+}
+
+//Create inserts the spot and returns its ID
+func (r memorySpotRepository) Create(ctx context.Context, spot models.Spot) (string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	spot.MazeID = owner(ctx, spot.MazeID)
+	if spot.ID.IsZero() {
+		spot.ID = primitive.NewObjectID()
+	} else if r.store.spot(spot.ID) >= 0 {
+		return "", ErrDuplicateID
+	}
+	r.store.spots = append(r.store.spots, newSpot(spot))
+	return spot.ID.Hex(), nil
+}
+
+//spot returns the position of the spot with the given ID, -1 when there's none
+func (s *MemoryStore) spot(id primitive.ObjectID) int {
+	for i, v := range s.spots {
+		if v.spot.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+//scopedSpot returns the position of the spot with the given ID, -1 when there's none in the context's maze
+func (s *MemoryStore) scopedSpot(ctx context.Context, id primitive.ObjectID) int {
+	i := s.spot(id)
+	if i < 0 || !within(ctx, s.spots[i].spot.MazeID) {
+		return -1
+	}
+	return i
+}
+
+//GetByID returns the spot with the given ID
+func (r memorySpotRepository) GetByID(ctx context.Context, id primitive.ObjectID) (models.Spot, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	i := r.store.scopedSpot(ctx, id)
+	if i < 0 {
+		return models.Spot{}, mongo.ErrNoDocuments
+	}
+	return r.store.spots[i].spot, nil
+}
+
+//GetByIDs returns the spots with the given IDs, leaving out the ones that don't exist
+func (r memorySpotRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Spot, error) {
+	return r.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+//List returns every spot
+func (r memorySpotRepository) List(ctx context.Context) ([]models.Spot, error) {
+	return r.Find(ctx, bson.M{})
+}
+
+//Find returns the spots matching the filter
+func (r memorySpotRepository) Find(ctx context.Context, filter interface{}) ([]models.Spot, error) {
+	var result []models.Spot
+	err := r.Stream(ctx, filter, func(spot models.Spot) error {
+		result = append(result, spot)
+		return nil
+	})
+	return result, err
+}
+
+//Stream hands the spots matching the filter to fn one at a time, stopping at the first error it returns. The spots
+//are those stored when it's called, fn may change them meanwhile
+func (r memorySpotRepository) Stream(ctx context.Context, filter interface{}, fn func(models.Spot) error) error {
+	filter = scope(ctx, filter)
+	r.store.mu.RLock()
+	var found []models.Spot
+	for _, v := range r.store.spots {
+		if matches(v.doc, filter) {
+			found = append(found, v.spot)
+		}
+	}
+	r.store.mu.RUnlock()
+
+	for _, v := range found {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Update replaces the spot's coordinates, name and number, returning whether anything changed
+func (r memorySpotRepository) Update(ctx context.Context, id primitive.ObjectID, spot models.Spot) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.store.scopedSpot(ctx, id)
+	if i < 0 {
+		return 0, nil
+	}
+	updated := r.store.spots[i].spot
+	updated.XCoordinate, updated.YCoordinate, updated.ZCoordinate = spot.XCoordinate, spot.YCoordinate, spot.ZCoordinate
+	updated.Name, updated.Number = spot.Name, spot.Number
+	return r.store.replaceSpot(i, updated), nil
+}
+
+//replaceSpot stores the spot in the position, returning 1 when it changed
+func (s *MemoryStore) replaceSpot(i int, spot models.Spot) int {
+	if reflect.DeepEqual(s.spots[i].spot, spot) {
+		return 0
+	}
+	s.spots[i] = newSpot(spot)
+	return 1
+}
+
+//Delete deletes the spot with the given ID
+func (r memorySpotRepository) Delete(ctx context.Context, id primitive.ObjectID) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.store.scopedSpot(ctx, id)
+	if i < 0 {
+		return 0, nil
+	}
+	r.store.spots = append(r.store.spots[:i], r.store.spots[i+1:]...)
+	return 1, nil
+}
+
+//DeleteMany deletes the spots matching the filter
+func (r memorySpotRepository) DeleteMany(ctx context.Context, filter interface{}) (int, error) {
+	filter = scope(ctx, filter)
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	kept := r.store.spots[:0]
+	for _, v := range r.store.spots {
+		if !matches(v.doc, filter) {
+			kept = append(kept, v)
+		}
+	}
+	deleted := len(r.store.spots) - len(kept)
+	r.store.spots = kept
+	return deleted, nil
+}
+
+//ClearClusters removes the cluster label of every spot
+func (r memorySpotRepository) ClearClusters(ctx context.Context) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, v := range r.store.spots {
+		if !within(ctx, v.spot.MazeID) {
+			continue
+		}
+		v.spot.Cluster = nil
+		r.store.replaceSpot(i, v.spot)
+	}
+	return nil
+}
+
+//SetCluster labels the spots with the given IDs with the cluster
+func (r memorySpotRepository) SetCluster(ctx context.Context, ids []primitive.ObjectID, label int) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, id := range ids {
+		if i := r.store.scopedSpot(ctx, id); i >= 0 {
+			spot := r.store.spots[i].spot
+			l := label
+			spot.Cluster = &l
+			r.store.replaceSpot(i, spot)
+		}
+	}
+	return nil
+}
+
+//Touch bumps the revision of the spots
+func (r memorySpotRepository) Touch(ctx context.Context, ids ...primitive.ObjectID) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	touched := 0
+	for _, id := range ids {
+		if i := r.store.scopedSpot(ctx, id); i >= 0 {
+			spot := r.store.spots[i].spot
+			spot.Revision++
+			touched += r.store.replaceSpot(i, spot)
+		}
+	}
+	return touched, nil
+}
+
+type memoryPathRepository struct {
+	store *MemoryStore
+}
+
+//NewMemoryPathRepository creates the path repository kept in the store
+func NewMemoryPathRepository(store *MemoryStore) PathRepository {
+	return memoryPathRepository{store: store}
+}
+
+func (s *MemoryStore) path(id primitive.ObjectID) int {
+	for i, v := range s.paths {
+		if v.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+//scopedPath returns the position of the path with the given ID, -1 when there's none in the context's maze
+func (s *MemoryStore) scopedPath(ctx context.Context, id primitive.ObjectID) int {
+	i := s.path(id)
+	if i < 0 || !within(ctx, s.paths[i].MazeID) {
+		return -1
+	}
+	return i
+}
+
+//joined tells whether another path of the maze joins the same spots, which the unique index on the ends of the paths
+//turns away in MongoDB
+func (s *MemoryStore) joined(path models.Path) bool {
+	if path.Ends == "" {
+		return false
+	}
+	for _, v := range s.paths {
+		if v.ID != path.ID && v.MazeID == path.MazeID && v.Ends == path.Ends {
+			return true
+		}
+	}
+	return false
+}
+
+//Create inserts the path and returns its ID
+func (r memoryPathRepository) Create(ctx context.Context, path models.Path) (string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	path.MazeID = owner(ctx, path.MazeID)
+	path.Ends = pathEnds(path)
+	if path.ID.IsZero() {
+		path.ID = primitive.NewObjectID()
+	} else if r.store.path(path.ID) >= 0 {
+		return "", ErrDuplicateID
+	}
+	if r.store.joined(path) {
+		return "", ErrPathExists
+	}
+	var p models.Path
+	stored(path, &p)
+	r.store.paths = append(r.store.paths, p)
+	return path.ID.Hex(), nil
+}
+
+//GetByID returns the path with the given ID
+func (r memoryPathRepository) GetByID(ctx context.Context, id primitive.ObjectID) (models.Path, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	i := r.store.scopedPath(ctx, id)
+	if i < 0 {
+		return models.Path{}, mongo.ErrNoDocuments
+	}
+	return r.store.paths[i], nil
+}
+
+//List returns every path
+func (r memoryPathRepository) List(ctx context.Context) ([]models.Path, error) {
+	return r.Find(ctx, bson.M{})
+}
+
+//Update replaces the spots the path joins and its distance, returning whether anything changed
+func (r memoryPathRepository) Update(ctx context.Context, id primitive.ObjectID, path models.Path) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.store.scopedPath(ctx, id)
+	if i < 0 {
+		return 0, nil
+	}
+	updated := r.store.paths[i]
+	updated.PointA, updated.PointB, updated.Distance = path.PointA, path.PointB, path.Distance
+	updated.Ends = pathEnds(updated)
+	if reflect.DeepEqual(r.store.paths[i], updated) {
+		return 0, nil
+	}
+	if r.store.joined(updated) {
+		return 0, ErrPathExists
+	}
+	stored(updated, &r.store.paths[i])
+	return 1, nil
+}
+
+//Delete deletes the path with the given ID
+func (r memoryPathRepository) Delete(ctx context.Context, id primitive.ObjectID) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.store.scopedPath(ctx, id)
+	if i < 0 {
+		return 0, nil
+	}
+	r.store.paths = append(r.store.paths[:i], r.store.paths[i+1:]...)
+	return 1, nil
+}
+
+//Find returns the paths matching the filter
+func (r memoryPathRepository) Find(ctx context.Context, filter interface{}) ([]models.Path, error) {
+	filter = scope(ctx, filter)
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var result []models.Path
+	for _, v := range r.store.paths {
+		if matches(document(v), filter) {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+//DeleteMany deletes the paths matching the filter
+func (r memoryPathRepository) DeleteMany(ctx context.Context, filter interface{}) (int, error) {
+	filter = scope(ctx, filter)
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	kept := r.store.paths[:0]
+	for _, v := range r.store.paths {
+		if !matches(document(v), filter) {
+			kept = append(kept, v)
+		}
+	}
+	deleted := len(r.store.paths) - len(kept)
+	r.store.paths = kept
+	return deleted, nil
+}
+
+//Detach unsets the spot from the paths it ends, along with their distance
+func (r memoryPathRepository) Detach(ctx context.Context, spot primitive.ObjectID) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	detached := 0
+	for i, v := range r.store.paths {
+		if !within(ctx, v.MazeID) {
+			continue
+		}
+		if v.PointA == spot {
+			v.PointA, v.Distance = primitive.NilObjectID, 0
+			detached++
+		}
+		if v.PointB == spot {
+			v.PointB, v.Distance = primitive.NilObjectID, 0
+			detached++
+		}
+		v.Ends = pathEnds(v)
+		r.store.paths[i] = v
+	}
+	return detached, nil
+}
+
+//SetDistances stores the distance of every path given, all at once
+func (r memoryPathRepository) SetDistances(ctx context.Context, distances map[primitive.ObjectID]float64) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	modified := 0
+	for i, v := range r.store.paths {
+		if distance, ok := distances[v.ID]; ok && v.Distance != distance && within(ctx, v.MazeID) {
+			r.store.paths[i].Distance = distance
+			modified++
+		}
+	}
+	return modified, nil
+}
+
+type memoryOriginRepository struct {
+	store *MemoryStore
+}
+
+//NewMemoryOriginRepository creates the origin repository kept in the store
+func NewMemoryOriginRepository(store *MemoryStore) OriginRepository {
+	return memoryOriginRepository{store: store}
+}
+
+//Count returns how many origins there are
+func (r memoryOriginRepository) Count(ctx context.Context) (int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	count := 0
+	for _, v := range r.store.origins {
+		if within(ctx, v.MazeID) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+//Create inserts the origin unless its maze already has one, returning the ID of the maze it's stored under
+func (r memoryOriginRepository) Create(ctx context.Context, origin models.Origin) (string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	origin.MazeID = owner(ctx, origin.MazeID)
+	for _, v := range r.store.origins {
+		if within(ctx, v.MazeID) || v.MazeID == origin.MazeID {
+			return "", ErrOriginExists
+		}
+	}
+	var o models.Origin
+	stored(origin, &o)
+	r.store.origins = append(r.store.origins, o)
+	return originKey(origin).Hex(), nil
+}
+
+//List returns every origin stored
+func (r memoryOriginRepository) List(ctx context.Context) ([]models.Origin, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var result []models.Origin
+	for _, v := range r.store.origins {
+		if within(ctx, v.MazeID) {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+//Update replaces the origin's coordinates, rotation and scales
+func (r memoryOriginRepository) Update(ctx context.Context, origin models.Origin) (int, error) {
+	return r.replace(ctx, bson.M{}, origin)
+}
+
+//Replace replaces the origin's coordinates, rotation and scales as long as they're still the current ones
+func (r memoryOriginRepository) Replace(ctx context.Context, current, origin models.Origin) (int, error) {
+	return r.replace(ctx, OriginFilter(current), origin)
+}
+
+//replace updates the first origin matching the filter, returning 1 when it changed
+func (r memoryOriginRepository) replace(ctx context.Context, filter interface{}, origin models.Origin) (int, error) {
+	filter = scope(ctx, filter)
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for i, v := range r.store.origins {
+		if !matches(document(v), filter) {
+			continue
+		}
+		origin.MazeID = v.MazeID
+		var o models.Origin
+		stored(origin, &o)
+		if v == o {
+			return 0, nil
+		}
+		r.store.origins[i] = o
+		return 1, nil
+	}
+	return 0, nil
+}
+
+//Delete deletes the origin
+func (r memoryOriginRepository) Delete(ctx context.Context) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	kept := r.store.origins[:0]
+	for _, v := range r.store.origins {
+		if !within(ctx, v.MazeID) {
+			kept = append(kept, v)
+		}
+	}
+	deleted := len(r.store.origins) - len(kept)
+	r.store.origins = kept
+	return deleted, nil
+}
+
+type memoryZoneRepository struct {
+	store *MemoryStore
+}
+
+//NewMemoryZoneRepository creates the zone repository kept in the store
+func NewMemoryZoneRepository(store *MemoryStore) ZoneRepository {
+	return memoryZoneRepository{store: store}
+}
+
+func (s *MemoryStore) zone(id primitive.ObjectID) int {
+	for i, v := range s.zones {
+		if v.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+//scopedZone returns the position of the zone with the given ID, -1 when there's none in the context's maze
+func (s *MemoryStore) scopedZone(ctx context.Context, id primitive.ObjectID) int {
+	i := s.zone(id)
+	if i < 0 || !within(ctx, s.zones[i].MazeID) {
+		return -1
+	}
+	return i
+}
+
+//Create inserts the zone and returns its ID
+func (r memoryZoneRepository) Create(ctx context.Context, zone models.Zone) (string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	zone.MazeID = owner(ctx, zone.MazeID)
+	if zone.ID.IsZero() {
+		zone.ID = primitive.NewObjectID()
+	} else if r.store.zone(zone.ID) >= 0 {
+		return "", ErrDuplicateID
+	}
+	var z models.Zone
+	stored(zone, &z)
+	r.store.zones = append(r.store.zones, z)
+	return zone.ID.Hex(), nil
+}
+
+//GetByID returns the zone with the given ID
+func (r memoryZoneRepository) GetByID(ctx context.Context, id primitive.ObjectID) (models.Zone, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	i := r.store.scopedZone(ctx, id)
+	if i < 0 {
+		return models.Zone{}, mongo.ErrNoDocuments
+	}
+	return r.store.zones[i], nil
+}
+
+//List returns every zone stored
+func (r memoryZoneRepository) List(ctx context.Context) ([]models.Zone, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var result []models.Zone
+	for _, v := range r.store.zones {
+		if within(ctx, v.MazeID) {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+//Update replaces the zone's name, polygon and frame, returning whether anything changed
+func (r memoryZoneRepository) Update(ctx context.Context, id primitive.ObjectID, zone models.Zone) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.store.scopedZone(ctx, id)
+	if i < 0 {
+		return 0, nil
+	}
+	updated := r.store.zones[i]
+	updated.Name, updated.Polygon, updated.OriginRelative = zone.Name, zone.Polygon, zone.OriginRelative
+	var z models.Zone
+	stored(updated, &z)
+	if reflect.DeepEqual(r.store.zones[i], z) {
+		return 0, nil
+	}
+	r.store.zones[i] = z
+	return 1, nil
+}
+
+//Delete deletes the zone with the given ID
+func (r memoryZoneRepository) Delete(ctx context.Context, id primitive.ObjectID) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.store.scopedZone(ctx, id)
+	if i < 0 {
+		return 0, nil
+	}
+	r.store.zones = append(r.store.zones[:i], r.store.zones[i+1:]...)
+	return 1, nil
+}
+
+//DeleteMany deletes the zones matching the filter
+func (r memoryZoneRepository) DeleteMany(ctx context.Context, filter interface{}) (int, error) {
+	filter = scope(ctx, filter)
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	kept := r.store.zones[:0]
+	for _, v := range r.store.zones {
+		if !matches(document(v), filter) {
+			kept = append(kept, v)
+		}
+	}
+	deleted := len(r.store.zones) - len(kept)
+	r.store.zones = kept
+	return deleted, nil
+}
+
+type memoryMazeRepository struct {
+	store *MemoryStore
+}
+
+//NewMemoryMazeRepository creates the maze repository kept in the store
+func NewMemoryMazeRepository(store *MemoryStore) MazeRepository {
+	return memoryMazeRepository{store: store}
+}
+
+func (s *MemoryStore) maze(id primitive.ObjectID) int {
+	for i, v := range s.mazes {
+		if v.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+//Create inserts the maze and returns its ID
+func (r memoryMazeRepository) Create(_ context.Context, maze models.Maze) (string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if maze.ID.IsZero() {
+		maze.ID = primitive.NewObjectID()
+	} else if r.store.maze(maze.ID) >= 0 {
+		return "", ErrDuplicateID
+	}
+	var m models.Maze
+	stored(maze, &m)
+	r.store.mazes = append(r.store.mazes, m)
+	return maze.ID.Hex(), nil
+}
+
+//GetByID returns the maze with the given ID
+func (r memoryMazeRepository) GetByID(_ context.Context, id primitive.ObjectID) (models.Maze, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	i := r.store.maze(id)
+	if i < 0 {
+		return models.Maze{}, mongo.ErrNoDocuments
+	}
+	return r.store.mazes[i], nil
+}
+
+//List returns every maze stored
+func (r memoryMazeRepository) List(_ context.Context) ([]models.Maze, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return append([]models.Maze(nil), r.store.mazes...), nil
+}
+
+//Update replaces the maze's name, description and settings, returning whether anything changed
+func (r memoryMazeRepository) Update(_ context.Context, id primitive.ObjectID, maze models.Maze) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.store.maze(id)
+	if i < 0 {
+		return 0, nil
+	}
+	maze.ID = id
+	var m models.Maze
+	stored(maze, &m)
+	if r.store.mazes[i] == m {
+		return 0, nil
+	}
+	r.store.mazes[i] = m
+	return 1, nil
+}
+
+//Delete deletes the maze with the given ID
+func (r memoryMazeRepository) Delete(_ context.Context, id primitive.ObjectID) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	i := r.store.maze(id)
+	if i < 0 {
+		return 0, nil
+	}
+	r.store.mazes = append(r.store.mazes[:i], r.store.mazes[i+1:]...)
+	return 1, nil
+}
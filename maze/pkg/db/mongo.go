@@ -4,21 +4,28 @@ import (
 	"context"
 	"github.com/avanticaTest/maze/pkg/models"
 	"github.com/go-kit/kit/log"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type DBManager interface {
 	FindOne(ctx context.Context, db, collection string, filter, objective interface{}) error
 	InsertOne(ctx context.Context, db, collection string, doc interface{}) (string, error)
 	UpdateOne(ctx context.Context, filter, update interface{}, db, collection string) (int, error)
+	UpdateMany(ctx context.Context, filter, update interface{}, db, collection string) (int, error)
+	UpsertOne(ctx context.Context, filter, update interface{}, db, collection string) (int, error)
 	DeleteOne(ctx context.Context, filter interface{}, db, collection string) (int, error)
 	DeleteMany(ctx context.Context, filter interface{}, db, col string) (int, error)
-	FindSpots(ctx context.Context, db, col string) (result []models.Spot, err error)
-	FindPaths(ctx context.Context, db, col string) (result []models.Path, err error)
-	FindOrigin(ctx context.Context, db, col string) (result []models.Origin, err error)
-	EstimatedDocumentCount(ctx context.Context, db, collection string) (int, error)
+	FindSpotsByFilter(ctx context.Context, db, col string, filter interface{}) (result []models.Spot, err error)
+	StreamSpots(ctx context.Context, db, col string, filter interface{}, fn func(models.Spot) error) error
+	FindPathsByFilter(ctx context.Context, db, col string, filter interface{}) (result []models.Path, err error)
+	FindOrigin(ctx context.Context, db, col string, filter interface{}) (result []models.Origin, err error)
+	FindZones(ctx context.Context, db, col string, filter interface{}) (result []models.Zone, err error)
+	FindMazes(ctx context.Context, db, col string, filter interface{}) (result []models.Maze, err error)
+	CountDocuments(ctx context.Context, db, collection string, filter interface{}) (int, error)
+	CreateIndex(ctx context.Context, db, col string, keys interface{}) (string, error)
+	CreateUniqueIndex(ctx context.Context, db, col string, keys, partial interface{}) (string, error)
 }
 
 type stubDBManager struct {
@@ -59,19 +66,58 @@ func (s *stubDBManager) InsertOne(ctx context.Context, db, col string, doc inter
 	return oid.Hex(), nil
 }
 
-//FindSpots finds every spot stored and returns them
-func (s *stubDBManager) FindSpots(ctx context.Context, db, col string) (result []models.Spot, err error) {
+//FindSpotsByFilter finds the spots matching the given filter, letting the database do the selection
+func (s *stubDBManager) FindSpotsByFilter(ctx context.Context, db, col string, filter interface{}) (result []models.Spot, err error) {
+
+	err = s.StreamSpots(ctx, db, col, filter, func(spot models.Spot) error {
+		result = append(result, spot)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+//StreamSpots hands the spots matching the given filter to fn one at a time, as the cursor reads them, so they don't
+//have to be held in memory all at once. It stops at the first error returned by fn
+func (s *stubDBManager) StreamSpots(ctx context.Context, db, col string, filter interface{}, fn func(models.Spot) error) error {
 
 	collection := s.client.Database(db).Collection(col)
 
-	cursor, err := collection.Find(ctx, bson.M{})
+	cursor, err := collection.Find(ctx, filter)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer cursor.Close(ctx)
 
 	for cursor.Next(ctx) {
 		var p models.Spot
+		if err := cursor.Decode(&p); err != nil {
+			return err
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+//FindPathsByFilter finds the paths matching the given filter
+func (s *stubDBManager) FindPathsByFilter(ctx context.Context, db, col string, filter interface{}) (result []models.Path, err error) {
+
+	collection := s.client.Database(db).Collection(col)
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var p models.Path
 		cursor.Decode(&p)
 		result = append(result, p)
 	}
@@ -82,19 +128,19 @@ func (s *stubDBManager) FindSpots(ctx context.Context, db, col string) (result [
 	return result, nil
 }
 
-//FindPaths finds every path stored and returns them
-func (s *stubDBManager) FindPaths(ctx context.Context, db, col string) (result []models.Path, err error) {
+//FindOrigin returns every Origin matching the given filter.
+func (s *stubDBManager) FindOrigin(ctx context.Context, db, col string, filter interface{}) (result []models.Origin, err error) {
 
 	collection := s.client.Database(db).Collection(col)
 
-	cursor, err := collection.Find(ctx, bson.M{})
+	cursor, err := collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
 	for cursor.Next(ctx) {
-		var p models.Path
+		var p models.Origin
 		cursor.Decode(&p)
 		result = append(result, p)
 	}
@@ -105,21 +151,48 @@ func (s *stubDBManager) FindPaths(ctx context.Context, db, col string) (result [
 	return result, nil
 }
 
-//FindOrigin returns every Origin stored.
-func (s *stubDBManager) FindOrigin(ctx context.Context, db, col string) (result []models.Origin, err error) {
+//FindZones finds the zones matching the given filter and returns them
+func (s *stubDBManager) FindZones(ctx context.Context, db, col string, filter interface{}) (result []models.Zone, err error) {
 
 	collection := s.client.Database(db).Collection(col)
 
-	cursor, err := collection.Find(ctx, bson.M{})
+	cursor, err := collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
 	for cursor.Next(ctx) {
-		var p models.Origin
-		cursor.Decode(&p)
-		result = append(result, p)
+		var z models.Zone
+		if err := cursor.Decode(&z); err != nil {
+			return nil, err
+		}
+		result = append(result, z)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+//FindMazes finds the mazes matching the given filter and returns them
+func (s *stubDBManager) FindMazes(ctx context.Context, db, col string, filter interface{}) (result []models.Maze, err error) {
+
+	collection := s.client.Database(db).Collection(col)
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var m models.Maze
+		if err := cursor.Decode(&m); err != nil {
+			return nil, err
+		}
+		result = append(result, m)
 	}
 	if err := cursor.Err(); err != nil {
 		return nil, err
@@ -141,6 +214,33 @@ func (s *stubDBManager) UpdateOne(ctx context.Context, filter, update interface{
 	return int(result.ModifiedCount), nil
 }
 
+//UpsertOne updates the object matching a filter, inserting it when there's none. It returns how many objects were
+//modified or inserted
+func (s *stubDBManager) UpsertOne(ctx context.Context, filter, update interface{}, db, col string) (int, error) {
+
+	collection := s.client.Database(db).Collection(col)
+
+	result, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(result.ModifiedCount + result.UpsertedCount), nil
+}
+
+//UpdateMany updates every object matching a filter
+func (s *stubDBManager) UpdateMany(ctx context.Context, filter, update interface{}, db, col string) (int, error) {
+
+	collection := s.client.Database(db).Collection(col)
+
+	result, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(result.ModifiedCount), nil
+}
+
 //DeleteOne deletes one object given a filter
 func (s *stubDBManager) DeleteOne(ctx context.Context, filter interface{}, db, col string) (int, error) {
 
@@ -167,14 +267,32 @@ func (s *stubDBManager) DeleteMany(ctx context.Context, filter interface{}, db,
 	return int(result.DeletedCount), nil
 }
 
-//EstimatedDocumentCount returns the approximate size of a collection
-func (s *stubDBManager) EstimatedDocumentCount(ctx context.Context, db, col string) (int, error){
+//CountDocuments returns how many objects match a filter
+func (s *stubDBManager) CountDocuments(ctx context.Context, db, col string, filter interface{}) (int, error){
 
 	collection := s.client.Database(db).Collection(col)
 
-	r, err := collection.EstimatedDocumentCount(ctx)
+	r, err := collection.CountDocuments(ctx, filter)
 	if err != nil{
 		return 0, err
 	}
 	return int(r),nil
-}
\ No newline at end of file
+}
+
+//CreateIndex creates an index with the given keys, unless it already exists. It returns the index name
+func (s *stubDBManager) CreateIndex(ctx context.Context, db, col string, keys interface{}) (string, error) {
+
+	collection := s.client.Database(db).Collection(col)
+
+	return collection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: keys})
+}
+
+//CreateUniqueIndex creates a unique index with the given keys, unless it already exists. Only the documents matching
+//the partial filter are indexed. It returns the index name
+func (s *stubDBManager) CreateUniqueIndex(ctx context.Context, db, col string, keys, partial interface{}) (string, error) {
+
+	collection := s.client.Database(db).Collection(col)
+
+	opts := options.Index().SetUnique(true).SetPartialFilterExpression(partial)
+	return collection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: keys, Options: opts})
+}
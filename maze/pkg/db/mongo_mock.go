@@ -26,6 +26,15 @@ func (m Mock) UpdateOne(ctx context.Context, filter, update interface{}, db, col
 	return args.Int(0), args.Error(1)
 }
 
+func (m Mock) UpdateMany(ctx context.Context, filter, update interface{}, db, collection string) (int, error){
+	args := m.Called(ctx, filter, update, db, collection)
+	return args.Int(0), args.Error(1)
+}
+
+func (m Mock) UpsertOne(ctx context.Context, filter, update interface{}, db, collection string) (int, error){
+	args := m.Called(ctx, filter, update, db, collection)
+	return args.Int(0), args.Error(1)
+}
 
 func (m Mock) DeleteOne(_ context.Context, _ interface{}, db, collection string) (int, error){
 	args := m.Called( db, collection)
@@ -37,23 +46,69 @@ func (m Mock) DeleteMany(ctx context.Context, filter interface{}, db, col string
 	return args.Int(0), args.Error(1)
 }
 
-func (m Mock) FindSpots(ctx context.Context, db, col string) (result []models.Spot, err error){
-	args := m.Called(ctx,  db, col)
-	return nil, args.Error(1)
+func (m Mock) FindSpotsByFilter(ctx context.Context, db, col string, filter interface{}) (result []models.Spot, err error){
+	args := m.Called(ctx, db, col, filter)
+	if spots, ok := args.Get(0).([]models.Spot); ok {
+		result = spots
+	}
+	return result, args.Error(1)
+}
+
+func (m Mock) StreamSpots(ctx context.Context, db, col string, filter interface{}, fn func(models.Spot) error) error{
+	args := m.Called(ctx, db, col, filter)
+	if spots, ok := args.Get(0).([]models.Spot); ok {
+		for _, v := range spots {
+			if err := fn(v); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m Mock) FindPathsByFilter(ctx context.Context, db, col string, filter interface{}) (result []models.Path, err error){
+	args := m.Called(ctx, db, col, filter)
+	if found, ok := args.Get(0).([]models.Path); ok {
+		result = found
+	}
+	return result, args.Error(1)
+}
+
+func (m Mock) FindOrigin(ctx context.Context, db, col string, filter interface{}) (result []models.Origin, err error){
+	args := m.Called(ctx, db, col, filter)
+	if found, ok := args.Get(0).([]models.Origin); ok {
+		result = found
+	}
+	return result, args.Error(1)
 }
 
-func (m Mock) FindPaths(ctx context.Context, db, col string) (result []models.Path, err error){
-	args := m.Called(ctx,  db, col)
-	return nil, args.Error(1)
+func (m Mock) FindZones(ctx context.Context, db, col string, filter interface{}) (result []models.Zone, err error){
+	args := m.Called(ctx, db, col, filter)
+	if zones, ok := args.Get(0).([]models.Zone); ok {
+		result = zones
+	}
+	return result, args.Error(1)
 }
 
-func (m Mock) FindOrigin(ctx context.Context, db, col string) (result []models.Origin, err error){
-	args := m.Called(ctx, db, col)
-	return nil, args.Error(1)
+func (m Mock) FindMazes(ctx context.Context, db, col string, filter interface{}) (result []models.Maze, err error){
+	args := m.Called(ctx, db, col, filter)
+	if mazes, ok := args.Get(0).([]models.Maze); ok {
+		result = mazes
+	}
+	return result, args.Error(1)
 }
 
-func (m Mock) EstimatedDocumentCount(ctx context.Context, db, collection string) (int, error){
-	args := m.Called(ctx,  db, collection)
+func (m Mock) CountDocuments(ctx context.Context, db, collection string, filter interface{}) (int, error){
+	args := m.Called(ctx, db, collection, filter)
 	return args.Int(0), args.Error(1)
 }
 
+func (m Mock) CreateIndex(ctx context.Context, db, col string, keys interface{}) (string, error){
+	args := m.Called(ctx, db, col, keys)
+	return args.String(0), args.Error(1)
+}
+
+func (m Mock) CreateUniqueIndex(ctx context.Context, db, col string, keys, partial interface{}) (string, error){
+	args := m.Called(ctx, db, col, keys, partial)
+	return args.String(0), args.Error(1)
+}
@@ -0,0 +1,462 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/avanticaTest/maze/pkg/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+//ErrOriginExists is returned when creating the origin of a maze that already has one
+var ErrOriginExists = errors.New("The maze already has an origin")
+
+//ErrPathExists is returned when writing a path between two spots another path of the maze already joins
+var ErrPathExists = errors.New("There's already a path between those spots")
+
+//SpotRepository stores the spots. The filters taken by Find and Stream are the ones built in this package. Like every
+//repository but the maze one, it only sees the maze its context is scoped to with WithMaze
+type SpotRepository interface {
+	Create(ctx context.Context, spot models.Spot) (string, error)
+	GetByID(ctx context.Context, id primitive.ObjectID) (models.Spot, error)
+	GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Spot, error)
+	List(ctx context.Context) ([]models.Spot, error)
+	Find(ctx context.Context, filter interface{}) ([]models.Spot, error)
+	Stream(ctx context.Context, filter interface{}, fn func(models.Spot) error) error
+	Update(ctx context.Context, id primitive.ObjectID, spot models.Spot) (int, error)
+	Delete(ctx context.Context, id primitive.ObjectID) (int, error)
+	DeleteMany(ctx context.Context, filter interface{}) (int, error)
+	ClearClusters(ctx context.Context) error
+	SetCluster(ctx context.Context, ids []primitive.ObjectID, label int) error
+	//Touch bumps the revision of the spots, so a transaction writing a path collides with the one deleting its ends.
+	//It returns how many of them exist
+	Touch(ctx context.Context, ids ...primitive.ObjectID) (int, error)
+}
+
+//PathRepository stores the paths between spots. Create and Update return ErrPathExists when another path of the maze
+//already joins the same spots, whichever way
+type PathRepository interface {
+	Create(ctx context.Context, path models.Path) (string, error)
+	GetByID(ctx context.Context, id primitive.ObjectID) (models.Path, error)
+	List(ctx context.Context) ([]models.Path, error)
+	Update(ctx context.Context, id primitive.ObjectID, path models.Path) (int, error)
+	Delete(ctx context.Context, id primitive.ObjectID) (int, error)
+	Find(ctx context.Context, filter interface{}) ([]models.Path, error)
+	DeleteMany(ctx context.Context, filter interface{}) (int, error)
+	//Detach unsets the spot from the paths it ends, along with their distance, returning how many paths lost an end
+	Detach(ctx context.Context, spot primitive.ObjectID) (int, error)
+	//SetDistances stores the distance of every path given, returning how many changed
+	SetDistances(ctx context.Context, distances map[primitive.ObjectID]float64) (int, error)
+}
+
+//OriginRepository stores the origin. There's a single one per maze, so it isn't looked up by ID: it's stored under
+//the ID of its maze, and Create returns ErrOriginExists when there's one already, however many are created at once
+type OriginRepository interface {
+	Count(ctx context.Context) (int, error)
+	Create(ctx context.Context, origin models.Origin) (string, error)
+	List(ctx context.Context) ([]models.Origin, error)
+	Update(ctx context.Context, origin models.Origin) (int, error)
+	//Replace updates the origin only while it's still the current one, returning zero otherwise
+	Replace(ctx context.Context, current, origin models.Origin) (int, error)
+	Delete(ctx context.Context) (int, error)
+}
+
+//ZoneRepository stores the zones drawn by the users. The quadrants are built in, so they're never stored
+type ZoneRepository interface {
+	Create(ctx context.Context, zone models.Zone) (string, error)
+	GetByID(ctx context.Context, id primitive.ObjectID) (models.Zone, error)
+	List(ctx context.Context) ([]models.Zone, error)
+	Update(ctx context.Context, id primitive.ObjectID, zone models.Zone) (int, error)
+	Delete(ctx context.Context, id primitive.ObjectID) (int, error)
+	DeleteMany(ctx context.Context, filter interface{}) (int, error)
+}
+
+//MazeRepository stores the mazes. Mazes aren't scoped, every one of them is seen whatever the context
+type MazeRepository interface {
+	Create(ctx context.Context, maze models.Maze) (string, error)
+	GetByID(ctx context.Context, id primitive.ObjectID) (models.Maze, error)
+	List(ctx context.Context) ([]models.Maze, error)
+	Update(ctx context.Context, id primitive.ObjectID, maze models.Maze) (int, error)
+	Delete(ctx context.Context, id primitive.ObjectID) (int, error)
+}
+
+//Collections the repositories keep their documents in by default
+const (
+	SpotsCollection  = "spots"
+	PathsCollection  = "paths"
+	OriginCollection = "origin"
+	ZonesCollection  = "zones"
+	MazesCollection  = "mazes"
+)
+
+type mongoSpotRepository struct {
+	db         DBManager
+	database   string
+	collection string
+}
+
+//NewSpotRepository creates the spot repository stored in the given MongoDB database and collection
+func NewSpotRepository(db DBManager, database, collection string) SpotRepository {
+	return mongoSpotRepository{db: db, database: database, collection: collection}
+}
+
+//Create inserts the spot and returns its ID
+func (r mongoSpotRepository) Create(ctx context.Context, spot models.Spot) (string, error) {
+	spot.MazeID = owner(ctx, spot.MazeID)
+	return r.db.InsertOne(ctx, r.database, r.collection, spot)
+}
+
+//GetByID returns the spot with the given ID
+func (r mongoSpotRepository) GetByID(ctx context.Context, id primitive.ObjectID) (models.Spot, error) {
+	var spot models.Spot
+	if err := r.db.FindOne(ctx, r.database, r.collection, scope(ctx, models.Spot{ID: id}), &spot); err != nil {
+		return models.Spot{}, err
+	}
+	return spot, nil
+}
+
+//GetByIDs returns the spots with the given IDs, leaving out the ones that don't exist
+func (r mongoSpotRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Spot, error) {
+	return r.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+//List returns every spot
+func (r mongoSpotRepository) List(ctx context.Context) ([]models.Spot, error) {
+	return r.Find(ctx, bson.M{})
+}
+
+//Find returns the spots matching the filter
+func (r mongoSpotRepository) Find(ctx context.Context, filter interface{}) ([]models.Spot, error) {
+	return r.db.FindSpotsByFilter(ctx, r.database, r.collection, scope(ctx, filter))
+}
+
+//Stream hands the spots matching the filter to fn one at a time, stopping at the first error it returns
+func (r mongoSpotRepository) Stream(ctx context.Context, filter interface{}, fn func(models.Spot) error) error {
+	return r.db.StreamSpots(ctx, r.database, r.collection, scope(ctx, filter), fn)
+}
+
+//Update replaces the spot's coordinates, name and number. Its cluster label is left alone, it's only set by
+//SetCluster
+func (r mongoSpotRepository) Update(ctx context.Context, id primitive.ObjectID, spot models.Spot) (int, error) {
+	update := bson.M{"$set": bson.M{"x_coordinate": spot.XCoordinate,
+		"y_coordinate": spot.YCoordinate,
+		"z_coordinate": spot.ZCoordinate,
+		"name":         spot.Name,
+		"number":       spot.Number}}
+	return r.db.UpdateOne(ctx, scope(ctx, bson.M{"_id": id}), update, r.database, r.collection)
+}
+
+//Delete deletes the spot with the given ID
+func (r mongoSpotRepository) Delete(ctx context.Context, id primitive.ObjectID) (int, error) {
+	return r.db.DeleteOne(ctx, scope(ctx, bson.M{"_id": id}), r.database, r.collection)
+}
+
+//DeleteMany deletes the spots matching the filter
+func (r mongoSpotRepository) DeleteMany(ctx context.Context, filter interface{}) (int, error) {
+	return r.db.DeleteMany(ctx, scope(ctx, filter), r.database, r.collection)
+}
+
+//ClearClusters removes the cluster label of every spot
+func (r mongoSpotRepository) ClearClusters(ctx context.Context) error {
+	update := bson.M{"$unset": bson.M{"cluster": ""}}
+	_, err := r.db.UpdateMany(ctx, scope(ctx, bson.M{}), update, r.database, r.collection)
+	return err
+}
+
+//SetCluster labels the spots with the given IDs with the cluster, in a single update
+func (r mongoSpotRepository) SetCluster(ctx context.Context, ids []primitive.ObjectID, label int) error {
+	filter := scope(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	_, err := r.db.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"cluster": label}}, r.database, r.collection)
+	return err
+}
+
+//Touch bumps the revision of the spots in a single update
+func (r mongoSpotRepository) Touch(ctx context.Context, ids ...primitive.ObjectID) (int, error) {
+	filter := scope(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	return r.db.UpdateMany(ctx, filter, bson.M{"$inc": bson.M{"revision": 1}}, r.database, r.collection)
+}
+
+type mongoPathRepository struct {
+	db         DBManager
+	database   string
+	collection string
+}
+
+//NewPathRepository creates the path repository stored in the given MongoDB database and collection
+func NewPathRepository(db DBManager, database, collection string) PathRepository {
+	return mongoPathRepository{db: db, database: database, collection: collection}
+}
+
+//Create inserts the path and returns its ID. The unique index on the maze and the path's ends turns away the
+//duplicates, however many are created at once
+func (r mongoPathRepository) Create(ctx context.Context, path models.Path) (string, error) {
+	path.MazeID = owner(ctx, path.MazeID)
+	path.Ends = pathEnds(path)
+	id, err := r.db.InsertOne(ctx, r.database, r.collection, path)
+	if duplicateKey(err) {
+		return "", ErrPathExists
+	}
+	return id, err
+}
+
+//GetByID returns the path with the given ID
+func (r mongoPathRepository) GetByID(ctx context.Context, id primitive.ObjectID) (models.Path, error) {
+	var path models.Path
+	if err := r.db.FindOne(ctx, r.database, r.collection, scope(ctx, models.Path{ID: id}), &path); err != nil {
+		return models.Path{}, err
+	}
+	return path, nil
+}
+
+//List returns every path
+func (r mongoPathRepository) List(ctx context.Context) ([]models.Path, error) {
+	return r.Find(ctx, bson.M{})
+}
+
+//Update replaces the spots the path joins and its distance
+func (r mongoPathRepository) Update(ctx context.Context, id primitive.ObjectID, path models.Path) (int, error) {
+	update := bson.M{"$set": bson.M{"point_a": path.PointA,
+		"point_b":  path.PointB,
+		"distance": path.Distance,
+		"ends":     pathEnds(path)}}
+	modified, err := r.db.UpdateOne(ctx, scope(ctx, bson.M{"_id": id}), update, r.database, r.collection)
+	if duplicateKey(err) {
+		return 0, ErrPathExists
+	}
+	return modified, err
+}
+
+//Delete deletes the path with the given ID
+func (r mongoPathRepository) Delete(ctx context.Context, id primitive.ObjectID) (int, error) {
+	return r.db.DeleteOne(ctx, scope(ctx, bson.M{"_id": id}), r.database, r.collection)
+}
+
+//Find returns the paths matching the filter
+func (r mongoPathRepository) Find(ctx context.Context, filter interface{}) ([]models.Path, error) {
+	return r.db.FindPathsByFilter(ctx, r.database, r.collection, scope(ctx, filter))
+}
+
+//DeleteMany deletes the paths matching the filter
+func (r mongoPathRepository) DeleteMany(ctx context.Context, filter interface{}) (int, error) {
+	return r.db.DeleteMany(ctx, scope(ctx, filter), r.database, r.collection)
+}
+
+//Detach unsets the spot from the paths it ends, along with their distance
+func (r mongoPathRepository) Detach(ctx context.Context, spot primitive.ObjectID) (int, error) {
+	detached := 0
+	for _, end := range []string{"point_a", "point_b"} {
+		update := bson.M{"$unset": bson.M{end: "", "distance": "", "ends": ""}}
+		result, err := r.db.UpdateMany(ctx, scope(ctx, bson.M{end: spot}), update, r.database, r.collection)
+		if err != nil {
+			return detached, err
+		}
+		detached += result
+	}
+	return detached, nil
+}
+
+//SetDistances stores the distance of every path given, one update each
+func (r mongoPathRepository) SetDistances(ctx context.Context, distances map[primitive.ObjectID]float64) (int, error) {
+	modified := 0
+	for id, distance := range distances {
+		update := bson.M{"$set": bson.M{"distance": distance}}
+		result, err := r.db.UpdateOne(ctx, scope(ctx, bson.M{"_id": id}), update, r.database, r.collection)
+		if err != nil {
+			return modified, err
+		}
+		modified += result
+	}
+	return modified, nil
+}
+
+type mongoOriginRepository struct {
+	db         DBManager
+	database   string
+	collection string
+}
+
+//NewOriginRepository creates the origin repository stored in the given MongoDB database and collection
+func NewOriginRepository(db DBManager, database, collection string) OriginRepository {
+	return mongoOriginRepository{db: db, database: database, collection: collection}
+}
+
+//Count returns how many origins there are
+func (r mongoOriginRepository) Count(ctx context.Context) (int, error) {
+	return r.db.CountDocuments(ctx, r.database, r.collection, scope(ctx, bson.M{}))
+}
+
+//Create inserts the origin under the ID of its maze and returns it. The insert is an upsert that leaves any origin of
+//the maze alone, and the unique index on the ID turns away the concurrent ones
+func (r mongoOriginRepository) Create(ctx context.Context, origin models.Origin) (string, error) {
+	origin.MazeID = owner(ctx, origin.MazeID)
+	key := originKey(origin)
+	doc := document(origin)
+	doc["_id"] = key
+
+	//an origin stored before they were keyed by maze still counts
+	filter := bson.M{"$or": bson.A{bson.M{"_id": key}, scope(ctx, bson.M{})}}
+	inserted, err := r.db.UpsertOne(ctx, filter, bson.M{"$setOnInsert": doc}, r.database, r.collection)
+	if duplicateKey(err) || (err == nil && inserted == 0) {
+		return "", ErrOriginExists
+	}
+	if err != nil {
+		return "", err
+	}
+	return key.Hex(), nil
+}
+
+//List returns every origin stored
+func (r mongoOriginRepository) List(ctx context.Context) ([]models.Origin, error) {
+	return r.db.FindOrigin(ctx, r.database, r.collection, scope(ctx, bson.M{}))
+}
+
+//Update replaces the origin's coordinates, rotation and scales
+func (r mongoOriginRepository) Update(ctx context.Context, origin models.Origin) (int, error) {
+	return r.db.UpdateOne(ctx, scope(ctx, bson.M{}), originUpdate(origin), r.database, r.collection)
+}
+
+//Replace replaces the origin's coordinates, rotation and scales as long as they're still the current ones
+func (r mongoOriginRepository) Replace(ctx context.Context, current, origin models.Origin) (int, error) {
+	filter := scope(ctx, OriginFilter(current))
+	return r.db.UpdateOne(ctx, filter, originUpdate(origin), r.database, r.collection)
+}
+
+//Delete deletes the origin
+func (r mongoOriginRepository) Delete(ctx context.Context) (int, error) {
+	return r.db.DeleteMany(ctx, scope(ctx, bson.M{}), r.database, r.collection)
+}
+
+//originKey returns the ID the origin is stored under, the one of its maze
+func originKey(origin models.Origin) primitive.ObjectID {
+	return origin.MazeID
+}
+
+//pathEnds returns the spots the path joins, the same whichever way it's walked. The paths missing an end have none,
+//so they're left out of the unique index
+func pathEnds(path models.Path) string {
+	if path.PointA.IsZero() || path.PointB.IsZero() {
+		return ""
+	}
+	a, b := path.PointA.Hex(), path.PointB.Hex()
+	if b < a {
+		a, b = b, a
+	}
+	return a + "-" + b
+}
+
+//duplicateKey tells whether the write was turned away by a unique index
+func duplicateKey(err error) bool {
+	switch e := err.(type) {
+	case mongo.WriteException:
+		for _, we := range e.WriteErrors {
+			if we.Code == 11000 {
+				return true
+			}
+		}
+	case mongo.CommandError:
+		return e.Code == 11000
+	}
+	return false
+}
+
+func originUpdate(origin models.Origin) bson.M {
+	return bson.M{"$set": bson.M{"x_origin": origin.XOrigin,
+		"y_origin": origin.YOrigin,
+		"rotation": origin.Rotation,
+		"x_scale":  origin.XScale,
+		"y_scale":  origin.YScale}}
+}
+
+type mongoZoneRepository struct {
+	db         DBManager
+	database   string
+	collection string
+}
+
+//NewZoneRepository creates the zone repository stored in the given MongoDB database and collection
+func NewZoneRepository(db DBManager, database, collection string) ZoneRepository {
+	return mongoZoneRepository{db: db, database: database, collection: collection}
+}
+
+//Create inserts the zone and returns its ID
+func (r mongoZoneRepository) Create(ctx context.Context, zone models.Zone) (string, error) {
+	zone.MazeID = owner(ctx, zone.MazeID)
+	return r.db.InsertOne(ctx, r.database, r.collection, zone)
+}
+
+//GetByID returns the zone with the given ID
+func (r mongoZoneRepository) GetByID(ctx context.Context, id primitive.ObjectID) (models.Zone, error) {
+	var zone models.Zone
+	if err := r.db.FindOne(ctx, r.database, r.collection, scope(ctx, models.Zone{ID: id}), &zone); err != nil {
+		return models.Zone{}, err
+	}
+	return zone, nil
+}
+
+//List returns every zone stored
+func (r mongoZoneRepository) List(ctx context.Context) ([]models.Zone, error) {
+	return r.db.FindZones(ctx, r.database, r.collection, scope(ctx, bson.M{}))
+}
+
+//Update replaces the zone's name, polygon and frame
+func (r mongoZoneRepository) Update(ctx context.Context, id primitive.ObjectID, zone models.Zone) (int, error) {
+	update := bson.M{"$set": bson.M{"name": zone.Name,
+		"polygon":         zone.Polygon,
+		"origin_relative": zone.OriginRelative}}
+	return r.db.UpdateOne(ctx, scope(ctx, bson.M{"_id": id}), update, r.database, r.collection)
+}
+
+//Delete deletes the zone with the given ID
+func (r mongoZoneRepository) Delete(ctx context.Context, id primitive.ObjectID) (int, error) {
+	return r.db.DeleteOne(ctx, scope(ctx, bson.M{"_id": id}), r.database, r.collection)
+}
+
+//DeleteMany deletes the zones matching the filter
+func (r mongoZoneRepository) DeleteMany(ctx context.Context, filter interface{}) (int, error) {
+	return r.db.DeleteMany(ctx, scope(ctx, filter), r.database, r.collection)
+}
+
+type mongoMazeRepository struct {
+	db         DBManager
+	database   string
+	collection string
+}
+
+//NewMazeRepository creates the maze repository stored in the given MongoDB database and collection
+func NewMazeRepository(db DBManager, database, collection string) MazeRepository {
+	return mongoMazeRepository{db: db, database: database, collection: collection}
+}
+
+//Create inserts the maze and returns its ID
+func (r mongoMazeRepository) Create(ctx context.Context, maze models.Maze) (string, error) {
+	return r.db.InsertOne(ctx, r.database, r.collection, maze)
+}
+
+//GetByID returns the maze with the given ID
+func (r mongoMazeRepository) GetByID(ctx context.Context, id primitive.ObjectID) (models.Maze, error) {
+	var maze models.Maze
+	if err := r.db.FindOne(ctx, r.database, r.collection, models.Maze{ID: id}, &maze); err != nil {
+		return models.Maze{}, err
+	}
+	return maze, nil
+}
+
+//List returns every maze stored
+func (r mongoMazeRepository) List(ctx context.Context) ([]models.Maze, error) {
+	return r.db.FindMazes(ctx, r.database, r.collection, bson.M{})
+}
+
+//Update replaces the maze's name, description and settings
+func (r mongoMazeRepository) Update(ctx context.Context, id primitive.ObjectID, maze models.Maze) (int, error) {
+	update := bson.M{"$set": bson.M{"name": maze.Name,
+		"description": maze.Description,
+		"coordinates": maze.Coordinates,
+		"metric":      maze.Metric}}
+	return r.db.UpdateOne(ctx, bson.M{"_id": id}, update, r.database, r.collection)
+}
+
+//Delete deletes the maze with the given ID, leaving alone what's stored in it
+func (r mongoMazeRepository) Delete(ctx context.Context, id primitive.ObjectID) (int, error) {
+	return r.db.DeleteOne(ctx, bson.M{"_id": id}, r.database, r.collection)
+}
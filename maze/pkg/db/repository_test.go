@@ -0,0 +1,587 @@
+package db
+
+import (
+	"context"
+	"github.com/avanticaTest/maze/pkg/models"
+	"github.com/go-kit/kit/log"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+//repositories are the repositories of one storage backend
+type repositories struct {
+	spots   SpotRepository
+	paths   PathRepository
+	origins OriginRepository
+	zones   ZoneRepository
+	mazes   MazeRepository
+	legacy  LegacyRepository
+}
+
+//eachBackend runs the test against an empty storage of every backend. MongoDB is only tested when
+//MAZE_TEST_MONGO_URI points to a server, in a database of its own which is dropped afterwards
+func eachBackend(t *testing.T, test func(t *testing.T, r repositories)) {
+
+	t.Run("memory", func(t *testing.T) {
+		store := NewMemoryStore()
+		test(t, repositories{
+			spots:   NewMemorySpotRepository(store),
+			paths:   NewMemoryPathRepository(store),
+			origins: NewMemoryOriginRepository(store),
+			zones:   NewMemoryZoneRepository(store),
+			mazes:   NewMemoryMazeRepository(store),
+			legacy:  NewMemoryLegacyRepository(store),
+		})
+	})
+
+	t.Run("bolt", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "maze")
+		assert.NilError(t, err)
+		defer os.RemoveAll(dir)
+		store, err := OpenBoltStore(filepath.Join(dir, "maze.db"))
+		assert.NilError(t, err)
+		defer store.Close()
+		test(t, repositories{
+			spots:   NewBoltSpotRepository(store),
+			paths:   NewBoltPathRepository(store),
+			origins: NewBoltOriginRepository(store),
+			zones:   NewBoltZoneRepository(store),
+			mazes:   NewBoltMazeRepository(store),
+			legacy:  NewBoltLegacyRepository(store),
+		})
+	})
+
+	t.Run("mongo", func(t *testing.T) {
+		uri := os.Getenv("MAZE_TEST_MONGO_URI")
+		if uri == "" {
+			t.Skip("MAZE_TEST_MONGO_URI isn't set")
+		}
+		ctx := context.Background()
+		settings := MongoSettings{
+			URI:         uri,
+			Database:    "maze_test_" + primitive.NewObjectID().Hex(),
+			Collections: DefaultCollections(),
+		}
+		opts, err := settings.ClientOptions()
+		assert.NilError(t, err)
+		client, err := mongo.Connect(ctx, opts)
+		assert.NilError(t, err)
+		defer client.Disconnect(ctx)
+		defer client.Database(settings.Database).Drop(ctx)
+		manager := New(client, log.NewNopLogger())
+		test(t, repositories{
+			spots:   NewSpotRepository(manager, settings.Database, settings.Collections.Spots),
+			paths:   NewPathRepository(manager, settings.Database, settings.Collections.Paths),
+			origins: NewOriginRepository(manager, settings.Database, settings.Collections.Origin),
+			zones:   NewZoneRepository(manager, settings.Database, settings.Collections.Zones),
+			mazes:   NewMazeRepository(manager, settings.Database, settings.Collections.Mazes),
+			legacy:  NewLegacyRepository(manager, settings.Database, settings.Collections),
+		})
+	})
+}
+
+func TestFilters(t *testing.T) {
+
+	ctx := context.Background()
+	ground := 0.0
+	radius := bson.M{"$expr": bson.M{"$lte": bson.A{
+		bson.M{"$add": bson.A{
+			bson.M{"$pow": bson.A{CoordinateExpr("x_coordinate"), 2}},
+			bson.M{"$pow": bson.A{CoordinateExpr("y_coordinate"), 2}},
+		}},
+		4.0,
+	}}}
+
+	tests := []struct {
+		name     string
+		filter   interface{}
+		expected []string
+	}{
+		{
+			name:     "Everything",
+			filter:   bson.M{},
+			expected: []string{"origin", "east", "north east", "upstairs"},
+		},
+		{
+			name:     "Missing coordinates are zero",
+			filter:   BoxFilter(0, 0, 2, 1),
+			expected: []string{"origin", "east"},
+		},
+		{
+			name:     "Ground floor",
+			filter:   OnFloor(bson.M{}, &ground),
+			expected: []string{"origin", "east", "north east"},
+		},
+		{
+			name:     "Quadrant",
+			filter:   QuadrantFilter(models.Origin{}, "upper_left"),
+			expected: []string{"origin", "upstairs"},
+		},
+		{
+			name:     "Expression",
+			filter:   radius,
+			expected: []string{"origin", "east", "upstairs"},
+		},
+		{
+			name:     "Comparisons skip missing fields",
+			filter:   bson.M{"y_coordinate": bson.M{"$lt": 2.0}},
+			expected: []string{"upstairs"},
+		},
+	}
+
+	eachBackend(t, func(t *testing.T, r repositories) {
+		for _, v := range []models.Spot{
+			{Name: "origin"},
+			{Name: "east", XCoordinate: 2},
+			{Name: "north east", XCoordinate: 2, YCoordinate: 2},
+			{Name: "upstairs", XCoordinate: -1, YCoordinate: 1, ZCoordinate: 1},
+		} {
+			_, err := r.spots.Create(ctx, v)
+			assert.NilError(t, err)
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				result, err := r.spots.Find(ctx, tt.filter)
+				assert.NilError(t, err)
+				var names []string
+				for _, v := range result {
+					names = append(names, v.Name)
+				}
+				assert.DeepEqual(t, tt.expected, names)
+			})
+		}
+	})
+}
+
+func TestSpotRepository(t *testing.T) {
+
+	ctx := context.Background()
+	eachBackend(t, func(t *testing.T, r repositories) {
+		hex, err := r.spots.Create(ctx, models.Spot{Name: "a", XCoordinate: 1, Zones: []string{"upper_right"}})
+		assert.NilError(t, err)
+		id, err := primitive.ObjectIDFromHex(hex)
+		assert.NilError(t, err)
+		spot, err := r.spots.GetByID(ctx, id)
+		assert.NilError(t, err)
+		//the zones aren't stored
+		assert.DeepEqual(t, models.Spot{ID: id, Name: "a", XCoordinate: 1}, spot)
+
+		//the cluster label survives the updates, which only count when something changes
+		assert.NilError(t, r.spots.SetCluster(ctx, []primitive.ObjectID{id}, 2))
+		modified, err := r.spots.Update(ctx, id, models.Spot{Name: "a", XCoordinate: 1})
+		assert.NilError(t, err)
+		assert.Equal(t, 0, modified)
+		modified, err = r.spots.Update(ctx, id, models.Spot{Name: "b"})
+		assert.NilError(t, err)
+		assert.Equal(t, 1, modified)
+		labelled, err := r.spots.Find(ctx, bson.M{"cluster": 2})
+		assert.NilError(t, err)
+		assert.Equal(t, 1, len(labelled))
+		assert.Equal(t, "b", labelled[0].Name)
+
+		assert.NilError(t, r.spots.ClearClusters(ctx))
+		labelled, err = r.spots.Find(ctx, bson.M{"cluster": bson.M{"$exists": true}})
+		assert.NilError(t, err)
+		assert.Equal(t, 0, len(labelled))
+
+		found, err := r.spots.GetByIDs(ctx, []primitive.ObjectID{id, primitive.NewObjectID()})
+		assert.NilError(t, err)
+		assert.Equal(t, 1, len(found))
+
+		touched, err := r.spots.Touch(ctx, id, primitive.NewObjectID())
+		assert.NilError(t, err)
+		assert.Equal(t, 1, touched)
+		spot, err = r.spots.GetByID(ctx, id)
+		assert.NilError(t, err)
+		assert.Equal(t, 1, spot.Revision)
+
+		deleted, err := r.spots.Delete(ctx, id)
+		assert.NilError(t, err)
+		assert.Equal(t, 1, deleted)
+		_, err = r.spots.GetByID(ctx, id)
+		assert.Equal(t, mongo.ErrNoDocuments, err)
+	})
+}
+
+func TestPathRepository(t *testing.T) {
+
+	ctx := context.Background()
+	eachBackend(t, func(t *testing.T, r repositories) {
+		a, b := primitive.NewObjectID(), primitive.NewObjectID()
+		hex, err := r.paths.Create(ctx, models.Path{PointA: a, PointB: b, Distance: 1})
+		assert.NilError(t, err)
+		id, err := primitive.ObjectIDFromHex(hex)
+		assert.NilError(t, err)
+
+		modified, err := r.paths.Update(ctx, id, models.Path{PointA: a, PointB: b, Distance: 2})
+		assert.NilError(t, err)
+		assert.Equal(t, 1, modified)
+		path, err := r.paths.GetByID(ctx, id)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, models.Path{ID: id, PointA: a, PointB: b, Distance: 2, Ends: pathEnds(path)}, path)
+
+		//no two paths of a maze join the same spots, whichever way, while other mazes may
+		_, err = r.paths.Create(ctx, models.Path{PointA: b, PointB: a})
+		assert.Equal(t, ErrPathExists, err)
+		c := primitive.NewObjectID()
+		other, err := r.paths.Create(ctx, models.Path{PointA: a, PointB: c})
+		assert.NilError(t, err)
+		otherID, err := primitive.ObjectIDFromHex(other)
+		assert.NilError(t, err)
+		_, err = r.paths.Update(ctx, otherID, models.Path{PointA: b, PointB: a})
+		assert.Equal(t, ErrPathExists, err)
+		_, err = r.paths.Create(WithMaze(ctx, primitive.NewObjectID()), models.Path{PointA: a, PointB: b})
+		assert.NilError(t, err)
+
+		//the detached paths are left out
+		_, err = r.paths.Detach(ctx, c)
+		assert.NilError(t, err)
+		_, err = r.paths.Create(ctx, models.Path{PointA: a})
+		assert.NilError(t, err)
+
+		deleted, err := r.paths.Delete(ctx, id)
+		assert.NilError(t, err)
+		assert.Equal(t, 1, deleted)
+		result, err := r.paths.List(ctx)
+		assert.NilError(t, err)
+		assert.Equal(t, 3, len(result))
+	})
+}
+
+func TestPathsOfSpot(t *testing.T) {
+
+	ctx := context.Background()
+	eachBackend(t, func(t *testing.T, r repositories) {
+		a, b, c := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+		for _, v := range []models.Path{
+			{PointA: a, PointB: b, Distance: 1},
+			{PointA: c, PointB: a, Distance: 2},
+			{PointA: b, PointB: c, Distance: 3},
+		} {
+			_, err := r.paths.Create(ctx, v)
+			assert.NilError(t, err)
+		}
+
+		joined, err := r.paths.Find(ctx, PathsOf(a))
+		assert.NilError(t, err)
+		assert.Equal(t, 2, len(joined))
+		between, err := r.paths.Find(ctx, PathBetween(a, c))
+		assert.NilError(t, err)
+		assert.Equal(t, 1, len(between))
+		assert.Equal(t, 2.0, between[0].Distance)
+
+		//the detached paths keep their other end only
+		detached, err := r.paths.Detach(ctx, a)
+		assert.NilError(t, err)
+		assert.Equal(t, 2, detached)
+		result, err := r.paths.List(ctx)
+		assert.NilError(t, err)
+		var ends [][2]primitive.ObjectID
+		for _, v := range result {
+			if v.PointA.IsZero() || v.PointB.IsZero() {
+				assert.Equal(t, 0.0, v.Distance)
+			}
+			ends = append(ends, [2]primitive.ObjectID{v.PointA, v.PointB})
+		}
+		assert.DeepEqual(t, [][2]primitive.ObjectID{{{}, b}, {c, {}}, {b, c}}, ends)
+
+		distances := map[primitive.ObjectID]float64{}
+		for _, v := range result {
+			distances[v.ID] = v.Distance
+		}
+		distances[result[2].ID] = 4
+		modified, err := r.paths.SetDistances(ctx, distances)
+		assert.NilError(t, err)
+		assert.Equal(t, 1, modified)
+		path, err := r.paths.GetByID(ctx, result[2].ID)
+		assert.NilError(t, err)
+		assert.Equal(t, 4.0, path.Distance)
+
+		deleted, err := r.paths.DeleteMany(ctx, PathsOf(c))
+		assert.NilError(t, err)
+		assert.Equal(t, 2, deleted)
+		result, err = r.paths.List(ctx)
+		assert.NilError(t, err)
+		assert.Equal(t, 1, len(result))
+	})
+}
+
+func TestZoneRepository(t *testing.T) {
+
+	ctx := context.Background()
+	eachBackend(t, func(t *testing.T, r repositories) {
+		polygon := []models.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}}
+		hex, err := r.zones.Create(ctx, models.Zone{Name: "hall", Polygon: polygon})
+		assert.NilError(t, err)
+		id, err := primitive.ObjectIDFromHex(hex)
+		assert.NilError(t, err)
+
+		modified, err := r.zones.Update(ctx, id, models.Zone{Name: "lobby", Polygon: polygon, OriginRelative: true})
+		assert.NilError(t, err)
+		assert.Equal(t, 1, modified)
+		result, err := r.zones.List(ctx)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []models.Zone{{ID: id, Name: "lobby", Polygon: polygon, OriginRelative: true}}, result)
+
+		deleted, err := r.zones.Delete(ctx, id)
+		assert.NilError(t, err)
+		assert.Equal(t, 1, deleted)
+		_, err = r.zones.GetByID(ctx, id)
+		assert.Equal(t, mongo.ErrNoDocuments, err)
+	})
+}
+
+func TestOriginRepository(t *testing.T) {
+
+	ctx := context.Background()
+	eachBackend(t, func(t *testing.T, r repositories) {
+		_, err := r.origins.Create(ctx, models.Origin{XOrigin: 1})
+		assert.NilError(t, err)
+		_, err = r.origins.Create(ctx, models.Origin{XOrigin: 2})
+		assert.Equal(t, ErrOriginExists, err)
+
+		//only the origin the change was computed from can be replaced
+		modified, err := r.origins.Replace(ctx, models.Origin{XOrigin: 2}, models.Origin{XOrigin: 3})
+		assert.NilError(t, err)
+		assert.Equal(t, 0, modified)
+		modified, err = r.origins.Replace(ctx, models.Origin{XOrigin: 1}, models.Origin{XOrigin: 3})
+		assert.NilError(t, err)
+		assert.Equal(t, 1, modified)
+		result, err := r.origins.List(ctx)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []models.Origin{{XOrigin: 3}}, result)
+
+		modified, err = r.origins.Update(ctx, models.Origin{YOrigin: 1, Rotation: 90})
+		assert.NilError(t, err)
+		assert.Equal(t, 1, modified)
+		result, err = r.origins.List(ctx)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []models.Origin{{YOrigin: 1, Rotation: 90}}, result)
+
+		deleted, err := r.origins.Delete(ctx)
+		assert.NilError(t, err)
+		assert.Equal(t, 1, deleted)
+		count, err := r.origins.Count(ctx)
+		assert.NilError(t, err)
+		assert.Equal(t, 0, count)
+	})
+}
+
+func TestCreateOriginConcurrently(t *testing.T) {
+
+	maze := primitive.NewObjectID()
+	ctx := WithMaze(context.Background(), maze)
+	eachBackend(t, func(t *testing.T, r repositories) {
+		const creates = 20
+		errs := make(chan error, creates)
+		for i := 0; i < creates; i++ {
+			go func(i int) {
+				_, err := r.origins.Create(ctx, models.Origin{XOrigin: float64(i + 1)})
+				errs <- err
+			}(i)
+		}
+
+		created := 0
+		for i := 0; i < creates; i++ {
+			err := <-errs
+			if err == nil {
+				created++
+				continue
+			}
+			assert.Equal(t, ErrOriginExists, err)
+		}
+		assert.Equal(t, 1, created)
+
+		result, err := r.origins.List(ctx)
+		assert.NilError(t, err)
+		assert.Equal(t, 1, len(result))
+		assert.Equal(t, maze, result[0].MazeID)
+	})
+}
+
+func TestMazeRepository(t *testing.T) {
+
+	ctx := context.Background()
+	eachBackend(t, func(t *testing.T, r repositories) {
+		result, err := r.mazes.List(ctx)
+		assert.NilError(t, err)
+		assert.Equal(t, 0, len(result))
+
+		created, err := r.mazes.Create(ctx, models.Maze{Name: "office", Coordinates: models.Cartesian})
+		assert.NilError(t, err)
+		id, err := primitive.ObjectIDFromHex(created)
+		assert.NilError(t, err)
+
+		maze := models.Maze{Name: "office", Description: "second floor", Coordinates: models.Cartesian, Metric: models.Manhattan}
+		modified, err := r.mazes.Update(ctx, id, maze)
+		assert.NilError(t, err)
+		assert.Equal(t, 1, modified)
+		modified, err = r.mazes.Update(ctx, id, maze)
+		assert.NilError(t, err)
+		assert.Equal(t, 0, modified)
+		maze.ID = id
+		result, err = r.mazes.List(ctx)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []models.Maze{maze}, result)
+
+		deleted, err := r.mazes.Delete(ctx, id)
+		assert.NilError(t, err)
+		assert.Equal(t, 1, deleted)
+		_, err = r.mazes.GetByID(ctx, id)
+		assert.Equal(t, mongo.ErrNoDocuments, err)
+	})
+}
+
+func TestMazeIsolation(t *testing.T) {
+
+	first, second := primitive.NewObjectID(), primitive.NewObjectID()
+	inFirst := WithMaze(context.Background(), first)
+	inSecond := WithMaze(context.Background(), second)
+	eachBackend(t, func(t *testing.T, r repositories) {
+		created, err := r.spots.Create(inFirst, models.Spot{Name: "lobby"})
+		assert.NilError(t, err)
+		spot, err := primitive.ObjectIDFromHex(created)
+		assert.NilError(t, err)
+		_, err = r.spots.Create(inSecond, models.Spot{Name: "hall"})
+		assert.NilError(t, err)
+		_, err = r.origins.Create(inFirst, models.Origin{XOrigin: 1})
+		assert.NilError(t, err)
+		_, err = r.origins.Create(inSecond, models.Origin{XOrigin: 2})
+		assert.NilError(t, err)
+
+		spots, err := r.spots.List(inSecond)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []models.Spot{{ID: spots[0].ID, Name: "hall", MazeID: second}}, spots)
+		origins, err := r.origins.List(inSecond)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []models.Origin{{XOrigin: 2, MazeID: second}}, origins)
+
+		//the other maze's spot can't be read, changed nor deleted
+		_, err = r.spots.GetByID(inSecond, spot)
+		assert.Equal(t, mongo.ErrNoDocuments, err)
+		modified, err := r.spots.Update(inSecond, spot, models.Spot{Name: "moved"})
+		assert.NilError(t, err)
+		assert.Equal(t, 0, modified)
+		deleted, err := r.spots.Delete(inSecond, spot)
+		assert.NilError(t, err)
+		assert.Equal(t, 0, deleted)
+
+		modified, err = r.origins.Update(inSecond, models.Origin{XOrigin: 3})
+		assert.NilError(t, err)
+		assert.Equal(t, 1, modified)
+		deleted, err = r.origins.Delete(inSecond)
+		assert.NilError(t, err)
+		assert.Equal(t, 1, deleted)
+		deleted, err = r.spots.DeleteMany(inSecond, bson.M{})
+		assert.NilError(t, err)
+		assert.Equal(t, 1, deleted)
+
+		spots, err = r.spots.List(inFirst)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []models.Spot{{ID: spot, Name: "lobby", MazeID: first}}, spots)
+		origins, err = r.origins.List(inFirst)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []models.Origin{{XOrigin: 1, MazeID: first}}, origins)
+	})
+}
+
+func TestLegacyRepository(t *testing.T) {
+
+	ctx := context.Background()
+	eachBackend(t, func(t *testing.T, r repositories) {
+		//what's created without a maze is what was stored before there were mazes
+		a, err := r.spots.Create(ctx, models.Spot{Name: "a"})
+		assert.NilError(t, err)
+		b, err := r.spots.Create(ctx, models.Spot{Name: "b"})
+		assert.NilError(t, err)
+		idA, _ := primitive.ObjectIDFromHex(a)
+		idB, _ := primitive.ObjectIDFromHex(b)
+		_, err = r.paths.Create(ctx, models.Path{PointA: idA, PointB: idB})
+		assert.NilError(t, err)
+		_, err = r.origins.Create(ctx, models.Origin{XOrigin: 1})
+		assert.NilError(t, err)
+		polygon := []models.Point{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: 1}}
+		_, err = r.zones.Create(ctx, models.Zone{Name: "hall", Polygon: polygon})
+		assert.NilError(t, err)
+		other := WithMaze(ctx, primitive.NewObjectID())
+		_, err = r.spots.Create(other, models.Spot{Name: "other"})
+		assert.NilError(t, err)
+
+		_, found, err := r.legacy.Settings(ctx)
+		assert.NilError(t, err)
+		assert.Assert(t, !found)
+		unscoped, err := r.legacy.Unscoped(ctx)
+		assert.NilError(t, err)
+		assert.Equal(t, 5, unscoped)
+
+		maze := primitive.NewObjectID()
+		assert.NilError(t, r.legacy.Adopt(ctx, maze))
+		unscoped, err = r.legacy.Unscoped(ctx)
+		assert.NilError(t, err)
+		assert.Equal(t, 0, unscoped)
+
+		//the maze sees every document adopted, and only those
+		scoped := WithMaze(ctx, maze)
+		spots, err := r.spots.List(scoped)
+		assert.NilError(t, err)
+		assert.Equal(t, 2, len(spots))
+		paths, err := r.paths.List(scoped)
+		assert.NilError(t, err)
+		assert.Equal(t, 1, len(paths))
+		zones, err := r.zones.List(scoped)
+		assert.NilError(t, err)
+		assert.Equal(t, 1, len(zones))
+		origins, err := r.origins.List(scoped)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []models.Origin{{XOrigin: 1, MazeID: maze}}, origins)
+		//and the origin is its single one
+		_, err = r.origins.Create(scoped, models.Origin{})
+		assert.Equal(t, ErrOriginExists, err)
+	})
+}
+
+func TestBoltLegacySettings(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "maze")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := OpenBoltStore(filepath.Join(dir, "maze.db"))
+	assert.NilError(t, err)
+	defer store.Close()
+	legacy := NewBoltLegacyRepository(store)
+
+	//the settings of the single maze were kept in a bucket of their own
+	old := models.Maze{Coordinates: models.Geographic, Metric: models.Euclidean}
+	err = store.db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.CreateBucket([]byte(LegacyMazeCollection))
+		if err != nil {
+			return err
+		}
+		raw, err := bson.Marshal(old)
+		if err != nil {
+			return err
+		}
+		return b.Put(legacyMazeKey, raw)
+	})
+	assert.NilError(t, err)
+
+	ctx := context.Background()
+	settings, found, err := legacy.Settings(ctx)
+	assert.NilError(t, err)
+	assert.Assert(t, found)
+	assert.DeepEqual(t, old, settings)
+
+	//and they're dropped once adopted
+	assert.NilError(t, legacy.Adopt(ctx, primitive.NewObjectID()))
+	_, found, err = legacy.Settings(ctx)
+	assert.NilError(t, err)
+	assert.Assert(t, !found)
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type mazeScope struct{}
+
+//WithMaze scopes the repositories to the maze: what's created with the context belongs to it, and only what belongs to
+//it is found, updated or deleted. Without a maze the repositories see everything stored, which is what loading the
+//spot index at start up needs
+func WithMaze(ctx context.Context, maze primitive.ObjectID) context.Context {
+	return context.WithValue(ctx, mazeScope{}, maze)
+}
+
+//MazeFrom returns the maze the context is scoped to
+func MazeFrom(ctx context.Context) (primitive.ObjectID, bool) {
+	maze, ok := ctx.Value(mazeScope{}).(primitive.ObjectID)
+	return maze, ok
+}
+
+//scope restricts the filter to the documents of the context's maze
+func scope(ctx context.Context, filter interface{}) interface{} {
+	maze, ok := MazeFrom(ctx)
+	if !ok {
+		return filter
+	}
+	return bson.M{"$and": bson.A{filter, bson.M{"maze_id": maze}}}
+}
+
+//within tells whether a document of the given maze can be seen with the context
+func within(ctx context.Context, maze primitive.ObjectID) bool {
+	scoped, ok := MazeFrom(ctx)
+	return !ok || scoped == maze
+}
+
+//owner returns the maze the context is scoped to, keeping the given one when it isn't scoped
+func owner(ctx context.Context, maze primitive.ObjectID) primitive.ObjectID {
+	if scoped, ok := MazeFrom(ctx); ok {
+		return scoped
+	}
+	return maze
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"fmt"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"strconv"
+	"time"
+)
+
+//DefaultDatabase is the MongoDB database the maze is kept in when the config doesn't name one
+const DefaultDatabase = "mazedb"
+
+//Collections names the MongoDB collection of every repository
+type Collections struct {
+	Spots  string `json:"spots"`
+	Paths  string `json:"paths"`
+	Origin string `json:"origin"`
+	Zones  string `json:"zones"`
+	Mazes  string `json:"mazes"`
+}
+
+//DefaultCollections returns the collections the repositories use unless they're renamed
+func DefaultCollections() Collections {
+	return Collections{
+		Spots:  SpotsCollection,
+		Paths:  PathsCollection,
+		Origin: OriginCollection,
+		Zones:  ZonesCollection,
+		Mazes:  MazesCollection,
+	}
+}
+
+//WithDefaults returns the collections, the ones left empty keeping their default name
+func (c Collections) WithDefaults() Collections {
+	defaults := DefaultCollections()
+	for _, name := range []struct {
+		name     *string
+		fallback string
+	}{
+		{&c.Spots, defaults.Spots},
+		{&c.Paths, defaults.Paths},
+		{&c.Origin, defaults.Origin},
+		{&c.Zones, defaults.Zones},
+		{&c.Mazes, defaults.Mazes},
+	} {
+		if *name.name == "" {
+			*name.name = name.fallback
+		}
+	}
+	return c
+}
+
+//MongoSettings is how the MongoDB storage is reached and where its documents are kept. Every setting left at its zero
+//value keeps the driver's default
+type MongoSettings struct {
+	URI         string
+	Database    string
+	Collections Collections
+
+	MinPoolSize uint64
+	MaxPoolSize uint64
+
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+	SocketTimeout          time.Duration
+
+	//ReadConcern is either local, available, majority, linearizable or snapshot
+	ReadConcern string
+	//WriteConcern is either majority or the amount of members that must acknowledge a write
+	WriteConcern string
+	//WriteTimeout bounds how long a write waits for its write concern
+	WriteTimeout time.Duration
+}
+
+//ClientOptions returns the options of the client connecting to MongoDB
+func (s MongoSettings) ClientOptions() (*options.ClientOptions, error) {
+
+	opts := options.Client().ApplyURI(s.URI)
+	if s.MinPoolSize > 0 {
+		opts.SetMinPoolSize(s.MinPoolSize)
+	}
+	if s.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(s.MaxPoolSize)
+	}
+	if s.ConnectTimeout > 0 {
+		opts.SetConnectTimeout(s.ConnectTimeout)
+	}
+	if s.ServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(s.ServerSelectionTimeout)
+	}
+	if s.SocketTimeout > 0 {
+		opts.SetSocketTimeout(s.SocketTimeout)
+	}
+
+	switch s.ReadConcern {
+	case "":
+	case "local", "available", "majority", "linearizable", "snapshot":
+		opts.SetReadConcern(readconcern.New(readconcern.Level(s.ReadConcern)))
+	default:
+		return nil, fmt.Errorf("unknown read concern %s: it must be either local, available, majority, linearizable or snapshot", s.ReadConcern)
+	}
+
+	var write []writeconcern.Option
+	switch s.WriteConcern {
+	case "":
+	case "majority":
+		write = append(write, writeconcern.WMajority())
+	default:
+		w, err := strconv.Atoi(s.WriteConcern)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("unknown write concern %s: it must be either majority or an amount of members", s.WriteConcern)
+		}
+		write = append(write, writeconcern.W(w))
+	}
+	if s.WriteTimeout > 0 {
+		write = append(write, writeconcern.WTimeout(s.WriteTimeout))
+	}
+	if len(write) > 0 {
+		opts.SetWriteConcern(writeconcern.New(write...))
+	}
+
+	return opts, opts.Validate()
+}
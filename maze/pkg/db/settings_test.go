@@ -0,0 +1,77 @@
+package db
+
+import (
+	"gotest.tools/v3/assert"
+	"testing"
+	"time"
+)
+
+func TestClientOptions(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		settings MongoSettings
+		err      string
+	}{
+		{
+			name:     "Defaults are left to the driver",
+			settings: MongoSettings{URI: "mongodb://localhost:27017"},
+		},
+		{
+			name: "Every setting is applied",
+			settings: MongoSettings{
+				URI:                    "mongodb://localhost:27017",
+				MinPoolSize:            2,
+				MaxPoolSize:            20,
+				ConnectTimeout:         5 * time.Second,
+				ServerSelectionTimeout: 5 * time.Second,
+				SocketTimeout:          time.Minute,
+				ReadConcern:            "majority",
+				WriteConcern:           "majority",
+				WriteTimeout:           time.Second,
+			},
+		},
+		{
+			name:     "Write concern can be an amount of members",
+			settings: MongoSettings{URI: "mongodb://localhost:27017", WriteConcern: "2"},
+		},
+		{
+			name:     "Unknown read concern",
+			settings: MongoSettings{URI: "mongodb://localhost:27017", ReadConcern: "eventual"},
+			err:      "unknown read concern eventual: it must be either local, available, majority, linearizable or snapshot",
+		},
+		{
+			name:     "Unknown write concern",
+			settings: MongoSettings{URI: "mongodb://localhost:27017", WriteConcern: "all"},
+			err:      "unknown write concern all: it must be either majority or an amount of members",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := tt.settings.ClientOptions()
+			if tt.err != "" {
+				assert.Error(t, err, tt.err)
+				return
+			}
+			assert.NilError(t, err)
+			if tt.settings.MaxPoolSize > 0 {
+				assert.Equal(t, *opts.MaxPoolSize, tt.settings.MaxPoolSize)
+				assert.Equal(t, *opts.MinPoolSize, tt.settings.MinPoolSize)
+				assert.Equal(t, *opts.SocketTimeout, tt.settings.SocketTimeout)
+				assert.Equal(t, opts.ReadConcern.GetLevel(), tt.settings.ReadConcern)
+				assert.Equal(t, opts.WriteConcern.GetW(), tt.settings.WriteConcern)
+				assert.Equal(t, opts.WriteConcern.GetWTimeout(), tt.settings.WriteTimeout)
+			}
+			if tt.settings.WriteConcern == "" {
+				assert.Assert(t, opts.WriteConcern == nil)
+			}
+		})
+	}
+}
+
+func TestCollectionsWithDefaults(t *testing.T) {
+	renamed := Collections{Spots: "maze_spots", Mazes: "maze_mazes"}.WithDefaults()
+	assert.Equal(t, Collections{Spots: "maze_spots", Paths: PathsCollection, Origin: OriginCollection,
+		Zones: ZonesCollection, Mazes: "maze_mazes"}, renamed)
+}
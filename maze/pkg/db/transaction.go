@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+//Transactor runs units of work made of several repository calls. What the repositories do with the context handed to
+//the work is committed together when it returns nil, and none of it is when it returns an error. The services taking
+//one run with NoTransactions when it's nil
+type Transactor interface {
+	Transaction(ctx context.Context, work func(ctx context.Context) error) error
+}
+
+type noTransactor struct{}
+
+//NoTransactions runs the work as it comes, for the storages without transactions: every repository call is still
+//atomic on its own, but a work failing halfway keeps what it already did
+func NoTransactions() Transactor {
+	return noTransactor{}
+}
+
+func (noTransactor) Transaction(ctx context.Context, work func(ctx context.Context) error) error {
+	return work(ctx)
+}
+
+type mongoTransactor struct {
+	client *mongo.Client
+}
+
+//NewMongoTransactor runs units of work in MongoDB transactions. Those need a replica set or a sharded cluster, so the
+//work runs as it comes against a standalone server
+func NewMongoTransactor(ctx context.Context, client *mongo.Client) (Transactor, error) {
+
+	var hello bson.M
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return nil, err
+	}
+	if _, replicated := hello["setName"]; !replicated && hello["msg"] != "isdbgrid" {
+		return NoTransactions(), nil
+	}
+	return mongoTransactor{client: client}, nil
+}
+
+//Transaction runs the work in a session's transaction, which the driver retries on transient errors. Work started
+//within another transaction joins it
+func (t mongoTransactor) Transaction(ctx context.Context, work func(ctx context.Context) error) error {
+
+	if mongo.SessionFromContext(ctx) != nil {
+		return work(ctx)
+	}
+	return t.client.UseSession(ctx, func(sc mongo.SessionContext) error {
+		_, err := sc.WithTransaction(sc, func(sc mongo.SessionContext) (interface{}, error) {
+			return nil, work(sc)
+		})
+		return err
+	})
+}
+
+type boltTransactor struct {
+	store *BoltStore
+}
+
+//NewBoltTransactor runs units of work in read-write transactions of the store. bbolt has a single writer, so they run
+//one after the other
+func NewBoltTransactor(store *BoltStore) Transactor {
+	return boltTransactor{store: store}
+}
+
+//Transaction runs the work in a transaction every repository of the store joins through the context, rolling it back
+//when the work fails. Work started within another transaction joins it
+func (t boltTransactor) Transaction(ctx context.Context, work func(ctx context.Context) error) error {
+
+	if t.store.current(ctx) != nil {
+		return work(ctx)
+	}
+	return t.store.db.Update(func(tx *bbolt.Tx) error {
+		return work(context.WithValue(ctx, boltTxKey{}, boltTx{store: t.store, tx: tx}))
+	})
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/avanticaTest/maze/pkg/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltTransaction(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "maze")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := OpenBoltStore(filepath.Join(dir, "maze.db"))
+	assert.NilError(t, err)
+	defer store.Close()
+	spots, paths := NewBoltSpotRepository(store), NewBoltPathRepository(store)
+	tx := NewBoltTransactor(store)
+
+	ctx := context.Background()
+	hex, err := spots.Create(ctx, models.Spot{Name: "a"})
+	assert.NilError(t, err)
+	a, err := primitive.ObjectIDFromHex(hex)
+	assert.NilError(t, err)
+
+	//the work sees what it wrote, and it's all committed together
+	var b primitive.ObjectID
+	err = tx.Transaction(ctx, func(ctx context.Context) error {
+		hex, err := spots.Create(ctx, models.Spot{Name: "b"})
+		if err != nil {
+			return err
+		}
+		b, _ = primitive.ObjectIDFromHex(hex)
+		if _, err := spots.GetByID(ctx, b); err != nil {
+			return err
+		}
+		_, err = paths.Create(ctx, models.Path{PointA: a, PointB: b})
+		return err
+	})
+	assert.NilError(t, err)
+	joined, err := paths.Find(ctx, PathsOf(b))
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(joined))
+
+	//a step failing rolls back every step before it, the ones of the work it joined included
+	failure := errors.New("failure")
+	err = tx.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := spots.Touch(ctx, a, b); err != nil {
+			return err
+		}
+		if _, err := paths.DeleteMany(ctx, PathsOf(b)); err != nil {
+			return err
+		}
+		return tx.Transaction(ctx, func(ctx context.Context) error {
+			if _, err := spots.Delete(ctx, b); err != nil {
+				return err
+			}
+			return failure
+		})
+	})
+	assert.Equal(t, failure, err)
+
+	spot, err := spots.GetByID(ctx, b)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, spot.Revision)
+	joined, err = paths.Find(ctx, PathsOf(b))
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(joined))
+
+	//and one succeeding keeps them
+	err = tx.Transaction(ctx, func(ctx context.Context) error {
+		_, err := spots.Delete(ctx, b)
+		return err
+	})
+	assert.NilError(t, err)
+	_, err = spots.GetByID(ctx, b)
+	assert.Equal(t, mongo.ErrNoDocuments, err)
+}
@@ -3,9 +3,11 @@ package endpoints
 import (
 	"context"
 	"github.com/avanticaTest/maze/pkg/models"
+	"github.com/avanticaTest/maze/pkg/service/maze"
 	"github.com/avanticaTest/maze/pkg/service/path"
 	"github.com/avanticaTest/maze/pkg/service/quadrant"
 	"github.com/avanticaTest/maze/pkg/service/spot"
+	"github.com/avanticaTest/maze/pkg/service/zone"
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
@@ -21,6 +23,13 @@ type Endpoints struct {
 	ModifySpotEndpoint    endpoint.Endpoint
 	DeleteSpotEndpoint    endpoint.Endpoint
 
+	GetSpotsInBoxEndpoint     endpoint.Endpoint
+	GetSpotsInRadiusEndpoint  endpoint.Endpoint
+	GetNearestSpotsEndpoint   endpoint.Endpoint
+	GetSpotGridEndpoint       endpoint.Endpoint
+	ClusterSpotsEndpoint      endpoint.Endpoint
+	GetSpotsInClusterEndpoint endpoint.Endpoint
+
 	CreatePathEndpoint    endpoint.Endpoint
 	GetSinglePathEndpoint endpoint.Endpoint
 	GetPathsEndpoint      endpoint.Endpoint
@@ -32,78 +41,173 @@ type Endpoints struct {
 	GetSpotsInQuadrantEndpoint endpoint.Endpoint
 	ModifyOriginEndpoint       endpoint.Endpoint
 	DeleteOriginEndpoint       endpoint.Endpoint
+	GetLocalSpotsEndpoint      endpoint.Endpoint
+	GetSingleLocalSpotEndpoint endpoint.Endpoint
+	GetSpotsInSectorEndpoint   endpoint.Endpoint
+	GetQuadrantStatsEndpoint   endpoint.Endpoint
+	GetHullEndpoint            endpoint.Endpoint
+	SuggestOriginEndpoint      endpoint.Endpoint
+
+	CreateZoneEndpoint     endpoint.Endpoint
+	GetSingleZoneEndpoint  endpoint.Endpoint
+	GetZonesEndpoint       endpoint.Endpoint
+	ModifyZoneEndpoint     endpoint.Endpoint
+	DeleteZoneEndpoint     endpoint.Endpoint
+	GetSpotsInZoneEndpoint endpoint.Endpoint
+
+	CreateMazeEndpoint endpoint.Endpoint
+	GetMazesEndpoint   endpoint.Endpoint
+	GetMazeEndpoint    endpoint.Endpoint
+	ModifyMazeEndpoint endpoint.Endpoint
+	DeleteMazeEndpoint endpoint.Endpoint
 }
 
 // New will create an Endpoints struct with initialized endpoint(s) and
 // middleware(s).
 //This allow us to wrap our service's final functions with layers of logging, decoding, encoding, etc, abstracting the core functionality of the service
 //from the rest
-func New(spot spot.SpotHandler, path path.PathHandler, orig quadrant.OriginHandler, logger log.Logger) (ep Endpoints) {
+func New(spot spot.SpotHandler, path path.PathHandler, orig quadrant.OriginHandler, zone zone.ZoneHandler, mazes maze.MazeHandler, logger log.Logger) (ep Endpoints) {
 	// create the GetMinesweeper endpoint
 
+	//every endpoint but the maze ones works inside the maze of the request
+	scoped := MazeMiddleware(mazes)
+
 	//Spot Endpoints:
 
-	ep.CreateSpotEndpoint = MakeCreateSpotEndpoint(spot)
+	ep.CreateSpotEndpoint = scoped(MakeCreateSpotEndpoint(spot))
 	ep.CreateSpotEndpoint = LoggingMiddleware(log.With(logger, "method", "CreateSpot"))(ep.CreateSpotEndpoint)
 
 	//create the NewGame endpoint
-	ep.GetSingleSpotEndpoint = MakeGetSingleSpotEndpoint(spot)
+	ep.GetSingleSpotEndpoint = scoped(MakeGetSingleSpotEndpoint(spot))
 	ep.GetSingleSpotEndpoint = LoggingMiddleware(log.With(logger, "method", "GetSingleSpot"))(ep.GetSingleSpotEndpoint)
 
 	//create the LoadGame endpoint
-	ep.GetSpotsEndpoint = MakeGetSpotsEndpoint(spot)
+	ep.GetSpotsEndpoint = scoped(MakeGetSpotsEndpoint(spot))
 	ep.GetSpotsEndpoint = LoggingMiddleware(log.With(logger, "method", "GetSpots"))(ep.GetSpotsEndpoint)
 
 	//create the SaveGame endpoint
-	ep.ModifySpotEndpoint = MakeModifySpotEndpoint(spot)
+	ep.ModifySpotEndpoint = scoped(MakeModifySpotEndpoint(spot))
 	ep.ModifySpotEndpoint = LoggingMiddleware(log.With(logger, "method", "ModifySpot"))(ep.ModifySpotEndpoint)
 
 	//create the Click endpoint
-	ep.DeleteSpotEndpoint = MakeDeleteSpotEndpoint(spot)
+	ep.DeleteSpotEndpoint = scoped(MakeDeleteSpotEndpoint(spot))
 	ep.DeleteSpotEndpoint = LoggingMiddleware(log.With(logger, "method", "DeleteSpot"))(ep.DeleteSpotEndpoint)
 
+	ep.GetSpotsInBoxEndpoint = scoped(MakeGetSpotsInBoxEndpoint(spot))
+	ep.GetSpotsInBoxEndpoint = LoggingMiddleware(log.With(logger, "method", "GetSpotsInBox"))(ep.GetSpotsInBoxEndpoint)
+
+	ep.GetSpotsInRadiusEndpoint = scoped(MakeGetSpotsInRadiusEndpoint(spot))
+	ep.GetSpotsInRadiusEndpoint = LoggingMiddleware(log.With(logger, "method", "GetSpotsInRadius"))(ep.GetSpotsInRadiusEndpoint)
+
+	ep.GetNearestSpotsEndpoint = scoped(MakeGetNearestSpotsEndpoint(spot))
+	ep.GetNearestSpotsEndpoint = LoggingMiddleware(log.With(logger, "method", "GetNearestSpots"))(ep.GetNearestSpotsEndpoint)
+
+	ep.GetSpotGridEndpoint = scoped(MakeGetSpotGridEndpoint(spot))
+	ep.GetSpotGridEndpoint = LoggingMiddleware(log.With(logger, "method", "GetSpotGrid"))(ep.GetSpotGridEndpoint)
+
+	ep.ClusterSpotsEndpoint = scoped(MakeClusterSpotsEndpoint(spot))
+	ep.ClusterSpotsEndpoint = LoggingMiddleware(log.With(logger, "method", "ClusterSpots"))(ep.ClusterSpotsEndpoint)
+
+	ep.GetSpotsInClusterEndpoint = scoped(MakeGetSpotsInClusterEndpoint(spot))
+	ep.GetSpotsInClusterEndpoint = LoggingMiddleware(log.With(logger, "method", "GetSpotsInCluster"))(ep.GetSpotsInClusterEndpoint)
+
 	//Path Endpoints:
 
-	ep.CreatePathEndpoint = MakeCreatePathEndpoint(path)
+	ep.CreatePathEndpoint = scoped(MakeCreatePathEndpoint(path))
 	ep.CreatePathEndpoint = LoggingMiddleware(log.With(logger, "method", "CreatePath"))(ep.CreatePathEndpoint)
 
 	//create the NewGame endpoint
-	ep.GetSinglePathEndpoint = MakeGetSinglePathEndpoint(path)
+	ep.GetSinglePathEndpoint = scoped(MakeGetSinglePathEndpoint(path))
 	ep.GetSinglePathEndpoint = LoggingMiddleware(log.With(logger, "method", "GetSinglePath"))(ep.GetSinglePathEndpoint)
 
 	//create the LoadGame endpoint
-	ep.GetPathsEndpoint = MakeGetPathsEndpoint(path)
+	ep.GetPathsEndpoint = scoped(MakeGetPathsEndpoint(path))
 	ep.GetPathsEndpoint = LoggingMiddleware(log.With(logger, "method", "GetPaths"))(ep.GetPathsEndpoint)
 
 	//create the SaveGame endpoint
-	ep.ModifyPathEndpoint = MakeModifyPathEndpoint(path)
+	ep.ModifyPathEndpoint = scoped(MakeModifyPathEndpoint(path))
 	ep.ModifyPathEndpoint = LoggingMiddleware(log.With(logger, "method", "ModifyPath"))(ep.ModifyPathEndpoint)
 
 	//create the Click endpoint
-	ep.DeletePathEndpoint = MakeDeletePathEndpoint(path)
+	ep.DeletePathEndpoint = scoped(MakeDeletePathEndpoint(path))
 	ep.DeletePathEndpoint = LoggingMiddleware(log.With(logger, "method", "DeletePath"))(ep.DeletePathEndpoint)
 
 	//Origin Endpoints:
 
-	ep.CreateOriginEndpoint = MakeCreateOriginEndpoint(orig)
+	ep.CreateOriginEndpoint = scoped(MakeCreateOriginEndpoint(orig))
 	ep.CreateOriginEndpoint = LoggingMiddleware(log.With(logger, "method", "CreateOrigin"))(ep.CreateOriginEndpoint)
 
 	//create the NewGame endpoint
-	ep.GetOriginEndpoint = MakeGetOriginEndpoint(orig)
+	ep.GetOriginEndpoint = scoped(MakeGetOriginEndpoint(orig))
 	ep.GetOriginEndpoint = LoggingMiddleware(log.With(logger, "method", "GetOrigin"))(ep.GetOriginEndpoint)
 
 	//create the LoadGame endpoint
-	ep.GetSpotsInQuadrantEndpoint = MakeGetSpotsInQuadrantEndpoint(orig)
+	ep.GetSpotsInQuadrantEndpoint = scoped(MakeGetSpotsInQuadrantEndpoint(orig))
 	ep.GetSpotsInQuadrantEndpoint = LoggingMiddleware(log.With(logger, "method", "GetSpotsInQuadrant"))(ep.GetSpotsInQuadrantEndpoint)
 
 	//create the SaveGame endpoint
-	ep.ModifyOriginEndpoint = MakeModifyOriginEndpoint(orig)
+	ep.ModifyOriginEndpoint = scoped(MakeModifyOriginEndpoint(orig))
 	ep.ModifyOriginEndpoint = LoggingMiddleware(log.With(logger, "method", "ModifyOrigin"))(ep.ModifyOriginEndpoint)
 
 	//create the Click endpoint
-	ep.DeleteOriginEndpoint = MakeDeleteOriginEndpoint(orig)
+	ep.DeleteOriginEndpoint = scoped(MakeDeleteOriginEndpoint(orig))
 	ep.DeleteOriginEndpoint = LoggingMiddleware(log.With(logger, "method", "DeleteOrigin"))(ep.DeleteOriginEndpoint)
 
+	ep.GetLocalSpotsEndpoint = scoped(MakeGetLocalSpotsEndpoint(orig))
+	ep.GetLocalSpotsEndpoint = LoggingMiddleware(log.With(logger, "method", "GetLocalSpots"))(ep.GetLocalSpotsEndpoint)
+
+	ep.GetSingleLocalSpotEndpoint = scoped(MakeGetSingleLocalSpotEndpoint(orig))
+	ep.GetSingleLocalSpotEndpoint = LoggingMiddleware(log.With(logger, "method", "GetSingleLocalSpot"))(ep.GetSingleLocalSpotEndpoint)
+
+	ep.GetSpotsInSectorEndpoint = scoped(MakeGetSpotsInSectorEndpoint(orig))
+	ep.GetSpotsInSectorEndpoint = LoggingMiddleware(log.With(logger, "method", "GetSpotsInSector"))(ep.GetSpotsInSectorEndpoint)
+
+	ep.GetQuadrantStatsEndpoint = scoped(MakeGetQuadrantStatsEndpoint(orig))
+	ep.GetQuadrantStatsEndpoint = LoggingMiddleware(log.With(logger, "method", "GetQuadrantStats"))(ep.GetQuadrantStatsEndpoint)
+
+	ep.GetHullEndpoint = scoped(MakeGetHullEndpoint(orig))
+	ep.GetHullEndpoint = LoggingMiddleware(log.With(logger, "method", "GetHull"))(ep.GetHullEndpoint)
+
+	ep.SuggestOriginEndpoint = scoped(MakeSuggestOriginEndpoint(orig))
+	ep.SuggestOriginEndpoint = LoggingMiddleware(log.With(logger, "method", "SuggestOrigin"))(ep.SuggestOriginEndpoint)
+
+	//Zone Endpoints:
+
+	ep.CreateZoneEndpoint = scoped(MakeCreateZoneEndpoint(zone))
+	ep.CreateZoneEndpoint = LoggingMiddleware(log.With(logger, "method", "CreateZone"))(ep.CreateZoneEndpoint)
+
+	ep.GetSingleZoneEndpoint = scoped(MakeGetSingleZoneEndpoint(zone))
+	ep.GetSingleZoneEndpoint = LoggingMiddleware(log.With(logger, "method", "GetSingleZone"))(ep.GetSingleZoneEndpoint)
+
+	ep.GetZonesEndpoint = scoped(MakeGetZonesEndpoint(zone))
+	ep.GetZonesEndpoint = LoggingMiddleware(log.With(logger, "method", "GetZones"))(ep.GetZonesEndpoint)
+
+	ep.ModifyZoneEndpoint = scoped(MakeModifyZoneEndpoint(zone))
+	ep.ModifyZoneEndpoint = LoggingMiddleware(log.With(logger, "method", "ModifyZone"))(ep.ModifyZoneEndpoint)
+
+	ep.DeleteZoneEndpoint = scoped(MakeDeleteZoneEndpoint(zone))
+	ep.DeleteZoneEndpoint = LoggingMiddleware(log.With(logger, "method", "DeleteZone"))(ep.DeleteZoneEndpoint)
+
+	ep.GetSpotsInZoneEndpoint = scoped(MakeGetSpotsInZoneEndpoint(zone))
+	ep.GetSpotsInZoneEndpoint = LoggingMiddleware(log.With(logger, "method", "GetSpotsInZone"))(ep.GetSpotsInZoneEndpoint)
+
+	//Maze Endpoints:
+
+	ep.CreateMazeEndpoint = MakeCreateMazeEndpoint(mazes)
+	ep.CreateMazeEndpoint = LoggingMiddleware(log.With(logger, "method", "CreateMaze"))(ep.CreateMazeEndpoint)
+
+	ep.GetMazesEndpoint = MakeGetMazesEndpoint(mazes)
+	ep.GetMazesEndpoint = LoggingMiddleware(log.With(logger, "method", "GetMazes"))(ep.GetMazesEndpoint)
+
+	ep.GetMazeEndpoint = MakeGetMazeEndpoint(mazes)
+	ep.GetMazeEndpoint = LoggingMiddleware(log.With(logger, "method", "GetMaze"))(ep.GetMazeEndpoint)
+
+	ep.ModifyMazeEndpoint = MakeModifyMazeEndpoint(mazes)
+	ep.ModifyMazeEndpoint = LoggingMiddleware(log.With(logger, "method", "ModifyMaze"))(ep.ModifyMazeEndpoint)
+
+	ep.DeleteMazeEndpoint = MakeDeleteMazeEndpoint(mazes)
+	ep.DeleteMazeEndpoint = LoggingMiddleware(log.With(logger, "method", "DeleteMaze"))(ep.DeleteMazeEndpoint)
+
 	return ep
 
 }
@@ -174,6 +278,84 @@ func MakeDeleteSpotEndpoint(svc spot.SpotHandler) (ep endpoint.Endpoint) {
 	}
 }
 
+// MakeGetSpotsInBoxEndpoint returns an endpoint that invokes GetSpotsInBox on the service.
+func MakeGetSpotsInBoxEndpoint(svc spot.SpotHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(GetSpotsInBoxRequest)
+		res, err := svc.GetSpotsInBox(ctx, req.Req)
+
+		// wrap service response with endpoint response
+		return GetSpotsResponse{Res: res, Err: err}, nil
+	}
+}
+
+// MakeGetSpotsInRadiusEndpoint returns an endpoint that invokes GetSpotsInRadius on the service.
+func MakeGetSpotsInRadiusEndpoint(svc spot.SpotHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(GetSpotsInRadiusRequest)
+		res, err := svc.GetSpotsInRadius(ctx, req.Req)
+
+		// wrap service response with endpoint response
+		return GetSpotsResponse{Res: res, Err: err}, nil
+	}
+}
+
+// MakeGetNearestSpotsEndpoint returns an endpoint that invokes GetNearestSpots on the service.
+func MakeGetNearestSpotsEndpoint(svc spot.SpotHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(GetNearestSpotsRequest)
+		res, err := svc.GetNearestSpots(ctx, req.Req)
+
+		// wrap service response with endpoint response
+		return GetSpotsResponse{Res: res, Err: err}, nil
+	}
+}
+
+// MakeGetSpotGridEndpoint returns an endpoint that invokes GetSpotGrid on the service.
+func MakeGetSpotGridEndpoint(svc spot.SpotHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(GetSpotGridRequest)
+		res, err := svc.GetSpotGrid(ctx, req.Req)
+
+		// wrap service response with endpoint response, keeping the format it's wanted in
+		return GetSpotGridResponse{Res: res, Format: req.Format, Err: err}, nil
+	}
+}
+
+// MakeClusterSpotsEndpoint returns an endpoint that invokes ClusterSpots on the service.
+func MakeClusterSpotsEndpoint(svc spot.SpotHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(ClusterSpotsRequest)
+		res, err := svc.ClusterSpots(ctx, req.Req)
+
+		// wrap service response with endpoint response
+		return ClusterSpotsResponse{Res: res, Err: err}, nil
+	}
+}
+
+// MakeGetSpotsInClusterEndpoint returns an endpoint that invokes GetSpotsInCluster on the service.
+func MakeGetSpotsInClusterEndpoint(svc spot.SpotHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(GetSpotsInClusterRequest)
+		res, err := svc.GetSpotsInCluster(ctx, req.Label)
+
+		// wrap service response with endpoint response
+		return GetSpotsResponse{Res: res, Err: err}, nil
+	}
+}
+
 //Make Paths Endpoints
 
 // MakeCreatePathEndpoint returns an endpoint that invokes CreatePath on the service.
@@ -311,6 +493,234 @@ func MakeDeleteOriginEndpoint(svc quadrant.OriginHandler) (ep endpoint.Endpoint)
 	}
 }
 
+// MakeGetLocalSpotsEndpoint returns an endpoint that invokes GetLocalSpots on the service.
+func MakeGetLocalSpotsEndpoint(svc quadrant.OriginHandler) (ep endpoint.Endpoint) {
+
+	// interface parameter is ignored because request does not
+	// require input parameters.
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+
+		res, err := svc.GetLocalSpots(ctx)
+
+		// wrap service response with endpoint response
+		return GetLocalSpotsResponse{Res: res, Err: err}, nil
+	}
+}
+
+// MakeGetSingleLocalSpotEndpoint returns an endpoint that invokes GetSingleLocalSpot on the service.
+func MakeGetSingleLocalSpotEndpoint(svc quadrant.OriginHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(GetSingleObjectRequest)
+		res, err := svc.GetSingleLocalSpot(ctx, req.ObjectID)
+
+		// wrap service response with endpoint response
+		return GetSingleLocalSpotResponse{Res: res, Err: err}, nil
+	}
+}
+
+// MakeGetSpotsInSectorEndpoint returns an endpoint that invokes GetSpotsInSector on the service.
+func MakeGetSpotsInSectorEndpoint(svc quadrant.OriginHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(GetSpotsInSectorRequest)
+		res, err := svc.GetSpotsInSector(ctx, req.Req)
+
+		// wrap service response with endpoint response
+		return GetPolarSpotsResponse{Res: res, Err: err}, nil
+	}
+}
+
+// MakeGetQuadrantStatsEndpoint returns an endpoint that invokes GetQuadrantStats on the service.
+func MakeGetQuadrantStatsEndpoint(svc quadrant.OriginHandler) (ep endpoint.Endpoint) {
+
+	// interface parameter is ignored because request does not
+	// require input parameters.
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+
+		res, err := svc.GetQuadrantStats(ctx)
+
+		// wrap service response with endpoint response
+		return GetQuadrantStatsResponse{Res: res, Err: err}, nil
+	}
+}
+
+// MakeGetHullEndpoint returns an endpoint that invokes GetHull on the service.
+func MakeGetHullEndpoint(svc quadrant.OriginHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(GetHullRequest)
+		res, err := svc.GetHull(ctx, req.Quadrant)
+
+		// wrap service response with endpoint response
+		return GetHullResponse{Res: res, Err: err}, nil
+	}
+}
+
+// MakeSuggestOriginEndpoint returns an endpoint that invokes SuggestOrigin on the service.
+func MakeSuggestOriginEndpoint(svc quadrant.OriginHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(SuggestOriginRequest)
+		res, err := svc.SuggestOrigin(ctx, req.Req)
+
+		// wrap service response with endpoint response
+		return SuggestOriginResponse{Res: res, Err: err}, nil
+	}
+}
+
+//Make Zone Endpoints
+
+// MakeCreateZoneEndpoint returns an endpoint that invokes CreateZone on the service.
+func MakeCreateZoneEndpoint(svc zone.ZoneHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(CreateZoneRequest)
+		res, err := svc.CreateZone(ctx, req.Req)
+
+		// wrap service response with endpoint response
+		return CreateObjectResponse{Res: models.CreateObjectResponse{ID: res}, Err: err}, nil
+	}
+}
+
+// MakeGetSingleZoneEndpoint returns an endpoint that invokes GetSingleZone on the service.
+func MakeGetSingleZoneEndpoint(svc zone.ZoneHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(GetSingleObjectRequest)
+		res, err := svc.GetSingleZone(ctx, req.ObjectID)
+
+		// wrap service response with endpoint response
+		return GetSingleZoneResponse{Res: res, Err: err}, nil
+	}
+}
+
+// MakeGetZonesEndpoint returns an endpoint that invokes GetZones on the service.
+func MakeGetZonesEndpoint(svc zone.ZoneHandler) (ep endpoint.Endpoint) {
+
+	// interface parameter is ignored because request does not
+	// require input parameters.
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+
+		res, err := svc.GetZones(ctx)
+
+		// wrap service response with endpoint response
+		return GetZonesResponse{Res: res, Err: err}, nil
+	}
+}
+
+// MakeModifyZoneEndpoint returns an endpoint that invokes ModifyZone on the service.
+func MakeModifyZoneEndpoint(svc zone.ZoneHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(ModifyZoneRequest)
+		res, err := svc.ModifyZone(ctx, req.Req, req.ID)
+
+		// wrap service response with endpoint response
+		return ModifyObjectResponse{Res: models.ModifyObjectResponse{AffectedItems: res}, Err: err}, nil
+	}
+}
+
+// MakeDeleteZoneEndpoint returns an endpoint that invokes DeleteZone on the service.
+func MakeDeleteZoneEndpoint(svc zone.ZoneHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(GetSingleObjectRequest)
+		res, err := svc.DeleteZone(ctx, req.ObjectID)
+
+		// wrap service response with endpoint response
+		return ModifyObjectResponse{Res: models.ModifyObjectResponse{AffectedItems: res}, Err: err}, nil
+	}
+}
+
+// MakeGetSpotsInZoneEndpoint returns an endpoint that invokes GetSpotsInZone on the service.
+func MakeGetSpotsInZoneEndpoint(svc zone.ZoneHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(GetSpotsInZoneRequest)
+		res, err := svc.GetSpotsInZone(ctx, req.ObjectID, req.Floor)
+
+		// wrap service response with endpoint response
+		return GetSpotsResponse{Res: res, Err: err}, nil
+	}
+}
+//Make Maze Endpoints
+
+// MakeCreateMazeEndpoint returns an endpoint that invokes CreateMaze on the service.
+func MakeCreateMazeEndpoint(svc maze.MazeHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(CreateMazeRequest)
+		res, err := svc.CreateMaze(ctx, req.Req)
+
+		// wrap service response with endpoint response
+		return CreateObjectResponse{Res: models.CreateObjectResponse{ID: res}, Err: err}, nil
+	}
+}
+
+// MakeGetMazesEndpoint returns an endpoint that invokes GetMazes on the service.
+func MakeGetMazesEndpoint(svc maze.MazeHandler) (ep endpoint.Endpoint) {
+
+	// interface parameter is ignored because request does not
+	// require input parameters.
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+
+		res, err := svc.GetMazes(ctx)
+
+		// wrap service response with endpoint response
+		return GetMazesResponse{Res: res, Err: err}, nil
+	}
+}
+
+// MakeGetMazeEndpoint returns an endpoint that invokes GetMaze on the service.
+func MakeGetMazeEndpoint(svc maze.MazeHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(GetSingleObjectRequest)
+		res, err := svc.GetMaze(ctx, req.ObjectID)
+
+		// wrap service response with endpoint response
+		return GetMazeResponse{Res: res, Err: err}, nil
+	}
+}
+
+// MakeModifyMazeEndpoint returns an endpoint that invokes ModifyMaze on the service.
+func MakeModifyMazeEndpoint(svc maze.MazeHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(ModifyMazeRequest)
+		res, err := svc.ModifyMaze(ctx, req.Req, req.ID)
+
+		// wrap service response with endpoint response
+		return ModifyObjectResponse{Res: models.ModifyObjectResponse{AffectedItems: res}, Err: err}, nil
+	}
+}
+
+// MakeDeleteMazeEndpoint returns an endpoint that invokes DeleteMaze on the service.
+func MakeDeleteMazeEndpoint(svc maze.MazeHandler) (ep endpoint.Endpoint) {
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+
+		req := request.(GetSingleObjectRequest)
+		res, err := svc.DeleteMaze(ctx, req.ObjectID)
+
+		// wrap service response with endpoint response
+		return ModifyObjectResponse{Res: models.ModifyObjectResponse{AffectedItems: res}, Err: err}, nil
+	}
+}
+
 type CreateSpotRequest struct {
 	Req models.Spot
 }
@@ -323,6 +733,14 @@ type CreateOriginRequest struct {
 	Req models.Origin
 }
 
+type CreateZoneRequest struct {
+	Req models.Zone
+}
+
+type CreateMazeRequest struct {
+	Req models.Maze
+}
+
 type CreateObjectResponse struct {
 	Res models.CreateObjectResponse
 	Err error
@@ -347,6 +765,70 @@ type GetOriginResponse struct {
 	Err error
 }
 
+type GetMazeResponse struct {
+	Res models.Maze
+	Err error
+}
+
+type GetMazesResponse struct {
+	Res []models.Maze
+	Err error
+}
+
+type ModifyMazeRequest struct {
+	Req models.Maze
+	ID  string
+}
+
+type GetSingleZoneResponse struct {
+	Res models.Zone
+	Err error
+}
+
+type GetZonesResponse struct {
+	Res []models.Zone
+	Err error
+}
+
+type GetSingleLocalSpotResponse struct {
+	Res models.LocalSpot
+	Err error
+}
+
+type GetLocalSpotsResponse struct {
+	Res []models.LocalSpot
+	Err error
+}
+
+type GetPolarSpotsResponse struct {
+	Res []models.PolarSpot
+	Err error
+}
+
+type GetQuadrantStatsResponse struct {
+	Res map[string]models.SpotStats
+	Err error
+}
+
+//GetHullRequest carries the quadrant the hull is restricted to, empty for every spot
+type GetHullRequest struct {
+	Quadrant string
+}
+
+type GetHullResponse struct {
+	Res models.Hull
+	Err error
+}
+
+type SuggestOriginRequest struct {
+	Req models.OriginSuggestionQuery
+}
+
+type SuggestOriginResponse struct {
+	Res models.OriginSuggestion
+	Err error
+}
+
 type GetSpotsResponse struct {
 	Res []models.Spot
 	Err error
@@ -361,6 +843,53 @@ type GetSpotsInQuadrantRequest struct {
 	Req models.Quadrant
 }
 
+type GetSpotsInBoxRequest struct {
+	Req models.BoxQuery
+}
+
+type GetSpotsInRadiusRequest struct {
+	Req models.RadiusQuery
+}
+
+type GetNearestSpotsRequest struct {
+	Req models.NearestQuery
+}
+
+//GetSpotGridRequest carries the format the grid is wanted in: json, png or svg
+type GetSpotGridRequest struct {
+	Req    models.GridQuery
+	Format string
+}
+
+type GetSpotGridResponse struct {
+	Res    models.Grid
+	Format string
+	Err    error
+}
+
+type ClusterSpotsRequest struct {
+	Req models.ClusterQuery
+}
+
+type ClusterSpotsResponse struct {
+	Res models.Clustering
+	Err error
+}
+
+//GetSpotsInZoneRequest carries the floor the zone is restricted to, nil for every floor
+type GetSpotsInZoneRequest struct {
+	ObjectID string
+	Floor    *float64
+}
+
+type GetSpotsInClusterRequest struct {
+	Label int
+}
+
+type GetSpotsInSectorRequest struct {
+	Req models.PolarQuery
+}
+
 type ModifySpotRequest struct {
 	Req models.Spot
 	ID  string
@@ -371,6 +900,11 @@ type ModifyPathRequest struct {
 	ID  string
 }
 
+type ModifyZoneRequest struct {
+	Req models.Zone
+	ID  string
+}
+
 type ModifyObjectResponse struct {
 	Res models.ModifyObjectResponse
 	Err error
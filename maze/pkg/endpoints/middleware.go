@@ -4,6 +4,8 @@ import (
 	"context"
 	"time"
 
+	"github.com/avanticaTest/maze/pkg/db"
+	"github.com/avanticaTest/maze/pkg/service/maze"
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/log"
 )
@@ -22,3 +24,25 @@ func LoggingMiddleware(logger log.Logger) endpoint.Middleware {
 		}
 	}
 }
+
+type mazeIDKey struct{}
+
+//WithMazeID keeps the ID of the maze a request was made to, as the transport read it
+func WithMazeID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, mazeIDKey{}, id)
+}
+
+//MazeMiddleware scopes the repositories to the maze the request was made to, so nothing stored in any other maze can
+//be seen nor changed. Requests to mazes that don't exist fail before reaching the service
+func MazeMiddleware(mazes maze.MazeHandler) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			id, _ := ctx.Value(mazeIDKey{}).(string)
+			m, err := mazes.GetMaze(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return next(db.WithMaze(ctx, m.ID), request)
+		}
+	}
+}
@@ -1,9 +1,163 @@
 package errors
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/http"
+)
 
 var (
 	ErrResponseEncoding     = errors.New("an error occurred encoding response")
 	ErrMissingBodyContent   = errors.New("missing content")
 	ErrMalformedBodyContent = errors.New("malformed content")
+	ErrMissingQueryParam    = errors.New("missing query parameter")
+	ErrMalformedQueryParam  = errors.New("malformed query parameter")
 )
+
+//NotFound is returned when what's asked for doesn't exist. It's answered with 404 Not Found
+type NotFound struct {
+	//Resource is the kind of thing looked for, such as spot or path
+	Resource string
+	//ID is the one looked for, empty for the things there's only one of, like the origin
+	ID string
+}
+
+//NewNotFound creates the error of a resource missing, the ID being empty for the ones there's only one of
+func NewNotFound(resource, id string) error {
+	return NotFound{Resource: resource, ID: id}
+}
+
+func (e NotFound) Error() string {
+	if e.ID == "" {
+		return "No " + e.Resource + " found"
+	}
+	return "No " + e.Resource + " found with ID " + e.ID
+}
+
+//StatusCode is the status the error is answered with
+func (e NotFound) StatusCode() int {
+	return http.StatusNotFound
+}
+
+//Missing turns the error of a lookup that found nothing into NotFound, leaving any other error as it is
+func Missing(err error, resource, id string) error {
+	if err == mongo.ErrNoDocuments {
+		return NewNotFound(resource, id)
+	}
+	return err
+}
+
+//InvalidID is returned when an ID isn't a hexadecimal ObjectID. It's answered with 400 Bad Request
+type InvalidID struct {
+	//Field is where the ID was given, such as id or point_a
+	Field string
+	Value string
+}
+
+func (e InvalidID) Error() string {
+	return "Invalid " + e.Field + ": " + e.Value + " isn't a 24 digit hexadecimal ID"
+}
+
+//StatusCode is the status the error is answered with
+func (e InvalidID) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+//ParseID reads the ID given in the field, failing with InvalidID
+func ParseID(field, value string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		return primitive.NilObjectID, InvalidID{Field: field, Value: value}
+	}
+	return id, nil
+}
+
+//Conflict is returned when a request clashes with what's stored. It's answered with 409 Conflict
+type Conflict struct {
+	Message string
+}
+
+//NewConflict creates a conflict with the message
+func NewConflict(message string) error {
+	return Conflict{Message: message}
+}
+
+func (e Conflict) Error() string {
+	return e.Message
+}
+
+//StatusCode is the status the error is answered with
+func (e Conflict) StatusCode() int {
+	return http.StatusConflict
+}
+
+//Validation is returned when a request is well formed but its values can't be accepted. It's answered with
+//422 Unprocessable Entity
+type Validation struct {
+	Message string
+}
+
+//NewValidation creates a validation error with the message
+func NewValidation(message string) error {
+	return Validation{Message: message}
+}
+
+func (e Validation) Error() string {
+	return e.Message
+}
+
+//StatusCode is the status the error is answered with
+func (e Validation) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+//Problem describes an error as RFC 7807 problem details. Resource and ID come with the NotFound errors, Field and
+//Value with the InvalidID ones
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Value    string `json:"value,omitempty"`
+}
+
+//ProblemFor describes the error. The ones of unknown kinds are internal errors, whose detail is left out since it's
+//of no use to the client
+func ProblemFor(err error) Problem {
+	var (
+		notFound   NotFound
+		invalidID  InvalidID
+		conflict   Conflict
+		validation Validation
+		syntax     *json.SyntaxError
+		types      *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &notFound):
+		p := problem("not-found", notFound.StatusCode(), err)
+		p.Resource, p.ID = notFound.Resource, notFound.ID
+		return p
+	case errors.As(err, &invalidID):
+		p := problem("invalid-id", invalidID.StatusCode(), err)
+		p.Field, p.Value = invalidID.Field, invalidID.Value
+		return p
+	case errors.As(err, &conflict):
+		return problem("conflict", conflict.StatusCode(), err)
+	case errors.As(err, &validation):
+		return problem("validation", validation.StatusCode(), err)
+	case errors.Is(err, ErrMissingBodyContent), errors.Is(err, ErrMalformedBodyContent),
+		errors.Is(err, ErrMissingQueryParam), errors.Is(err, ErrMalformedQueryParam),
+		errors.As(err, &syntax), errors.As(err, &types):
+		return problem("bad-request", http.StatusBadRequest, err)
+	}
+	return Problem{Type: "about:blank", Title: http.StatusText(http.StatusInternalServerError), Status: http.StatusInternalServerError}
+}
+
+func problem(kind string, status int, err error) Problem {
+	return Problem{Type: "urn:maze:problem:" + kind, Title: http.StatusText(status), Status: status, Detail: err.Error()}
+}
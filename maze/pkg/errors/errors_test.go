@@ -0,0 +1,64 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gotest.tools/v3/assert"
+	"net/http"
+	"testing"
+)
+
+func TestProblemFor(t *testing.T) {
+
+	_, invalidID := ParseID("point_a", "nope")
+
+	tests := []struct {
+		name     string
+		err      error
+		expected Problem
+	}{
+		{
+			name: "Not found",
+			err:  Missing(mongo.ErrNoDocuments, "spot", "5fbecff95f80a305742abb10"),
+			expected: Problem{Type: "urn:maze:problem:not-found", Title: "Not Found", Status: http.StatusNotFound,
+				Detail: "No spot found with ID 5fbecff95f80a305742abb10", Resource: "spot", ID: "5fbecff95f80a305742abb10"},
+		},
+		{
+			name: "Invalid ID",
+			err:  invalidID,
+			expected: Problem{Type: "urn:maze:problem:invalid-id", Title: "Bad Request", Status: http.StatusBadRequest,
+				Detail: "Invalid point_a: nope isn't a 24 digit hexadecimal ID", Field: "point_a", Value: "nope"},
+		},
+		{
+			name: "Wrapped conflict",
+			err:  fmt.Errorf("creating: %w", NewConflict("There can be only one Origin")),
+			expected: Problem{Type: "urn:maze:problem:conflict", Title: "Conflict", Status: http.StatusConflict,
+				Detail: "creating: There can be only one Origin"},
+		},
+		{
+			name: "Validation",
+			err:  NewValidation("Invalid radius: it must not be negative"),
+			expected: Problem{Type: "urn:maze:problem:validation", Title: "Unprocessable Entity",
+				Status: http.StatusUnprocessableEntity, Detail: "Invalid radius: it must not be negative"},
+		},
+		{
+			name: "Malformed body",
+			err:  json.Unmarshal([]byte("{"), &struct{}{}),
+			expected: Problem{Type: "urn:maze:problem:bad-request", Title: "Bad Request", Status: http.StatusBadRequest,
+				Detail: "unexpected end of JSON input"},
+		},
+		{
+			name:     "Internal",
+			err:      errors.New("connection refused"),
+			expected: Problem{Type: "about:blank", Title: "Internal Server Error", Status: http.StatusInternalServerError},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.DeepEqual(t, tt.expected, ProblemFor(tt.err))
+		})
+	}
+}
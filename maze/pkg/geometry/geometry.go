@@ -0,0 +1,309 @@
+package geometry
+
+import (
+	"github.com/avanticaTest/maze/pkg/models"
+	"math"
+	"sort"
+)
+
+const (
+	UpperLeft   = "upper_left"
+	UpperRight  = "upper_right"
+	BottomLeft  = "bottom_left"
+	BottomRight = "bottom_right"
+)
+
+//QuadrantReach is how far the quadrant polygons extend from the origin. It's far beyond any realistic maze, so they
+//behave as if they were unbounded
+const QuadrantReach = 1e12
+
+//EarthRadius is the mean radius of the earth, in meters
+const EarthRadius = 6371008.8
+
+//Quadrants returns the four quadrants as origin relative zones. Their borders lie on the axes, so a spot on an axis
+//belongs to every quadrant sharing it
+func Quadrants() []models.Zone {
+	r := QuadrantReach
+	return []models.Zone{
+		quadrant(UpperLeft, -r, r),
+		quadrant(UpperRight, r, r),
+		quadrant(BottomLeft, -r, -r),
+		quadrant(BottomRight, r, -r),
+	}
+}
+
+//IsQuadrant tells whether the name is one of the four quadrants
+func IsQuadrant(name string) bool {
+	switch name {
+	case UpperLeft, UpperRight, BottomLeft, BottomRight:
+		return true
+	}
+	return false
+}
+
+func quadrant(name string, x, y float64) models.Zone {
+	return models.Zone{
+		Name:           name,
+		Polygon:        []models.Point{{X: 0, Y: 0}, {X: x, Y: 0}, {X: x, Y: y}, {X: 0, Y: y}},
+		OriginRelative: true,
+		BuiltIn:        true,
+	}
+}
+
+//ToLocal converts world coordinates into the origin's frame: translated, rotated and scaled. The frame of a geographic
+//origin measures degrees east and north of it, longitudes wrapping around
+func ToLocal(origin models.Origin, x, y float64) (float64, float64) {
+	dx, dy := x-origin.XOrigin, y-origin.YOrigin
+	if origin.Geographic {
+		dx = WrapLongitude(dx)
+	}
+	sin, cos := sincos(origin.Rotation)
+	xScale, yScale := Scales(origin)
+	return (dx*cos + dy*sin) / xScale, (dy*cos - dx*sin) / yScale
+}
+
+//ToWorld converts coordinates in the origin's frame back into world coordinates
+func ToWorld(origin models.Origin, x, y float64) (float64, float64) {
+	xScale, yScale := Scales(origin)
+	x, y = x*xScale, y*yScale
+	sin, cos := sincos(origin.Rotation)
+	x, y = x*cos-y*sin+origin.XOrigin, x*sin+y*cos+origin.YOrigin
+	if origin.Geographic {
+		x = WrapLongitude(x)
+	}
+	return x, y
+}
+
+//WrapLongitude brings a longitude, or a difference between two, into (-180, 180]. The meridian opposite to the one
+//the differences are taken from is therefore east of it
+func WrapLongitude(degrees float64) float64 {
+	degrees = math.Mod(degrees, 360)
+	if degrees <= -180 {
+		return degrees + 360
+	}
+	if degrees > 180 {
+		return degrees - 360
+	}
+	return degrees
+}
+
+//ValidLatLon tells whether the coordinates are a longitude in (-180, 180] and a latitude in [-90, 90]. -180 is left
+//out so every meridian has a single longitude
+func ValidLatLon(lon, lat float64) bool {
+	return lon > -180 && lon <= 180 && lat >= -90 && lat <= 90
+}
+
+//GreatCircle returns the distance in meters between two points given by their longitude and latitude, using the
+//haversine formula
+func GreatCircle(lon1, lat1, lon2, lat2 float64) float64 {
+	toRadians := math.Pi / 180
+	dLat := (lat2 - lat1) * toRadians
+	dLon := (lon2 - lon1) * toRadians
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1*toRadians)*math.Cos(lat2*toRadians)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * EarthRadius * math.Asin(math.Sqrt(math.Min(h, 1)))
+}
+
+//GeoAngle returns the direction the great circle from the first point to the second leaves the first in, in degrees
+//between 0 and 360 counterclockwise from east, as Polar does on the plane
+func GeoAngle(lon1, lat1, lon2, lat2 float64) float64 {
+	toRadians := math.Pi / 180
+	dLon := (lon2 - lon1) * toRadians
+	sin1, cos1 := math.Sincos(lat1 * toRadians)
+	sin2, cos2 := math.Sincos(lat2 * toRadians)
+	east := math.Sin(dLon) * cos2
+	north := cos1*sin2 - sin1*cos2*math.Cos(dLon)
+	return NormalizeAngle(math.Atan2(north, east) / toRadians)
+}
+
+//GeoBox returns the longitudes and latitudes bounding the points within radius meters of the given one. The box spans
+//every longitude when the circle reaches a pole or crosses the antimeridian
+func GeoBox(lon, lat, radius float64) (minLon, minLat, maxLon, maxLat float64) {
+	angle := radius / EarthRadius
+	if angle >= math.Pi {
+		return -180, -90, 180, 90
+	}
+	toDegrees := 180 / math.Pi
+	minLat, maxLat = lat-angle*toDegrees, lat+angle*toDegrees
+	if minLat <= -90 || maxLat >= 90 {
+		return -180, math.Max(minLat, -90), 180, math.Min(maxLat, 90)
+	}
+	//the widest longitudes are reached where the circle's meridians are tangent to it
+	dLon := math.Asin(math.Min(math.Sin(angle)/math.Cos(lat*math.Pi/180), 1)) * toDegrees
+	minLon, maxLon = lon-dLon, lon+dLon
+	if minLon < -180 || maxLon > 180 {
+		return -180, minLat, 180, maxLat
+	}
+	return minLon, minLat, maxLon, maxLat
+}
+
+//Axes returns the directions of the origin's x and y axes as unit vectors in world coordinates
+func Axes(origin models.Origin) (x, y models.Point) {
+	sin, cos := sincos(origin.Rotation)
+	return models.Point{X: cos, Y: sin}, models.Point{X: -sin, Y: cos}
+}
+
+//QuadrantSigns returns the signs the local coordinates of the spots in a quadrant have: 1 for positive and -1 for
+//negative, zero being allowed in both cases
+func QuadrantSigns(name string) (x, y float64) {
+	switch name {
+	case UpperLeft:
+		return -1, 1
+	case UpperRight:
+		return 1, 1
+	case BottomLeft:
+		return -1, -1
+	}
+	return 1, -1
+}
+
+//sincos returns the sine and cosine of an angle in degrees. Right angles are exact, so spots lying on the axes of a
+//frame rotated by them stay on the quadrant borders
+func sincos(degrees float64) (float64, float64) {
+	switch math.Mod(math.Mod(degrees, 360)+360, 360) {
+	case 0:
+		return 0, 1
+	case 90:
+		return 1, 0
+	case 180:
+		return 0, -1
+	case 270:
+		return -1, 0
+	}
+	return math.Sincos(degrees * math.Pi / 180)
+}
+
+//Scales returns the origin's axis scales, a missing one standing for no scaling
+func Scales(origin models.Origin) (float64, float64) {
+	xScale, yScale := origin.XScale, origin.YScale
+	if xScale == 0 {
+		xScale = 1
+	}
+	if yScale == 0 {
+		yScale = 1
+	}
+	return xScale, yScale
+}
+
+//Polar returns the angle, in degrees between 0 and 360, and the radius of a point given in the origin's frame
+func Polar(x, y float64) (angle, radius float64) {
+	return NormalizeAngle(math.Atan2(y, x) * 180 / math.Pi), math.Hypot(x, y)
+}
+
+//NormalizeAngle brings an angle in degrees between 0 and 360
+func NormalizeAngle(degrees float64) float64 {
+	degrees = math.Mod(degrees, 360)
+	if degrees < 0 {
+		degrees += 360
+	}
+	return degrees
+}
+
+//InSector tells whether the angle lies in the sector going counterclockwise from one angle to the other, both
+//included. Sectors spanning 360 degrees or more cover the whole circle
+func InSector(angle, from, to float64) bool {
+	if to-from >= 360 {
+		return true
+	}
+	return NormalizeAngle(angle-from) <= NormalizeAngle(to-from)
+}
+
+//Bounds returns the axis-aligned rectangle enclosing the polygon
+func Bounds(polygon []models.Point) (minX, minY, maxX, maxY float64) {
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+	for _, p := range polygon {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	return minX, minY, maxX, maxY
+}
+
+//InPolygon tells whether the point lies inside the polygon or on its border, using ray casting
+func InPolygon(x, y float64, polygon []models.Point) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[j], polygon[i]
+		if onSegment(x, y, a, b) {
+			return true
+		}
+		if (a.Y > y) != (b.Y > y) && x < (b.X-a.X)*(y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+//onSegment tells whether the point lies on the segment going from a to b
+func onSegment(x, y float64, a, b models.Point) bool {
+	cross := (b.X-a.X)*(y-a.Y) - (b.Y-a.Y)*(x-a.X)
+	if cross != 0 {
+		return false
+	}
+	return x >= math.Min(a.X, b.X) && x <= math.Max(a.X, b.X) &&
+		y >= math.Min(a.Y, b.Y) && y <= math.Max(a.Y, b.Y)
+}
+
+//ConvexHull returns the smallest convex polygon enclosing the points, counterclockwise from the lowest leftmost point,
+//using Andrew's monotone chain. Points lying on its edges are left out, so collinear points give a two point hull
+func ConvexHull(points []models.Point) []models.Point {
+	sorted := append([]models.Point(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].X != sorted[j].X {
+			return sorted[i].X < sorted[j].X
+		}
+		return sorted[i].Y < sorted[j].Y
+	})
+	unique := sorted[:0]
+	for i, p := range sorted {
+		if i == 0 || p != sorted[i-1] {
+			unique = append(unique, p)
+		}
+	}
+	if len(unique) < 3 {
+		return unique
+	}
+
+	hull := make([]models.Point, 0, 2*len(unique))
+	//the lower chain goes left to right and the upper one back, each dropping the points that don't turn left
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for _, p := range unique {
+			for len(hull) >= start+2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		//the last point of a chain is the first of the other one
+		hull = hull[:len(hull)-1]
+		for i, j := 0, len(unique)-1; i < j; i, j = i+1, j-1 {
+			unique[i], unique[j] = unique[j], unique[i]
+		}
+	}
+	return hull
+}
+
+//Area returns the area enclosed by the polygon, using the shoelace formula
+func Area(polygon []models.Point) float64 {
+	sum := 0.0
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		sum += polygon[j].X*polygon[i].Y - polygon[i].X*polygon[j].Y
+	}
+	return math.Abs(sum) / 2
+}
+
+//Perimeter returns the length of the polygon's border. A two point polygon goes there and back
+func Perimeter(polygon []models.Point) float64 {
+	if len(polygon) < 2 {
+		return 0
+	}
+	sum := 0.0
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		sum += math.Hypot(polygon[i].X-polygon[j].X, polygon[i].Y-polygon[j].Y)
+	}
+	return sum
+}
+
+//cross is positive when going from a to b and then to c turns left
+func cross(a, b, c models.Point) float64 {
+	return (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+}
@@ -0,0 +1,194 @@
+package geometry
+
+import (
+	"github.com/avanticaTest/maze/pkg/models"
+	"gotest.tools/v3/assert"
+	"math"
+	"testing"
+)
+
+func TestInPolygon(t *testing.T) {
+
+	//a concave "L" shape
+	polygon := []models.Point{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 2}, {X: 2, Y: 2}, {X: 2, Y: 4}, {X: 0, Y: 4}}
+
+	tests := []struct {
+		name     string
+		x, y     float64
+		expected bool
+	}{
+		{name: "Inside", x: 1, y: 1, expected: true},
+		{name: "Inside the arm", x: 1, y: 3, expected: true},
+		{name: "In the notch", x: 3, y: 3, expected: false},
+		{name: "On an edge", x: 3, y: 2, expected: true},
+		{name: "On a vertex", x: 4, y: 0, expected: true},
+		{name: "Outside", x: -1, y: 1, expected: false},
+		{name: "Aligned with an edge", x: 5, y: 0, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, InPolygon(tt.x, tt.y, polygon))
+		})
+	}
+}
+
+func TestQuadrantsShareAxes(t *testing.T) {
+
+	var containing []string
+	for _, q := range Quadrants() {
+		if InPolygon(0, 5, q.Polygon) {
+			containing = append(containing, q.Name)
+		}
+	}
+	assert.DeepEqual(t, []string{UpperLeft, UpperRight}, containing)
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+
+	origin := models.Origin{XOrigin: 3, YOrigin: -2, Rotation: 90, XScale: 2}
+
+	x, y := ToLocal(origin, 3, 2)
+	assert.Equal(t, 2.0, x)
+	assert.Equal(t, 0.0, y)
+
+	wx, wy := ToWorld(origin, x, y)
+	assert.Equal(t, 3.0, wx)
+	assert.Equal(t, 2.0, wy)
+}
+
+func TestInSector(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		angle    float64
+		from, to float64
+		expected bool
+	}{
+		{name: "Inside", angle: 45, from: 30, to: 60, expected: true},
+		{name: "On the border", angle: 60, from: 30, to: 60, expected: true},
+		{name: "Outside", angle: 90, from: 30, to: 60, expected: false},
+		{name: "Wrapping around", angle: 5, from: 350, to: 10, expected: true},
+		{name: "Outside a wrapping sector", angle: 180, from: 350, to: 10, expected: false},
+		{name: "Whole circle", angle: 180, from: 90, to: 450, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, InSector(tt.angle, tt.from, tt.to))
+		})
+	}
+}
+
+func TestConvexHull(t *testing.T) {
+
+	tests := []struct {
+		name            string
+		points          []models.Point
+		expected        []models.Point
+		area, perimeter float64
+	}{
+		{
+			name: "Square with inner and edge points",
+			points: []models.Point{{X: 2, Y: 2}, {X: 0, Y: 0}, {X: 4, Y: 4}, {X: 1, Y: 3}, {X: 4, Y: 0}, {X: 0, Y: 4},
+				{X: 2, Y: 0}, {X: 4, Y: 4}},
+			expected:  []models.Point{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 4, Y: 4}, {X: 0, Y: 4}},
+			area:      16,
+			perimeter: 16,
+		},
+		{
+			name:      "Collinear",
+			points:    []models.Point{{X: 3, Y: 3}, {X: 0, Y: 0}, {X: 1, Y: 1}},
+			expected:  []models.Point{{X: 0, Y: 0}, {X: 3, Y: 3}},
+			area:      0,
+			perimeter: 2 * math.Hypot(3, 3),
+		},
+		{
+			name:     "Single point",
+			points:   []models.Point{{X: 1, Y: 2}, {X: 1, Y: 2}},
+			expected: []models.Point{{X: 1, Y: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hull := ConvexHull(tt.points)
+			assert.DeepEqual(t, tt.expected, hull)
+			assert.Equal(t, tt.area, Area(hull))
+			assert.Equal(t, tt.perimeter, Perimeter(hull))
+		})
+	}
+}
+
+func TestGeographicFrame(t *testing.T) {
+
+	//the origin sits next to the antimeridian, so the spots right across it are east of the origin
+	origin := models.Origin{XOrigin: 170, YOrigin: -10, Geographic: true}
+
+	x, y := ToLocal(origin, -170, 5)
+	assert.Equal(t, 20.0, x)
+	assert.Equal(t, 15.0, y)
+
+	wx, wy := ToWorld(origin, x, y)
+	assert.Equal(t, -170.0, wx)
+	assert.Equal(t, 5.0, wy)
+
+	assert.Equal(t, 180.0, WrapLongitude(-180))
+	assert.Assert(t, !ValidLatLon(-180, 0))
+	assert.Assert(t, !ValidLatLon(10, 90.5))
+}
+
+func TestGreatCircle(t *testing.T) {
+
+	//a quarter of the equator
+	assert.Assert(t, math.Abs(GreatCircle(0, 0, 90, 0)-math.Pi*EarthRadius/2) < 1e-6)
+	//Paris to London, about 344km
+	d := GreatCircle(2.3522, 48.8566, -0.1276, 51.5072)
+	assert.Assert(t, d > 343000 && d < 345000, d)
+}
+
+func TestGeoBox(t *testing.T) {
+
+	//the points at the radius, east, west, north and south, lie on the box
+	minLon, minLat, maxLon, maxLat := GeoBox(10, 60, 5000)
+	assert.Assert(t, math.Abs(GreatCircle(10, 60, 10, maxLat)-5000) < 1e-6)
+	assert.Assert(t, math.Abs(GreatCircle(10, 60, 10, minLat)-5000) < 1e-6)
+	//at 60 degrees a degree of longitude is about half of one at the equator
+	assert.Assert(t, maxLon-10 > 2*(maxLat-60)*0.99, maxLon)
+	assert.Assert(t, minLon < 10 && maxLon > 10)
+
+	minLon, _, maxLon, _ = GeoBox(179.99, 0, 5000)
+	assert.Equal(t, -180.0, minLon)
+	assert.Equal(t, 180.0, maxLon)
+	minLon, _, maxLon, maxLat = GeoBox(0, 89.99, 5000)
+	assert.Equal(t, -180.0, minLon)
+	assert.Equal(t, 90.0, maxLat)
+}
+
+func TestPartitionSectors(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		x, y     float64
+		n        int
+		rule     string
+		expected []int
+	}{
+		{name: "Inside an octant", x: 2, y: 1, n: 8, expected: []int{0}},
+		{name: "On a diagonal", x: -3, y: 3, n: 8, expected: []int{2, 3}},
+		{name: "On a diagonal, counterclockwise", x: -3, y: 3, n: 8, rule: Counterclockwise, expected: []int{3}},
+		{name: "On a diagonal, clockwise", x: -3, y: 3, n: 8, rule: Clockwise, expected: []int{2}},
+		{name: "On the x axis, clockwise", x: 5, y: 0, n: 4, rule: Clockwise, expected: []int{3}},
+		{name: "Halves, counterclockwise", x: -5, y: 0, n: 2, rule: Counterclockwise, expected: []int{1}},
+		{name: "Halves, clockwise", x: 5, y: 0, n: 2, rule: Clockwise, expected: []int{1}},
+		{name: "Origin", n: 3, expected: []int{0, 1, 2}},
+		{name: "Origin, clockwise", n: 3, rule: Clockwise, expected: []int{2}},
+		{name: "Single sector", x: -1, y: -1, n: 1, expected: []int{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.DeepEqual(t, tt.expected, PartitionSectors(tt.x, tt.y, tt.n, tt.rule))
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package geometry
+
+import (
+	"math"
+	"sort"
+)
+
+//Partition schemes split the plane around the origin into equal sectors, numbered counterclockwise from its x axis
+const (
+	QuadrantScheme = "quadrants"
+	OctantScheme   = "octants"
+	SectorScheme   = "sectors"
+)
+
+//Tie-breaking rules for the spots lying exactly on the boundary between two sectors
+const (
+	//Shared puts the spot in both sectors, as the quadrants always did
+	Shared = "shared"
+	//Counterclockwise puts the spot in the sector lying counterclockwise of the boundary, the one it starts
+	Counterclockwise = "counterclockwise"
+	//Clockwise puts the spot in the sector lying clockwise of the boundary, the one it ends
+	Clockwise = "clockwise"
+)
+
+//QuadrantSector returns the index of the quadrant in a four sector partition, or -1 when the name isn't a quadrant
+func QuadrantSector(name string) int {
+	switch name {
+	case UpperRight:
+		return 0
+	case UpperLeft:
+		return 1
+	case BottomLeft:
+		return 2
+	case BottomRight:
+		return 3
+	}
+	return -1
+}
+
+//QuadrantName returns the name of the quadrant with the index in a four sector partition
+func QuadrantName(sector int) string {
+	return []string{UpperRight, UpperLeft, BottomLeft, BottomRight}[sector]
+}
+
+//IsBoundaryRule tells whether the tie-breaking rule is known, an empty one standing for Shared
+func IsBoundaryRule(rule string) bool {
+	switch rule {
+	case "", Shared, Counterclockwise, Clockwise:
+		return true
+	}
+	return false
+}
+
+//Ray returns the unit vector pointing at the angle, in degrees. Multiples of 45 degrees are exact and symmetric, so
+//spots on the diagonals lie exactly on the octant boundaries
+func Ray(degrees float64) (x, y float64) {
+	normalized := NormalizeAngle(degrees)
+	if math.Mod(normalized, 90) == 45 {
+		h := math.Sqrt2 / 2
+		x, y = h, h
+		if normalized > 90 && normalized < 270 {
+			x = -h
+		}
+		if normalized > 180 {
+			y = -h
+		}
+		return x, y
+	}
+	sin, cos := sincos(normalized)
+	return cos, sin
+}
+
+//PartitionSectors returns the sectors a point, in the origin's frame, lies in when the plane is split into n equal
+//sectors. A point on a boundary lies in the two sectors sharing it unless the rule picks one of them. The origin
+//itself is treated as lying on the boundary at zero degrees
+func PartitionSectors(x, y float64, n int, rule string) []int {
+	if n < 2 {
+		return []int{0}
+	}
+	span := 360 / float64(n)
+
+	var closed []int
+	if x == 0 && y == 0 {
+		closed = []int{0, n - 1}
+		if rule == "" || rule == Shared {
+			closed = make([]int, n)
+			for i := range closed {
+				closed[i] = i
+			}
+			return closed
+		}
+	} else {
+		//the angle gives the sector up to rounding, the exact tests settle the neighbours
+		angle, _ := Polar(x, y)
+		guess := int(angle / span)
+		for _, i := range []int{guess - 1, guess, guess + 1} {
+			i = (i%n + n) % n
+			if inClosedSector(x, y, i, span) && !containsInt(closed, i) {
+				closed = append(closed, i)
+			}
+		}
+	}
+	if len(closed) < 2 || rule == "" || rule == Shared {
+		sort.Ints(closed)
+		return closed
+	}
+
+	//the boundary between the two sectors is the ray starting the counterclockwise one
+	counterclockwise, clockwise := closed[1], closed[0]
+	if x == 0 && y == 0 || onRay(x, y, float64(closed[0])*span) {
+		counterclockwise, clockwise = closed[0], closed[1]
+	}
+	if rule == Counterclockwise {
+		return []int{counterclockwise}
+	}
+	return []int{clockwise}
+}
+
+//onRay tells whether the point lies on the ray leaving the origin at the angle
+func onRay(x, y, degrees float64) bool {
+	rx, ry := Ray(degrees)
+	return rx*y-ry*x == 0 && rx*x+ry*y > 0
+}
+
+//inClosedSector tells whether the point lies in the sector, both boundaries included
+func inClosedSector(x, y float64, sector int, span float64) bool {
+	fromX, fromY := Ray(float64(sector) * span)
+	toX, toY := Ray(float64(sector+1) * span)
+	//left of the ray starting the sector and right of the one ending it. A half-plane sector only needs the first test
+	afterFrom := fromX*y-fromY*x >= 0
+	if span >= 180 {
+		return afterFrom
+	}
+	return afterFrom && toX*y-toY*x <= 0
+}
+
+func containsInt(values []int, v int) bool {
+	for _, w := range values {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
@@ -6,10 +6,13 @@ import (
 	"github.com/avanticaTest/maze/pkg/endpoints"
 	"github.com/avanticaTest/maze/pkg/errors"
 	"github.com/avanticaTest/maze/pkg/models"
+	"github.com/avanticaTest/maze/pkg/render"
 	"github.com/go-kit/kit/transport"
 	"github.com/gorilla/mux"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -21,105 +24,255 @@ import (
 func NewHTTPHandler(endpoints endpoints.Endpoints, logger log.Logger) http.Handler {
 	options := []httptransport.ServerOption{
 		httptransport.ServerErrorHandler(transport.NewLogErrorHandler(logger)),
+		httptransport.ServerErrorEncoder(EncodeError),
 		httptransport.ServerFinalizer(RequestLogFinalizer(logger)),
 	}
 
 	c := mux.NewRouter()
-	c.Methods("POST").Path( "/spot").Handler(httptransport.NewServer(
+	c.Methods("POST").Path("/mazes").Handler(httptransport.NewServer(
+		endpoints.CreateMazeEndpoint,
+		DecodeCreateMazeRequest,
+		EncodeCreateMazeResponse,
+		options...,
+	))
+	c.Methods("GET").Path("/mazes").Handler(httptransport.NewServer(
+		endpoints.GetMazesEndpoint,
+		DecodeGetMazesRequest,
+		EncodeGetMazesResponse,
+		options...,
+	))
+	c.Methods("GET").Path("/mazes/{mazeID}").Handler(httptransport.NewServer(
+		endpoints.GetMazeEndpoint,
+		DecodeGetMazeRequest,
+		EncodeGetMazeResponse,
+		options...,
+	))
+	c.Methods("PUT").Path("/mazes/{mazeID}").Handler(httptransport.NewServer(
+		endpoints.ModifyMazeEndpoint,
+		DecodeModifyMazeRequest,
+		EncodeModifyMazeResponse,
+		options...,
+	))
+	c.Methods("DELETE").Path("/mazes/{mazeID}").Handler(httptransport.NewServer(
+		endpoints.DeleteMazeEndpoint,
+		DecodeDeleteMazeRequest,
+		EncodeDeleteMazeResponse,
+		options...,
+	))
+
+	//everything else is stored in a maze, the one in the path
+	scoped := append(options, httptransport.ServerBefore(MazeFromPath))
+	m := c.PathPrefix("/mazes/{mazeID}").Subrouter()
+	m.Methods("POST").Path( "/spot").Handler(httptransport.NewServer(
 		endpoints.CreateSpotEndpoint,
 		DecodeCreateSpotRequest,
 		EncodeCreateSpotResponse,
-		append(options)...,
+		scoped...,
 	))
-	c.Methods("GET").Path( "/spot/{id}").Handler(httptransport.NewServer(
+	m.Methods("GET").Path( "/spot/{id}").Handler(httptransport.NewServer(
 		endpoints.GetSingleSpotEndpoint,
 		DecodeGetSingleSpotRequest,
 		EncodeGetSingleSpotResponse,
-		append(options)...,
+		scoped...,
 	))
-	c.Methods("PUT").Path( "/spot/{id}").Handler(httptransport.NewServer(
+	m.Methods("PUT").Path( "/spot/{id}").Handler(httptransport.NewServer(
 		endpoints.ModifySpotEndpoint,
 		DecodeModifySpotRequest,
 		EncodeModifySpotResponse,
-		append(options)...,
+		scoped...,
 	))
-	c.Methods("GET").Path( "/spots").Handler(httptransport.NewServer(
+	m.Methods("GET").Path( "/spots").Handler(httptransport.NewServer(
 		endpoints.GetSpotsEndpoint,
 		DecodeGetSpotsRequest,
 		EncodeGetSpotsResponse,
-		append(options)...,
+		scoped...,
 	))
-	c.Methods("DELETE").Path( "/spot/{id}").Handler(httptransport.NewServer(
+	m.Methods("DELETE").Path( "/spot/{id}").Handler(httptransport.NewServer(
 		endpoints.DeleteSpotEndpoint,
 		DecodeDeleteSpotRequest,
 		EncodeDeleteSpotResponse,
-		append(options)...,
+		scoped...,
+	))
+
+	m.Methods("GET").Path("/spots/box").Handler(httptransport.NewServer(
+		endpoints.GetSpotsInBoxEndpoint,
+		DecodeGetSpotsInBoxRequest,
+		EncodeGetSpotsResponse,
+		scoped...,
+	))
+	m.Methods("GET").Path("/spots/radius").Handler(httptransport.NewServer(
+		endpoints.GetSpotsInRadiusEndpoint,
+		DecodeGetSpotsInRadiusRequest,
+		EncodeGetSpotsResponse,
+		scoped...,
 	))
 
 	//PATH endpoints
 
-	c.Methods("POST").Path( "/path").Handler(httptransport.NewServer(
+	m.Methods("POST").Path( "/path").Handler(httptransport.NewServer(
 		endpoints.CreatePathEndpoint,
 		DecodeCreatePathRequest,
 		EncodeCreatePathResponse,
-		append(options)...,
+		scoped...,
 	))
-	c.Methods("GET").Path( "/path/{id}").Handler(httptransport.NewServer(
+	m.Methods("GET").Path( "/path/{id}").Handler(httptransport.NewServer(
 		endpoints.GetSinglePathEndpoint,
 		DecodeGetSinglePathRequest,
 		EncodeGetSinglePathResponse,
-		append(options)...,
+		scoped...,
 	))
-	c.Methods("PUT").Path( "/path/{id}").Handler(httptransport.NewServer(
+	m.Methods("PUT").Path( "/path/{id}").Handler(httptransport.NewServer(
 		endpoints.ModifyPathEndpoint,
 		DecodeModifyPathRequest,
 		EncodeModifyPathResponse,
-		append(options)...,
+		scoped...,
 	))
-	c.Methods("GET").Path( "/paths").Handler(httptransport.NewServer(
+	m.Methods("GET").Path( "/paths").Handler(httptransport.NewServer(
 		endpoints.GetPathsEndpoint,
 		DecodeGetPathsRequest,
 		EncodeGetPathsResponse,
-		append(options)...,
+		scoped...,
 	))
-	c.Methods("DELETE").Path( "/path/{id}").Handler(httptransport.NewServer(
+	m.Methods("DELETE").Path( "/path/{id}").Handler(httptransport.NewServer(
 		endpoints.DeletePathEndpoint,
 		DecodeDeletePathRequest,
 		EncodeDeletePathResponse,
-		append(options)...,
+		scoped...,
 	))
 
 	//ORIGIN endpoints
 
-	c.Methods("POST").Path( "/origin").Handler(httptransport.NewServer(
+	m.Methods("POST").Path( "/origin").Handler(httptransport.NewServer(
 		endpoints.CreateOriginEndpoint,
 		DecodeCreateOriginRequest,
 		EncodeCreateOriginResponse,
-		append(options)...,
+		scoped...,
 	))
-	c.Methods("GET").Path( "/origin").Handler(httptransport.NewServer(
+	m.Methods("GET").Path( "/origin").Handler(httptransport.NewServer(
 		endpoints.GetOriginEndpoint,
 		DecodeGetOriginRequest,
 		EncodeGetOriginResponse,
-		append(options)...,
+		scoped...,
 	))
-	c.Methods("PUT").Path( "/origin").Handler(httptransport.NewServer(
+	m.Methods("PUT").Path( "/origin").Handler(httptransport.NewServer(
 		endpoints.ModifyOriginEndpoint,
 		DecodeModifyOriginRequest,
 		EncodeModifyOriginResponse,
-		append(options)...,
+		scoped...,
 	))
-	c.Methods("POST").Path( "/quadrantSpots").Handler(httptransport.NewServer(
+	m.Methods("POST").Path( "/quadrantSpots").Handler(httptransport.NewServer(
 		endpoints.GetSpotsInQuadrantEndpoint,
 		DecodeGetSpotsInQuadrantRequest,
 		EncodeGetSpotsInQuadrantResponse,
-		append(options)...,
+		scoped...,
 	))
-	c.Methods("DELETE").Path( "/origin").Handler(httptransport.NewServer(
+	m.Methods("DELETE").Path( "/origin").Handler(httptransport.NewServer(
 		endpoints.DeleteOriginEndpoint,
 		DecodeDeleteOriginRequest,
 		EncodeDeleteOriginResponse,
-		append(options)...,
+		scoped...,
+	))
+
+	m.Methods("GET").Path("/spots/nearest").Handler(httptransport.NewServer(
+		endpoints.GetNearestSpotsEndpoint,
+		DecodeGetNearestSpotsRequest,
+		EncodeGetSpotsResponse,
+		scoped...,
+	))
+	m.Methods("GET").Path("/spots/grid").Handler(httptransport.NewServer(
+		endpoints.GetSpotGridEndpoint,
+		DecodeGetSpotGridRequest,
+		EncodeGetSpotGridResponse,
+		scoped...,
+	))
+	m.Methods("POST").Path("/spots/clusters").Handler(httptransport.NewServer(
+		endpoints.ClusterSpotsEndpoint,
+		DecodeClusterSpotsRequest,
+		EncodeClusterSpotsResponse,
+		scoped...,
+	))
+	m.Methods("GET").Path("/clusters/{label}/spots").Handler(httptransport.NewServer(
+		endpoints.GetSpotsInClusterEndpoint,
+		DecodeGetSpotsInClusterRequest,
+		EncodeGetSpotsResponse,
+		scoped...,
+	))
+	m.Methods("GET").Path("/spots/local").Handler(httptransport.NewServer(
+		endpoints.GetLocalSpotsEndpoint,
+		DecodeGetLocalSpotsRequest,
+		EncodeGetLocalSpotsResponse,
+		scoped...,
+	))
+	m.Methods("GET").Path("/spot/{id}/local").Handler(httptransport.NewServer(
+		endpoints.GetSingleLocalSpotEndpoint,
+		DecodeGetSingleLocalSpotRequest,
+		EncodeGetSingleLocalSpotResponse,
+		scoped...,
+	))
+
+	m.Methods("GET").Path("/spots/polar").Handler(httptransport.NewServer(
+		endpoints.GetSpotsInSectorEndpoint,
+		DecodeGetSpotsInSectorRequest,
+		EncodeGetSpotsInSectorResponse,
+		scoped...,
+	))
+
+	m.Methods("GET").Path("/quadrants/stats").Handler(httptransport.NewServer(
+		endpoints.GetQuadrantStatsEndpoint,
+		DecodeGetQuadrantStatsRequest,
+		EncodeGetQuadrantStatsResponse,
+		scoped...,
+	))
+	m.Methods("GET").Path("/spots/hull").Handler(httptransport.NewServer(
+		endpoints.GetHullEndpoint,
+		DecodeGetHullRequest,
+		EncodeGetHullResponse,
+		scoped...,
+	))
+	m.Methods("POST").Path("/origin/suggestion").Handler(httptransport.NewServer(
+		endpoints.SuggestOriginEndpoint,
+		DecodeSuggestOriginRequest,
+		EncodeSuggestOriginResponse,
+		scoped...,
+	))
+
+	//ZONE endpoints
+
+	m.Methods("POST").Path("/zone").Handler(httptransport.NewServer(
+		endpoints.CreateZoneEndpoint,
+		DecodeCreateZoneRequest,
+		EncodeCreateZoneResponse,
+		scoped...,
+	))
+	m.Methods("GET").Path("/zone/{id}").Handler(httptransport.NewServer(
+		endpoints.GetSingleZoneEndpoint,
+		DecodeGetSingleZoneRequest,
+		EncodeGetSingleZoneResponse,
+		scoped...,
+	))
+	m.Methods("PUT").Path("/zone/{id}").Handler(httptransport.NewServer(
+		endpoints.ModifyZoneEndpoint,
+		DecodeModifyZoneRequest,
+		EncodeModifyZoneResponse,
+		scoped...,
+	))
+	m.Methods("GET").Path("/zones").Handler(httptransport.NewServer(
+		endpoints.GetZonesEndpoint,
+		DecodeGetZonesRequest,
+		EncodeGetZonesResponse,
+		scoped...,
+	))
+	m.Methods("DELETE").Path("/zone/{id}").Handler(httptransport.NewServer(
+		endpoints.DeleteZoneEndpoint,
+		DecodeDeleteZoneRequest,
+		EncodeDeleteZoneResponse,
+		scoped...,
+	))
+	m.Methods("GET").Path("/zones/{id}/spots").Handler(httptransport.NewServer(
+		endpoints.GetSpotsInZoneEndpoint,
+		DecodeGetSpotsInZoneRequest,
+		EncodeGetSpotsResponse,
+		scoped...,
 	))
 
 	return c
@@ -140,6 +293,15 @@ func RequestLogFinalizer(logger log.Logger) httptransport.ServerFinalizerFunc {
 	}
 }
 
+// EncodeError is a transport/http.ErrorEncoder that answers an error with its RFC 7807
+// problem details, the status depending on the kind of error.
+func EncodeError(_ context.Context, err error, w http.ResponseWriter) {
+	problem := errors.ProblemFor(err)
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
 // DecodeCreateSpotRequest is a transport/http.DecodeRequestFunc that decodes a
 // JSON-encoded request from the HTTP request body. Primarily useful in a server.
 func DecodeCreateSpotRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
@@ -305,7 +467,214 @@ func EncodeDeleteSpotResponse(_ context.Context, w http.ResponseWriter, response
 	return json.NewEncoder(w).Encode(res.Res)
 }
 
+// DecodeGetSpotsInBoxRequest is a transport/http.DecodeRequestFunc that decodes the
+// rectangle to search from the query parameters. Primarily useful in a server.
+func DecodeGetSpotsInBoxRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	q := r.URL.Query()
+	var rp models.BoxQuery
+	if rp.MinX, err = floatQueryParam(q, "min_x"); err != nil {
+		return nil, err
+	}
+	if rp.MinY, err = floatQueryParam(q, "min_y"); err != nil {
+		return nil, err
+	}
+	if rp.MaxX, err = floatQueryParam(q, "max_x"); err != nil {
+		return nil, err
+	}
+	if rp.MaxY, err = floatQueryParam(q, "max_y"); err != nil {
+		return nil, err
+	}
+	rp.SortBy = q.Get("sort_by")
+
+	return endpoints.GetSpotsInBoxRequest{
+		Req: rp,
+	}, err
+}
+
+// DecodeGetSpotsInRadiusRequest is a transport/http.DecodeRequestFunc that decodes the
+// circle to search from the query parameters. Primarily useful in a server.
+func DecodeGetSpotsInRadiusRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	q := r.URL.Query()
+	var rp models.RadiusQuery
+	if rp.X, err = floatQueryParam(q, "x"); err != nil {
+		return nil, err
+	}
+	if rp.Y, err = floatQueryParam(q, "y"); err != nil {
+		return nil, err
+	}
+	if rp.Radius, err = floatQueryParam(q, "radius"); err != nil {
+		return nil, err
+	}
+	rp.SortBy = q.Get("sort_by")
+
+	return endpoints.GetSpotsInRadiusRequest{
+		Req: rp,
+	}, err
+}
+
+// DecodeGetNearestSpotsRequest is a transport/http.DecodeRequestFunc that decodes the
+// point to search around and the amount of spots, one by default, from the query
+// parameters. Primarily useful in a server.
+func DecodeGetNearestSpotsRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	q := r.URL.Query()
+	var rp models.NearestQuery
+	if rp.X, err = floatQueryParam(q, "x"); err != nil {
+		return nil, err
+	}
+	if rp.Y, err = floatQueryParam(q, "y"); err != nil {
+		return nil, err
+	}
+	rp.K = 1
+	if k := q.Get("k"); k != "" {
+		if rp.K, err = strconv.Atoi(k); err != nil {
+			return nil, errors.ErrMalformedQueryParam
+		}
+	}
+
+	return endpoints.GetNearestSpotsRequest{
+		Req: rp,
+	}, err
+}
+
+// DecodeGetSpotGridRequest is a transport/http.DecodeRequestFunc that decodes the
+// cell size, the optional bounds (all four of them or none) and the format (json,
+// png or svg, json by default) from the query parameters. Primarily useful in a server.
+func DecodeGetSpotGridRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	q := r.URL.Query()
+	var rp models.GridQuery
+	if rp.CellSize, err = floatQueryParam(q, "cell_size"); err != nil {
+		return nil, err
+	}
+	if q.Get("min_x") != "" || q.Get("min_y") != "" || q.Get("max_x") != "" || q.Get("max_y") != "" {
+		var b models.Extent
+		if b.MinX, err = floatQueryParam(q, "min_x"); err != nil {
+			return nil, err
+		}
+		if b.MinY, err = floatQueryParam(q, "min_y"); err != nil {
+			return nil, err
+		}
+		if b.MaxX, err = floatQueryParam(q, "max_x"); err != nil {
+			return nil, err
+		}
+		if b.MaxY, err = floatQueryParam(q, "max_y"); err != nil {
+			return nil, err
+		}
+		rp.Bounds = &b
+	}
+	format := q.Get("format")
+	switch format {
+	case "":
+		format = "json"
+	case "json", "png", "svg":
+	default:
+		return nil, errors.ErrMalformedQueryParam
+	}
+
+	return endpoints.GetSpotGridRequest{
+		Req:    rp,
+		Format: format,
+	}, err
+}
+
+// EncodeGetSpotGridResponse is a transport/http.EncodeResponseFunc that encodes
+// the grid as JSON, or draws it as a PNG or SVG heatmap. Primarily useful in a server.
+func EncodeGetSpotGridResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// cast response to known type
+	res, ok := response.(endpoints.GetSpotGridResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	switch res.Format {
+	case "png":
+		w.Header().Set("Content-Type", "image/png")
+		return render.HeatmapPNG(w, res.Res)
+	case "svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		return render.HeatmapSVG(w, res.Res)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeClusterSpotsRequest is a transport/http.DecodeRequestFunc that decodes a
+// JSON-encoded request from the HTTP request body. Primarily useful in a server.
+func DecodeClusterSpotsRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	var rp models.ClusterQuery
+	if err := json.NewDecoder(r.Body).Decode(&rp); err != nil {
+		if err == io.EOF {
+			return nil, errors.ErrMissingBodyContent
+		} else if err == io.ErrUnexpectedEOF {
+			return nil, errors.ErrMalformedBodyContent
+		} else {
+			return nil, err
+		}
+	}
+	return endpoints.ClusterSpotsRequest{
+		Req: rp,
+	}, err
+}
+
+// EncodeClusterSpotsResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeClusterSpotsResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.ClusterSpotsResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeGetSpotsInClusterRequest is a transport/http.DecodeRequestFunc that decodes the
+// cluster label, -1 standing for noise, from the path. Primarily useful in a server.
+func DecodeGetSpotsInClusterRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	label, err := strconv.Atoi(mux.Vars(r)["label"])
+	if err != nil {
+		return nil, errors.ErrMalformedQueryParam
+	}
+
+	return endpoints.GetSpotsInClusterRequest{
+		Label: label,
+	}, nil
+}
+
+// floatQueryParam reads a mandatory numeric query parameter
+func floatQueryParam(q url.Values, key string) (float64, error) {
+	v := q.Get(key)
+	if v == "" {
+		return 0, errors.ErrMissingQueryParam
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, errors.ErrMalformedQueryParam
+	}
+	return f, nil
+}
 
+// optionalFloatQueryParam reads a numeric query parameter, falling back to def when it's missing
+func optionalFloatQueryParam(q url.Values, key string, def float64) (float64, error) {
+	if q.Get(key) == "" {
+		return def, nil
+	}
+	return floatQueryParam(q, key)
+}
 
 //Path Decoders / Encoders
 
@@ -471,6 +840,556 @@ func EncodeDeleteOriginResponse(_ context.Context, w http.ResponseWriter, respon
 	return json.NewEncoder(w).Encode(res.Res)
 }
 
+// DecodeGetLocalSpotsRequest is a transport/http.DecodeRequestFunc that decodes a
+// JSON-encoded request from the HTTP request body. Primarily useful in a server.
+func DecodeGetLocalSpotsRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	return endpoints.EmptyGetRequest{}, err
+}
+
+// EncodeGetLocalSpotsResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeGetLocalSpotsResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.GetLocalSpotsResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeGetSingleLocalSpotRequest is a transport/http.DecodeRequestFunc that decodes the
+// spot ID from the request path. Primarily useful in a server.
+func DecodeGetSingleLocalSpotRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	pvars := mux.Vars(r)
+
+	id := pvars["id"]
+
+	return endpoints.GetSingleObjectRequest{
+		ObjectID: id,
+	}, err
+}
+
+// EncodeGetSingleLocalSpotResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeGetSingleLocalSpotResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.GetSingleLocalSpotResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeGetSpotsInSectorRequest is a transport/http.DecodeRequestFunc that decodes the
+// sector and ring to search from the query parameters. Every one of them is optional,
+// a missing sector covering the whole circle. Primarily useful in a server.
+func DecodeGetSpotsInSectorRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	q := r.URL.Query()
+	var rp models.PolarQuery
+	if rp.FromAngle, err = optionalFloatQueryParam(q, "from_angle", 0); err != nil {
+		return nil, err
+	}
+	if rp.ToAngle, err = optionalFloatQueryParam(q, "to_angle", rp.FromAngle+360); err != nil {
+		return nil, err
+	}
+	if rp.MinRadius, err = optionalFloatQueryParam(q, "min_radius", 0); err != nil {
+		return nil, err
+	}
+	if rp.MaxRadius, err = optionalFloatQueryParam(q, "max_radius", 0); err != nil {
+		return nil, err
+	}
+
+	return endpoints.GetSpotsInSectorRequest{
+		Req: rp,
+	}, err
+}
+
+// EncodeGetSpotsInSectorResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeGetSpotsInSectorResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.GetPolarSpotsResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeGetQuadrantStatsRequest is a transport/http.DecodeRequestFunc that decodes a
+// JSON-encoded request from the HTTP request body. Primarily useful in a server.
+func DecodeGetQuadrantStatsRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	return endpoints.EmptyGetRequest{}, err
+}
+
+// EncodeGetQuadrantStatsResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeGetQuadrantStatsResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.GetQuadrantStatsResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeGetHullRequest is a transport/http.DecodeRequestFunc that decodes the
+// optional quadrant from the query parameters. Primarily useful in a server.
+func DecodeGetHullRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	return endpoints.GetHullRequest{
+		Quadrant: r.URL.Query().Get("quadrant"),
+	}, err
+}
+
+// EncodeGetHullResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeGetHullResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.GetHullResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeSuggestOriginRequest is a transport/http.DecodeRequestFunc that decodes a
+// JSON-encoded request from the HTTP request body. Primarily useful in a server.
+func DecodeSuggestOriginRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	var rp models.OriginSuggestionQuery
+	if err := json.NewDecoder(r.Body).Decode(&rp); err != nil {
+		if err == io.EOF {
+			return nil, errors.ErrMissingBodyContent
+		} else if err == io.ErrUnexpectedEOF {
+			return nil, errors.ErrMalformedBodyContent
+		} else {
+			return nil, err
+		}
+	}
+	return endpoints.SuggestOriginRequest{
+		Req: rp,
+	}, err
+}
+
+// EncodeSuggestOriginResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeSuggestOriginResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.SuggestOriginResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+//Zone Decoders / Encoders
+
+// DecodeCreateZoneRequest is a transport/http.DecodeRequestFunc that decodes a
+// JSON-encoded request from the HTTP request body. Primarily useful in a server.
+func DecodeCreateZoneRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	var rp models.Zone
+	if err := json.NewDecoder(r.Body).Decode(&rp); err != nil {
+		if err == io.EOF {
+			return nil, errors.ErrMissingBodyContent
+		} else if err == io.ErrUnexpectedEOF {
+			return nil, errors.ErrMalformedBodyContent
+		} else {
+			return nil, err
+		}
+	}
+	return endpoints.CreateZoneRequest{
+		Req: rp,
+	}, err
+}
+
+// EncodeCreateZoneResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeCreateZoneResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.CreateObjectResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeGetSingleZoneRequest is a transport/http.DecodeRequestFunc that decodes the
+// zone ID from the request path. Primarily useful in a server.
+func DecodeGetSingleZoneRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	pvars := mux.Vars(r)
+
+	id := pvars["id"]
+
+	return endpoints.GetSingleObjectRequest{
+		ObjectID: id,
+	}, err
+}
+
+// EncodeGetSingleZoneResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeGetSingleZoneResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.GetSingleZoneResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeGetZonesRequest is a transport/http.DecodeRequestFunc that decodes a
+// JSON-encoded request from the HTTP request body. Primarily useful in a server.
+func DecodeGetZonesRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	return endpoints.EmptyGetRequest{}, err
+}
+
+// EncodeGetZonesResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeGetZonesResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.GetZonesResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeModifyZoneRequest is a transport/http.DecodeRequestFunc that decodes a
+// JSON-encoded request from the HTTP request body. Primarily useful in a server.
+func DecodeModifyZoneRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	pvars := mux.Vars(r)
+
+	id := pvars["id"]
+	var rp models.Zone
+	if err := json.NewDecoder(r.Body).Decode(&rp); err != nil {
+		if err == io.EOF {
+			return nil, errors.ErrMissingBodyContent
+		} else if err == io.ErrUnexpectedEOF {
+			return nil, errors.ErrMalformedBodyContent
+		} else {
+			return nil, err
+		}
+	}
+	return endpoints.ModifyZoneRequest{
+		Req: rp,
+		ID:  id,
+	}, err
+}
+
+// EncodeModifyZoneResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeModifyZoneResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.ModifyObjectResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeDeleteZoneRequest is a transport/http.DecodeRequestFunc that decodes the
+// zone ID from the request path. Primarily useful in a server.
+func DecodeDeleteZoneRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	pvars := mux.Vars(r)
+
+	id := pvars["id"]
+
+	return endpoints.GetSingleObjectRequest{
+		ObjectID: id,
+	}, err
+}
+
+// EncodeDeleteZoneResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeDeleteZoneResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.ModifyObjectResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeGetSpotsInZoneRequest is a transport/http.DecodeRequestFunc that decodes the
+// zone ID from the request path and the optional floor from the query parameters.
+// Primarily useful in a server.
+func DecodeGetSpotsInZoneRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	pvars := mux.Vars(r)
+
+	id := pvars["id"]
+
+	var floor *float64
+	if q := r.URL.Query(); q.Get("floor") != "" {
+		f, err := floatQueryParam(q, "floor")
+		if err != nil {
+			return nil, err
+		}
+		floor = &f
+	}
+
+	return endpoints.GetSpotsInZoneRequest{
+		ObjectID: id,
+		Floor:    floor,
+	}, err
+}
+
+//Maze Decoders / Encoders
+
+// MazeFromPath keeps the maze the request was made to, so the endpoints can scope it
+func MazeFromPath(ctx context.Context, r *http.Request) context.Context {
+	return endpoints.WithMazeID(ctx, mux.Vars(r)["mazeID"])
+}
+
+// DecodeCreateMazeRequest is a transport/http.DecodeRequestFunc that decodes a
+// JSON-encoded request from the HTTP request body. Primarily useful in a server.
+func DecodeCreateMazeRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	var rp models.Maze
+	if err := json.NewDecoder(r.Body).Decode(&rp); err != nil {
+		if err == io.EOF {
+			return nil, errors.ErrMissingBodyContent
+		} else if err == io.ErrUnexpectedEOF {
+			return nil, errors.ErrMalformedBodyContent
+		} else {
+			return nil, err
+		}
+	}
+	return endpoints.CreateMazeRequest{
+		Req: rp,
+	}, err
+}
+
+// EncodeCreateMazeResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeCreateMazeResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.CreateObjectResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeGetMazesRequest is a transport/http.DecodeRequestFunc that decodes a
+// JSON-encoded request from the HTTP request body. Primarily useful in a server.
+func DecodeGetMazesRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	return endpoints.EmptyGetRequest{}, err
+}
+
+// EncodeGetMazesResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeGetMazesResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.GetMazesResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeDeleteMazeRequest is a transport/http.DecodeRequestFunc that decodes a
+// JSON-encoded request from the HTTP request body. Primarily useful in a server.
+func DecodeDeleteMazeRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	pvars := mux.Vars(r)
+
+	id := pvars["mazeID"]
+
+	return endpoints.GetSingleObjectRequest{
+		ObjectID: id,
+	}, err
+}
+
+// EncodeDeleteMazeResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeDeleteMazeResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.ModifyObjectResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeGetMazeRequest is a transport/http.DecodeRequestFunc that decodes a
+// JSON-encoded request from the HTTP request body. Primarily useful in a server.
+func DecodeGetMazeRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	pvars := mux.Vars(r)
+
+	id := pvars["mazeID"]
+
+	return endpoints.GetSingleObjectRequest{
+		ObjectID: id,
+	}, err
+}
+
+// EncodeGetMazeResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeGetMazeResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.GetMazeResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
+
+// DecodeModifyMazeRequest is a transport/http.DecodeRequestFunc that decodes a
+// JSON-encoded request from the HTTP request body. Primarily useful in a server.
+func DecodeModifyMazeRequest(_ context.Context, r *http.Request) (req interface{}, err error) {
+
+	pvars := mux.Vars(r)
+
+	id := pvars["mazeID"]
+	var rp models.Maze
+	if err := json.NewDecoder(r.Body).Decode(&rp); err != nil {
+		if err == io.EOF {
+			return nil, errors.ErrMissingBodyContent
+		} else if err == io.ErrUnexpectedEOF {
+			return nil, errors.ErrMalformedBodyContent
+		} else {
+			return nil, err
+		}
+	}
+	return endpoints.ModifyMazeRequest{
+		Req: rp,
+		ID:  id,
+	}, err
+}
+
+// EncodeModifyMazeResponse is a transport/http.EncodeResponseFunc that encodes
+// the response as JSON to the response writer. Primarily useful in a server.
+func EncodeModifyMazeResponse(_ context.Context, w http.ResponseWriter, response interface{}) (err error) {
+	// set response header
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// cast response to known type
+	res, ok := response.(endpoints.ModifyObjectResponse)
+	if !ok {
+		return errors.ErrResponseEncoding
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	// create json
+	return json.NewEncoder(w).Encode(res.Res)
+}
 
 // Origin Endpoints
 
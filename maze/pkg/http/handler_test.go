@@ -0,0 +1,47 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/avanticaTest/maze/pkg/errors"
+	"gotest.tools/v3/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEncodeError(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		err      error
+		expected errors.Problem
+	}{
+		{
+			name: "Not found",
+			err:  errors.NewNotFound("path", "5fbecff95f80a305742abb10"),
+			expected: errors.Problem{Type: "urn:maze:problem:not-found", Title: "Not Found", Status: http.StatusNotFound,
+				Detail: "No path found with ID 5fbecff95f80a305742abb10", Resource: "path", ID: "5fbecff95f80a305742abb10"},
+		},
+		{
+			name: "Conflict",
+			err:  errors.NewConflict("Built-in zones can't be deleted"),
+			expected: errors.Problem{Type: "urn:maze:problem:conflict", Title: "Conflict", Status: http.StatusConflict,
+				Detail: "Built-in zones can't be deleted"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			EncodeError(context.Background(), tt.err, w)
+
+			assert.Equal(t, tt.expected.Status, w.Code)
+			assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+			var problem errors.Problem
+			assert.NilError(t, json.NewDecoder(w.Body).Decode(&problem))
+			assert.DeepEqual(t, tt.expected, problem)
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package index
+
+import (
+	"container/heap"
+	"github.com/avanticaTest/maze/pkg/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"sort"
+	"sync"
+)
+
+//minRebuild is the amount of changes a small tree tolerates before being rebuilt
+const minRebuild = 64
+
+//SpotIndex keeps the spots in a k-d tree, so spatial queries don't need to read the whole collection. It lives in the
+//process memory, so it has to be told about every spot created, modified or deleted. It's safe for concurrent use.
+//The tree is planar: box, radius and nearest queries ignore the z coordinate, matching the spots of every floor
+type SpotIndex struct {
+	mu      sync.RWMutex
+	root    *node
+	byID    map[primitive.ObjectID]*node
+	built   int
+	changes int
+}
+
+type node struct {
+	spot        models.Spot
+	left, right *node
+	//axis is 0 when the node splits on x and 1 when it splits on y
+	axis    int
+	removed bool
+}
+
+func New() *SpotIndex {
+	return &SpotIndex{byID: make(map[primitive.ObjectID]*node)}
+}
+
+//Load replaces the indexed spots with the given ones
+func (ix *SpotIndex) Load(spots []models.Spot) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.build(spots)
+}
+
+//Len returns the amount of indexed spots
+func (ix *SpotIndex) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return len(ix.byID)
+}
+
+//Upsert adds a spot to the index, replacing the one with the same ID if there's any
+func (ix *SpotIndex) Upsert(spot models.Spot) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if n, ok := ix.byID[spot.ID]; ok {
+		n.removed = true
+	}
+	ix.byID[spot.ID] = ix.insert(spot)
+	ix.changed()
+}
+
+//Remove takes the spot with the given ID out of the index
+func (ix *SpotIndex) Remove(id primitive.ObjectID) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	n, ok := ix.byID[id]
+	if !ok {
+		return
+	}
+	n.removed = true
+	delete(ix.byID, id)
+	ix.changed()
+}
+
+//Get returns the indexed spot with the given ID
+func (ix *SpotIndex) Get(id primitive.ObjectID) (models.Spot, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	n, ok := ix.byID[id]
+	if !ok {
+		return models.Spot{}, false
+	}
+	return n.spot, true
+}
+
+//All returns every indexed spot
+func (ix *SpotIndex) All() []models.Spot {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	result := make([]models.Spot, 0, len(ix.byID))
+	for _, n := range ix.byID {
+		result = append(result, n.spot)
+	}
+	return result
+}
+
+//InBox returns the spots inside the given rectangle, borders included
+func (ix *SpotIndex) InBox(minX, minY, maxX, maxY float64) []models.Spot {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	var result []models.Spot
+	low, high := [2]float64{minX, minY}, [2]float64{maxX, maxY}
+	var visit func(n *node)
+	visit = func(n *node) {
+		if n == nil {
+			return
+		}
+		p := coordinates(n.spot)
+		if !n.removed && p[0] >= minX && p[0] <= maxX && p[1] >= minY && p[1] <= maxY {
+			result = append(result, n.spot)
+		}
+		if low[n.axis] < p[n.axis] {
+			visit(n.left)
+		}
+		if high[n.axis] >= p[n.axis] {
+			visit(n.right)
+		}
+	}
+	visit(ix.root)
+	return result
+}
+
+//InRadius returns the spots inside the given circle, border included
+func (ix *SpotIndex) InRadius(x, y, radius float64) []models.Spot {
+	var result []models.Spot
+	for _, v := range ix.InBox(x-radius, y-radius, x+radius, y+radius) {
+		if math.Hypot(v.XCoordinate-x, v.YCoordinate-y) <= radius {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+//Nearest returns the k spots closest to the given point, the closest first
+func (ix *SpotIndex) Nearest(x, y float64, k int) []models.Spot {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	if k <= 0 {
+		return nil
+	}
+	target := [2]float64{x, y}
+	best := &candidates{}
+	var visit func(n *node)
+	visit = func(n *node) {
+		if n == nil {
+			return
+		}
+		p := coordinates(n.spot)
+		if !n.removed {
+			d := math.Hypot(p[0]-x, p[1]-y)
+			if best.Len() < k {
+				heap.Push(best, candidate{spot: n.spot, distance: d})
+			} else if d < (*best)[0].distance {
+				(*best)[0] = candidate{spot: n.spot, distance: d}
+				heap.Fix(best, 0)
+			}
+		}
+		near, far := n.left, n.right
+		if target[n.axis] >= p[n.axis] {
+			near, far = n.right, n.left
+		}
+		visit(near)
+		//the far side can only hold closer spots when the splitting line is closer than the worst candidate
+		if best.Len() < k || math.Abs(target[n.axis]-p[n.axis]) <= (*best)[0].distance {
+			visit(far)
+		}
+	}
+	visit(ix.root)
+
+	result := make([]models.Spot, best.Len())
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(best).(candidate).spot
+	}
+	return result
+}
+
+//insert adds a leaf to the tree. Equal coordinates go right, as they do when building it
+func (ix *SpotIndex) insert(spot models.Spot) *node {
+	p := coordinates(spot)
+	link := &ix.root
+	axis := 0
+	for *link != nil {
+		n := *link
+		if p[n.axis] < coordinates(n.spot)[n.axis] {
+			link = &n.left
+		} else {
+			link = &n.right
+		}
+		axis = 1 - n.axis
+	}
+	*link = &node{spot: spot, axis: axis}
+	return *link
+}
+
+//changed rebuilds the tree once it has changed as much as its size, which keeps it balanced and frees the removed
+//nodes at an amortized logarithmic cost
+func (ix *SpotIndex) changed() {
+	ix.changes++
+	if ix.changes > ix.built && ix.changes > minRebuild {
+		spots := make([]models.Spot, 0, len(ix.byID))
+		for _, n := range ix.byID {
+			spots = append(spots, n.spot)
+		}
+		ix.build(spots)
+	}
+}
+
+//build replaces the tree by a balanced one holding the given spots
+func (ix *SpotIndex) build(spots []models.Spot) {
+	ix.byID = make(map[primitive.ObjectID]*node, len(spots))
+	ix.root = ix.split(append([]models.Spot(nil), spots...), 0)
+	ix.built = len(spots)
+	ix.changes = 0
+}
+
+//split builds the subtree holding the spots, splitting them on their median along the axis
+func (ix *SpotIndex) split(spots []models.Spot, axis int) *node {
+	if len(spots) == 0 {
+		return nil
+	}
+	sort.Slice(spots, func(i, j int) bool {
+		return coordinates(spots[i])[axis] < coordinates(spots[j])[axis]
+	})
+	m := len(spots) / 2
+	//equal coordinates must end up on the right
+	for m > 0 && coordinates(spots[m-1])[axis] == coordinates(spots[m])[axis] {
+		m--
+	}
+	n := &node{spot: spots[m], axis: axis}
+	ix.byID[spots[m].ID] = n
+	n.left = ix.split(spots[:m], 1-axis)
+	n.right = ix.split(spots[m+1:], 1-axis)
+	return n
+}
+
+func coordinates(spot models.Spot) [2]float64 {
+	return [2]float64{spot.XCoordinate, spot.YCoordinate}
+}
+
+type candidate struct {
+	spot     models.Spot
+	distance float64
+}
+
+//candidates is a max-heap on the distance, so the worst candidate is always at hand
+type candidates []candidate
+
+func (c candidates) Len() int            { return len(c) }
+func (c candidates) Less(i, j int) bool  { return c[i].distance > c[j].distance }
+func (c candidates) Swap(i, j int)       { c[i], c[j] = c[j], c[i] }
+func (c *candidates) Push(x interface{}) { *c = append(*c, x.(candidate)) }
+func (c *candidates) Pop() interface{} {
+	old := *c
+	last := old[len(old)-1]
+	*c = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,171 @@
+package index
+
+import (
+	"github.com/avanticaTest/maze/pkg/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gotest.tools/v3/assert"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func randomSpots(n int, r *rand.Rand) []models.Spot {
+	spots := make([]models.Spot, n)
+	for i := range spots {
+		spots[i] = models.Spot{
+			ID:          primitive.NewObjectID(),
+			XCoordinate: math.Round(r.Float64()*2000 - 1000),
+			YCoordinate: math.Round(r.Float64()*2000 - 1000),
+		}
+	}
+	return spots
+}
+
+//scanBox is the loop-over-everything approach the index replaces
+func scanBox(spots []models.Spot, minX, minY, maxX, maxY float64) []models.Spot {
+	var result []models.Spot
+	for _, v := range spots {
+		if v.XCoordinate >= minX && v.XCoordinate <= maxX && v.YCoordinate >= minY && v.YCoordinate <= maxY {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func ids(spots []models.Spot) []string {
+	result := make([]string, len(spots))
+	for i, v := range spots {
+		result[i] = v.ID.Hex()
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestSpotIndex(t *testing.T) {
+
+	r := rand.New(rand.NewSource(1))
+	spots := randomSpots(2000, r)
+	ix := New()
+	ix.Load(spots[:1000])
+
+	//keep the index in sync through inserts, moves and deletes, enough of them to trigger rebuilds
+	for _, v := range spots[1000:] {
+		ix.Upsert(v)
+	}
+	for i := 0; i < 500; i++ {
+		spots[i].XCoordinate, spots[i].YCoordinate = spots[i].YCoordinate, -spots[i].XCoordinate
+		ix.Upsert(spots[i])
+	}
+	for _, v := range spots[1500:] {
+		ix.Remove(v.ID)
+	}
+	live := spots[:1500]
+	assert.Equal(t, 1500, ix.Len())
+
+	t.Run("InBox", func(t *testing.T) {
+		for i := 0; i < 50; i++ {
+			x, y := r.Float64()*2000-1000, r.Float64()*2000-1000
+			expected := scanBox(live, x, y, x+300, y+200)
+			assert.DeepEqual(t, ids(expected), ids(ix.InBox(x, y, x+300, y+200)))
+		}
+	})
+
+	t.Run("Nearest", func(t *testing.T) {
+		for i := 0; i < 50; i++ {
+			x, y := r.Float64()*2000-1000, r.Float64()*2000-1000
+			sorted := append([]models.Spot(nil), live...)
+			sort.SliceStable(sorted, func(i, j int) bool {
+				return math.Hypot(sorted[i].XCoordinate-x, sorted[i].YCoordinate-y) <
+					math.Hypot(sorted[j].XCoordinate-x, sorted[j].YCoordinate-y)
+			})
+			nearest := ix.Nearest(x, y, 5)
+			assert.Equal(t, 5, len(nearest))
+			for j := range nearest {
+				assert.Equal(t, math.Hypot(sorted[j].XCoordinate-x, sorted[j].YCoordinate-y),
+					math.Hypot(nearest[j].XCoordinate-x, nearest[j].YCoordinate-y))
+			}
+		}
+	})
+}
+
+func TestMazes(t *testing.T) {
+
+	first, second := primitive.NewObjectID(), primitive.NewObjectID()
+	spots := randomSpots(10, rand.New(rand.NewSource(1)))
+	for i := range spots {
+		spots[i].MazeID = first
+		if i%2 == 1 {
+			spots[i].MazeID = second
+		}
+	}
+	m := NewMazes()
+	m.Load(spots)
+
+	assert.Equal(t, 10, m.Len())
+	for _, v := range m.Of(second).InBox(-1000, -1000, 1000, 1000) {
+		assert.Equal(t, second, v.MazeID)
+	}
+	assert.Equal(t, 5, m.Of(first).Len())
+
+	m.Drop(first)
+	assert.Equal(t, 0, m.Of(first).Len())
+	assert.Equal(t, 5, m.Len())
+}
+
+const benchSpots = 200000
+
+func BenchmarkInBox(b *testing.B) {
+
+	spots := randomSpots(benchSpots, rand.New(rand.NewSource(1)))
+	ix := New()
+	ix.Load(spots)
+
+	b.Run("Index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ix.InBox(-50, -50, 50, 50)
+		}
+	})
+	b.Run("Scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			scanBox(spots, -50, -50, 50, 50)
+		}
+	})
+}
+
+func BenchmarkNearest(b *testing.B) {
+
+	spots := randomSpots(benchSpots, rand.New(rand.NewSource(1)))
+	ix := New()
+	ix.Load(spots)
+
+	b.Run("Index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ix.Nearest(12.5, -40.5, 10)
+		}
+	})
+	b.Run("Scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sorted := append([]models.Spot(nil), spots...)
+			sort.Slice(sorted, func(i, j int) bool {
+				return math.Hypot(sorted[i].XCoordinate-12.5, sorted[i].YCoordinate+40.5) <
+					math.Hypot(sorted[j].XCoordinate-12.5, sorted[j].YCoordinate+40.5)
+			})
+		}
+	})
+}
+
+func BenchmarkUpsert(b *testing.B) {
+
+	r := rand.New(rand.NewSource(1))
+	spots := randomSpots(benchSpots, r)
+	ix := New()
+	ix.Load(spots)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v := spots[i%len(spots)]
+		v.XCoordinate = r.Float64()*2000 - 1000
+		ix.Upsert(v)
+	}
+}
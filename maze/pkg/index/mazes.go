@@ -0,0 +1,69 @@
+package index
+
+import (
+	"github.com/avanticaTest/maze/pkg/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+)
+
+//Mazes keeps a SpotIndex for every maze, so the spatial queries of a maze never see the spots of another one. It's
+//safe for concurrent use
+type Mazes struct {
+	mu     sync.Mutex
+	byMaze map[primitive.ObjectID]*SpotIndex
+}
+
+func NewMazes() *Mazes {
+	return &Mazes{byMaze: make(map[primitive.ObjectID]*SpotIndex)}
+}
+
+//Load replaces the indexed spots with the given ones, each going to the index of its maze
+func (m *Mazes) Load(spots []models.Spot) {
+	grouped := make(map[primitive.ObjectID][]models.Spot)
+	for _, v := range spots {
+		grouped[v.MazeID] = append(grouped[v.MazeID], v)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.byMaze = make(map[primitive.ObjectID]*SpotIndex, len(grouped))
+	for maze, v := range grouped {
+		ix := New()
+		ix.Load(v)
+		m.byMaze[maze] = ix
+	}
+}
+
+//Len returns the amount of indexed spots, whatever their maze
+func (m *Mazes) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, ix := range m.byMaze {
+		count += ix.Len()
+	}
+	return count
+}
+
+//Of returns the index of the maze, an empty one when none of its spots has been indexed yet
+func (m *Mazes) Of(maze primitive.ObjectID) *SpotIndex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ix, ok := m.byMaze[maze]
+	if !ok {
+		ix = New()
+		m.byMaze[maze] = ix
+	}
+	return ix
+}
+
+//Drop forgets the index of a deleted maze
+func (m *Mazes) Drop(maze primitive.ObjectID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.byMaze, maze)
+}
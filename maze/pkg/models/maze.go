@@ -2,12 +2,19 @@ package models
 
 import "go.mongodb.org/mongo-driver/bson/primitive"
 
+//Spot is a point of the maze. ZCoordinate is its floor, spots without one being on the ground floor (zero)
 type Spot struct {
 	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
 	XCoordinate float64            `json:"x_coordinate,omitempty" bson:"x_coordinate,omitempty"`
 	YCoordinate float64            `json:"y_coordinate,omitempty" bson:"y_coordinate,omitempty"`
+	ZCoordinate float64            `json:"z_coordinate,omitempty" bson:"z_coordinate,omitempty"`
 	Name        string             `json:"name,omitempty" bson:"name,omitempty"`
 	Number      int                `json:"number,omitempty" bson:"number,omitempty"`
+	Zones       []string           `json:"zones,omitempty" bson:"-"`
+	Cluster     *int               `json:"cluster,omitempty" bson:"cluster,omitempty"`
+	MazeID      primitive.ObjectID `json:"maze_id,omitempty" bson:"maze_id,omitempty"`
+	//Revision is bumped whenever a path is written to the spot, so the write collides with the spot's deletion
+	Revision int `json:"-" bson:"revision,omitempty"`
 }
 
 type Path struct {
@@ -15,15 +22,92 @@ type Path struct {
 	PointA   primitive.ObjectID `json:"point_a,omitempty" bson:"point_a,omitempty"`
 	PointB   primitive.ObjectID `json:"point_b,omitempty" bson:"point_b,omitempty"`
 	Distance float64            `json:"distance,omitempty" bson:"distance,omitempty"`
+	MazeID   primitive.ObjectID `json:"maze_id,omitempty" bson:"maze_id,omitempty"`
+	//Ends is the pair of spots the path joins whichever way it's walked, kept by the repositories so no two paths of a
+	//maze join the same spots
+	Ends string `json:"-" bson:"ends,omitempty"`
 }
 
+//What happens to the paths of a deleted spot: the deletion is refused while there are any, they're deleted along with
+//the spot, or they lose the end at the spot, along with their distance
+const (
+	RestrictDeletion = "restrict"
+	CascadeDeletion  = "cascade"
+	DetachDeletion   = "detach"
+)
+
+const (
+	Cartesian  = "cartesian"
+	Geographic = "geographic"
+)
+
+const (
+	Euclidean = "euclidean"
+	Manhattan = "manhattan"
+	Chebyshev = "chebyshev"
+)
+
+//Maze holds its spots, paths, origin and zones, along with the settings shared by every spot. In a geographic maze the
+//spots' x and y coordinates are their longitude and latitude, in degrees, and distances are measured in meters along
+//the earth's surface. Cartesian mazes measure distances with their metric, Manhattan and Chebyshev suiting grid mazes
+type Maze struct {
+	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name        string             `json:"name,omitempty" bson:"name,omitempty"`
+	Description string             `json:"description,omitempty" bson:"description,omitempty"`
+	Coordinates string             `json:"coordinates" bson:"coordinates,omitempty"`
+	Metric      string             `json:"metric" bson:"metric,omitempty"`
+}
+
+//Origin is the reference frame of the maze. Its axes may be rotated counterclockwise by Rotation degrees and scaled,
+//a missing scale meaning no scaling at all
 type Origin struct {
-	XOrigin float64 `json:"x_origin,omitempty" bson:"x_origin,omitempty"`
-	YOrigin float64 `json:"y_origin,omitempty" bson:"y_origin,omitempty"`
+	XOrigin  float64 `json:"x_origin,omitempty" bson:"x_origin,omitempty"`
+	YOrigin  float64 `json:"y_origin,omitempty" bson:"y_origin,omitempty"`
+	Rotation float64 `json:"rotation,omitempty" bson:"rotation,omitempty"`
+	XScale   float64 `json:"x_scale,omitempty" bson:"x_scale,omitempty"`
+	YScale   float64 `json:"y_scale,omitempty" bson:"y_scale,omitempty"`
+	MazeID   primitive.ObjectID `json:"maze_id,omitempty" bson:"maze_id,omitempty"`
+	//Geographic is taken from the maze's settings when the origin is loaded
+	Geographic bool `json:"geographic,omitempty" bson:"-"`
+}
+
+//LocalSpot is a spot along with its coordinates in the origin's frame
+type LocalSpot struct {
+	Spot
+	LocalX float64 `json:"local_x"`
+	LocalY float64 `json:"local_y"`
+}
+
+//OriginSuggestionQuery asks for an origin splitting the spots evenly. The strategy is median (the default), the
+//coordinate-wise median of the spots, or balanced, the point minimizing the spread between the heaviest and lightest
+//quadrant. Weight is what the quadrants weigh: count (the default) or number, the sum of the spots' numbers. When Apply
+//is set the suggestion replaces the origin
+type OriginSuggestionQuery struct {
+	Strategy string `json:"strategy"`
+	Weight   string `json:"weight"`
+	Apply    bool   `json:"apply"`
+}
+
+//OriginSuggestion is a suggested origin, keeping the current rotation and scales, along with the weight of each
+//quadrant around it and the spread between the heaviest and lightest of them
+type OriginSuggestion struct {
+	Origin    Origin             `json:"origin"`
+	Quadrants map[string]float64 `json:"quadrants"`
+	Imbalance float64            `json:"imbalance"`
+	Applied   bool               `json:"applied"`
 }
 
+//Quadrant selects one sector of a partition of the plane around the origin. The scheme is quadrants (the default),
+//octants or Sectors equal sectors, numbered counterclockwise from the origin's x axis. Quadrants may be given by name
+//instead. Boundary decides where spots lying exactly between two sectors go: shared (the default, in both),
+//counterclockwise or clockwise. When Floor is given, only the spots on that floor are looked up
 type Quadrant struct {
-	Quadrant string `json:"name"`
+	Quadrant string   `json:"name"`
+	Floor    *float64 `json:"floor,omitempty"`
+	Scheme   string   `json:"scheme,omitempty"`
+	Sectors  int      `json:"sectors,omitempty"`
+	Sector   *int     `json:"sector,omitempty"`
+	Boundary string   `json:"boundary,omitempty"`
 }
 
 type CreatePathRequest struct{
@@ -38,3 +122,142 @@ type CreateObjectResponse struct {
 type ModifyObjectResponse struct {
 	AffectedItems int `json:"affected_items"`
 }
+
+type BoxQuery struct {
+	MinX   float64 `json:"min_x"`
+	MinY   float64 `json:"min_y"`
+	MaxX   float64 `json:"max_x"`
+	MaxY   float64 `json:"max_y"`
+	SortBy string  `json:"sort_by"`
+}
+
+type RadiusQuery struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Radius float64 `json:"radius"`
+	SortBy string  `json:"sort_by"`
+}
+
+type NearestQuery struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	K int     `json:"k"`
+}
+
+const (
+	SortByDistance = "distance"
+	SortByName     = "name"
+)
+
+//PolarQuery selects the spots inside a sector of a ring around the origin. The sector goes counterclockwise from
+//FromAngle to ToAngle, in degrees, and a zero MaxRadius means the ring is unbounded
+type PolarQuery struct {
+	FromAngle float64 `json:"from_angle"`
+	ToAngle   float64 `json:"to_angle"`
+	MinRadius float64 `json:"min_radius"`
+	MaxRadius float64 `json:"max_radius"`
+}
+
+//PolarSpot is a spot along with its polar coordinates around the origin
+type PolarSpot struct {
+	Spot
+	Angle  float64 `json:"angle"`
+	Radius float64 `json:"radius"`
+}
+
+const (
+	KMeans = "kmeans"
+	DBSCAN = "dbscan"
+)
+
+//ClusterQuery groups the spots with k-means, given K, or DBSCAN, given Eps and MinPoints. When Persist is set, every
+//spot keeps its cluster label (-1 for noise) so it can be filtered by it later
+type ClusterQuery struct {
+	Algorithm string  `json:"algorithm"`
+	K         int     `json:"k"`
+	Eps       float64 `json:"eps"`
+	MinPoints int     `json:"min_points"`
+	Persist   bool    `json:"persist"`
+}
+
+type Clustering struct {
+	Clusters []Cluster            `json:"clusters"`
+	Noise    []primitive.ObjectID `json:"noise"`
+	Labels   map[string]int       `json:"labels"`
+}
+
+type Cluster struct {
+	Label    int                  `json:"label"`
+	Centroid Point                `json:"centroid"`
+	Spots    []primitive.ObjectID `json:"spots"`
+}
+
+//Extent is an axis-aligned rectangle
+type Extent struct {
+	MinX float64 `json:"min_x"`
+	MinY float64 `json:"min_y"`
+	MaxX float64 `json:"max_x"`
+	MaxY float64 `json:"max_y"`
+}
+
+//GridQuery bins the spots into square cells. Without bounds the cells are aligned on multiples of the cell size and
+//the grid covers every spot
+type GridQuery struct {
+	CellSize float64 `json:"cell_size"`
+	Bounds   *Extent `json:"bounds,omitempty"`
+}
+
+//Grid holds the spot count and number sum of its non-empty cells. Cells are numbered from the grid's lower left corner
+type Grid struct {
+	CellSize float64    `json:"cell_size"`
+	Bounds   Extent     `json:"bounds"`
+	Columns  int        `json:"columns"`
+	Rows     int        `json:"rows"`
+	Cells    []GridCell `json:"cells"`
+}
+
+type GridCell struct {
+	Column    int     `json:"column"`
+	Row       int     `json:"row"`
+	MinX      float64 `json:"min_x"`
+	MinY      float64 `json:"min_y"`
+	Count     int     `json:"count"`
+	NumberSum int     `json:"number_sum"`
+}
+
+//SpotStats summarizes a group of spots. Centroid and bounding box are missing when the group is empty
+type SpotStats struct {
+	Count       int     `json:"count"`
+	NumberSum   int     `json:"number_sum"`
+	NumberAvg   float64 `json:"number_avg"`
+	NumberMin   int     `json:"number_min"`
+	NumberMax   int     `json:"number_max"`
+	Centroid    *Point  `json:"centroid,omitempty"`
+	BoundingBox *Extent `json:"bounding_box,omitempty"`
+}
+
+//Hull describes the shape covered by a group of spots: their convex hull, counterclockwise, and the axis-aligned
+//extent. The extent is missing when the group is empty
+type Hull struct {
+	Count     int     `json:"count"`
+	Polygon   []Point `json:"polygon"`
+	Area      float64 `json:"area"`
+	Perimeter float64 `json:"perimeter"`
+	Extent    *Extent `json:"extent,omitempty"`
+}
+
+type Point struct {
+	X float64 `json:"x" bson:"x"`
+	Y float64 `json:"y" bson:"y"`
+}
+
+//Zone is a named polygon. Origin relative zones have their vertices expressed in the origin's frame, so they follow it
+//when it moves. The quadrants are the built-in zones, they can't be modified nor deleted
+type Zone struct {
+	ID             primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name           string             `json:"name,omitempty" bson:"name,omitempty"`
+	Polygon        []Point            `json:"polygon,omitempty" bson:"polygon,omitempty"`
+	OriginRelative bool               `json:"origin_relative,omitempty" bson:"origin_relative,omitempty"`
+	BuiltIn        bool               `json:"built_in,omitempty" bson:"-"`
+	MazeID         primitive.ObjectID `json:"maze_id,omitempty" bson:"maze_id,omitempty"`
+}
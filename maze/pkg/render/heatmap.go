@@ -0,0 +1,93 @@
+package render
+
+import (
+	"fmt"
+	"github.com/avanticaTest/maze/pkg/models"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+//HeatmapSide is the length, in pixels, the longest side of a heatmap aims for
+const HeatmapSide = 512
+
+//HeatmapPNG draws the grid's spot counts as a PNG image, north up. Empty cells are white and the fullest cell is red
+func HeatmapPNG(w io.Writer, grid models.Grid) error {
+	scale := cellPixels(grid)
+	//an empty grid still makes a valid, blank, image
+	width, height := grid.Columns*scale, grid.Rows*scale
+	if width == 0 || height == 0 {
+		width, height = 1, 1
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	max := maxCount(grid)
+	for _, c := range grid.Cells {
+		shade := heat(c.Count, max)
+		//image rows grow downwards while grid rows grow upwards
+		top := (grid.Rows - 1 - c.Row) * scale
+		for y := top; y < top+scale; y++ {
+			for x := c.Column * scale; x < (c.Column+1)*scale; x++ {
+				img.SetRGBA(x, y, shade)
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+//HeatmapSVG draws the grid's spot counts as an SVG image, north up. Every non-empty cell gets a tooltip with its count
+//and number sum
+func HeatmapSVG(w io.Writer, grid models.Grid) error {
+	scale := cellPixels(grid)
+	width, height := grid.Columns*scale, grid.Rows*scale
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n"+
+		`<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", width, height, width, height, width, height)
+	if err != nil {
+		return err
+	}
+	max := maxCount(grid)
+	for _, c := range grid.Cells {
+		shade := heat(c.Count, max)
+		_, err = fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d" fill="#%02x%02x%02x"><title>%d spots, number sum %d</title></rect>`+"\n",
+			c.Column*scale, (grid.Rows-1-c.Row)*scale, scale, scale, shade.R, shade.G, shade.B, c.Count, c.NumberSum)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "</svg>\n")
+	return err
+}
+
+//cellPixels returns the side of a cell in pixels
+func cellPixels(grid models.Grid) int {
+	longest := grid.Columns
+	if grid.Rows > longest {
+		longest = grid.Rows
+	}
+	if longest == 0 || longest >= HeatmapSide {
+		return 1
+	}
+	return HeatmapSide / longest
+}
+
+func maxCount(grid models.Grid) int {
+	max := 0
+	for _, c := range grid.Cells {
+		if c.Count > max {
+			max = c.Count
+		}
+	}
+	return max
+}
+
+//heat fades from a pale yellow for the emptiest cells to red for the fullest one
+func heat(count, max int) color.RGBA {
+	if max == 0 {
+		return color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	}
+	t := float64(count) / float64(max)
+	return color.RGBA{R: 0xff, G: uint8(0xf0 * (1 - t)), B: uint8(0xa0 * (1 - t)), A: 0xff}
+}
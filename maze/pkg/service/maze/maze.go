@@ -0,0 +1,316 @@
+package maze
+
+import (
+	"context"
+	"github.com/avanticaTest/maze/pkg/db"
+	mazeerrors "github.com/avanticaTest/maze/pkg/errors"
+	"github.com/avanticaTest/maze/pkg/geometry"
+	"github.com/avanticaTest/maze/pkg/index"
+	"github.com/avanticaTest/maze/pkg/models"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MazeHandler interface {
+	CreateMaze(ctx context.Context, request models.Maze) (string, error)
+	GetMazes(ctx context.Context) ([]models.Maze, error)
+	GetMaze(ctx context.Context, id string) (models.Maze, error)
+	ModifyMaze(ctx context.Context, request models.Maze, id string) (int, error)
+	DeleteMaze(ctx context.Context, id string) (int, error)
+}
+
+type stubMazeHandler struct {
+	mazes   db.MazeRepository
+	origins db.OriginRepository
+	spots   db.SpotRepository
+	paths   db.PathRepository
+	zones   db.ZoneRepository
+	index   *index.Mazes
+	tx      db.Transactor
+	logger  log.Logger
+}
+
+//New creates the maze service, which keeps the mazes along with the settings shared by the other services. Deleting
+//a maze deletes everything stored in it. The index is optional: when given, a deleted maze's index is dropped
+func New(logger log.Logger, mazes db.MazeRepository, origins db.OriginRepository, spots db.SpotRepository, paths db.PathRepository, zones db.ZoneRepository, index *index.Mazes, tx db.Transactor) MazeHandler {
+	if tx == nil {
+		tx = db.NoTransactions()
+	}
+	return stubMazeHandler{
+		mazes:   mazes,
+		origins: origins,
+		spots:   spots,
+		paths:   paths,
+		zones:   zones,
+		index:   index,
+		tx:      tx,
+		logger:  logger,
+	}
+}
+
+//Get returns the settings of the maze the context is scoped to through the given service. Without a service, or
+//without a maze, the maze has the default settings
+func Get(ctx context.Context, mazes MazeHandler) (models.Maze, error) {
+	id, ok := db.MazeFrom(ctx)
+	if mazes == nil || !ok {
+		return defaults(models.Maze{}), nil
+	}
+	return mazes.GetMaze(ctx, id.Hex())
+}
+
+//DefaultName is the name of the maze adopting what was stored before there were mazes
+const DefaultName = "default"
+
+//Adopt moves what was stored before there were mazes into a maze of its own, created with the settings of the single
+//maze of back then. It returns the ID of that maze, empty when there was nothing to adopt, so it's meant to run at
+//every start up
+func Adopt(ctx context.Context, legacy db.LegacyRepository, mazes db.MazeRepository, tx db.Transactor) (string, error) {
+	if tx == nil {
+		tx = db.NoTransactions()
+	}
+	var result string
+	err := tx.Transaction(ctx, func(ctx context.Context) error {
+		settings, found, err := legacy.Settings(ctx)
+		if err != nil {
+			return err
+		}
+		unscoped, err := legacy.Unscoped(ctx)
+		if err != nil {
+			return err
+		}
+		if !found && unscoped == 0 {
+			return nil
+		}
+
+		settings.ID = primitive.NilObjectID
+		settings.Name = DefaultName
+		settings.Description = "What was stored before there were mazes"
+		if result, err = mazes.Create(ctx, defaults(settings)); err != nil {
+			return err
+		}
+		id, err := primitive.ObjectIDFromHex(result)
+		if err != nil {
+			return err
+		}
+		return legacy.Adopt(ctx, id)
+	})
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+//CreateMaze creates a maze given its name, description and settings. It returns the ID of the Maze created
+func (s stubMazeHandler) CreateMaze(ctx context.Context, request models.Maze) (string, error) {
+
+	if request.Name == "" {
+		return "", mazeerrors.NewValidation("Invalid name: the maze must have one")
+	}
+	request = defaults(request)
+	if err := validate(request); err != nil {
+		return "", err
+	}
+
+	request.ID = primitive.NilObjectID
+	result, err := s.mazes.Create(ctx, request)
+	if err != nil {
+		level.Error(s.logger).Log("method", "CreateMaze", "error", err)
+		return "", err
+	}
+	return result, nil
+}
+
+//GetMazes returns every maze
+func (s stubMazeHandler) GetMazes(ctx context.Context) ([]models.Maze, error) {
+
+	result, err := s.mazes.List(ctx)
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetMazes", "error", err)
+		return nil, err
+	}
+	for i := range result {
+		result[i] = defaults(result[i])
+	}
+	return result, nil
+}
+
+//GetMaze returns the maze with the given ID, along with its settings
+func (s stubMazeHandler) GetMaze(ctx context.Context, id string) (models.Maze, error) {
+
+	idm, err := mazeerrors.ParseID("mazeID", id)
+	if err != nil {
+		return models.Maze{}, err
+	}
+	result, err := s.mazes.GetByID(ctx, idm)
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetMaze", "error", err)
+		return models.Maze{}, mazeerrors.Missing(err, "maze", id)
+	}
+	return defaults(result), nil
+}
+
+//ModifyMaze changes the maze's name, description and settings. A maze can only become geographic when its spots and
+//origin are valid longitudes and latitudes, and its origin is neither rotated nor scaled. Geographic mazes are always
+//euclidean
+func (s stubMazeHandler) ModifyMaze(ctx context.Context, request models.Maze, id string) (int, error) {
+
+	current, err := s.GetMaze(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if request.Name == "" {
+		request.Name = current.Name
+	}
+	request = defaults(request)
+	if err := validate(request); err != nil {
+		return 0, err
+	}
+	var result int
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if request.Coordinates == models.Geographic {
+			if err := s.checkGeographic(db.WithMaze(ctx, current.ID)); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		result, err = s.mazes.Update(ctx, current.ID, request)
+		if err != nil {
+			level.Error(s.logger).Log("method", "ModifyMaze", "error", err)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result, nil
+}
+
+//DeleteMaze deletes the maze along with its spots, paths, origin and zones. The maze itself goes last, so a deletion
+//that fails halfway without a transaction can be retried
+func (s stubMazeHandler) DeleteMaze(ctx context.Context, id string) (int, error) {
+
+	current, err := s.GetMaze(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	var result int
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		scoped := db.WithMaze(ctx, current.ID)
+		if _, err := s.paths.DeleteMany(scoped, bson.M{}); err != nil {
+			level.Error(s.logger).Log("method", "DeleteMaze", "error", err)
+			return err
+		}
+		if _, err := s.spots.DeleteMany(scoped, bson.M{}); err != nil {
+			level.Error(s.logger).Log("method", "DeleteMaze", "error", err)
+			return err
+		}
+		if _, err := s.origins.Delete(scoped); err != nil {
+			level.Error(s.logger).Log("method", "DeleteMaze", "error", err)
+			return err
+		}
+		if _, err := s.zones.DeleteMany(scoped, bson.M{}); err != nil {
+			level.Error(s.logger).Log("method", "DeleteMaze", "error", err)
+			return err
+		}
+
+		var err error
+		result, err = s.mazes.Delete(ctx, current.ID)
+		if err != nil {
+			level.Error(s.logger).Log("method", "DeleteMaze", "error", err)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if s.index != nil {
+		s.index.Drop(current.ID)
+	}
+	return result, nil
+}
+
+//validate checks the maze's settings, once the defaults are filled
+func validate(maze models.Maze) error {
+	switch maze.Metric {
+	case models.Euclidean, models.Manhattan, models.Chebyshev:
+	default:
+		return mazeerrors.NewValidation("Invalid metric: it must be either euclidean, manhattan or chebyshev")
+	}
+	if maze.Coordinates == models.Geographic && maze.Metric != models.Euclidean {
+		return mazeerrors.NewValidation("Invalid metric: geographic mazes measure great-circle distances")
+	}
+	switch maze.Coordinates {
+	case models.Cartesian, models.Geographic:
+	default:
+		return mazeerrors.NewValidation("Invalid coordinates: they must be either cartesian or geographic")
+	}
+	return nil
+}
+
+//checkGeographic makes sure the stored spots and origin fit a geographic maze
+func (s stubMazeHandler) checkGeographic(ctx context.Context) error {
+
+	origins, err := s.origins.List(ctx)
+	if err != nil {
+		level.Error(s.logger).Log("method", "ModifyMaze", "error", err)
+		return err
+	}
+	for _, o := range origins {
+		if err := ValidateOrigin(models.Maze{Coordinates: models.Geographic}, o); err != nil {
+			return err
+		}
+	}
+
+	errOutOfRange := mazeerrors.NewConflict("There are spots out of the longitude and latitude ranges")
+	outOfRange := bson.M{"$or": bson.A{
+		bson.M{"x_coordinate": bson.M{"$lte": -180.0}},
+		bson.M{"x_coordinate": bson.M{"$gt": 180.0}},
+		bson.M{"y_coordinate": bson.M{"$lt": -90.0}},
+		bson.M{"y_coordinate": bson.M{"$gt": 90.0}},
+	}}
+	//a single spot is enough to refuse
+	err = s.spots.Stream(ctx, outOfRange, func(models.Spot) error {
+		return errOutOfRange
+	})
+	if err != nil && err != errOutOfRange {
+		level.Error(s.logger).Log("method", "ModifyMaze", "error", err)
+	}
+	return err
+}
+
+//ValidateSpot checks that the spot's coordinates make sense in the maze
+func ValidateSpot(maze models.Maze, spot models.Spot) error {
+	if maze.Coordinates == models.Geographic && !geometry.ValidLatLon(spot.XCoordinate, spot.YCoordinate) {
+		return mazeerrors.NewValidation("Invalid coordinates: the longitude must be in (-180, 180] and the latitude in [-90, 90]")
+	}
+	return nil
+}
+
+//ValidateOrigin checks that the origin makes sense in the maze. Geographic origins can't be rotated nor scaled
+func ValidateOrigin(maze models.Maze, origin models.Origin) error {
+	if maze.Coordinates != models.Geographic {
+		return nil
+	}
+	if !geometry.ValidLatLon(origin.XOrigin, origin.YOrigin) {
+		return mazeerrors.NewValidation("Invalid origin: the longitude must be in (-180, 180] and the latitude in [-90, 90]")
+	}
+	if origin.Rotation != 0 || (origin.XScale != 0 && origin.XScale != 1) || (origin.YScale != 0 && origin.YScale != 1) {
+		return mazeerrors.NewValidation("Invalid origin: geographic origins can't be rotated nor scaled")
+	}
+	return nil
+}
+
+//defaults fills the settings left empty
+func defaults(maze models.Maze) models.Maze {
+	if maze.Coordinates == "" {
+		maze.Coordinates = models.Cartesian
+	}
+	if maze.Metric == "" {
+		maze.Metric = models.Euclidean
+	}
+	return maze
+}
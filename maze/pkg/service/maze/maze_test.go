@@ -0,0 +1,174 @@
+package maze
+
+import (
+	"context"
+	"errors"
+	"github.com/avanticaTest/maze/pkg/db"
+	"github.com/avanticaTest/maze/pkg/models"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestModifyMaze(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		request      models.Maze
+		origins      []models.Origin
+		outOfRange   []models.Spot
+		expectedResp int
+		expectedErr  error
+	}{
+		{
+			name:         "Geographic",
+			request:      models.Maze{Coordinates: models.Geographic},
+			origins:      []models.Origin{{XOrigin: -58.4, YOrigin: -34.6}},
+			expectedResp: 1,
+		},
+		{
+			name:         "Back to cartesian",
+			request:      models.Maze{},
+			outOfRange:   []models.Spot{{XCoordinate: 500}},
+			expectedResp: 1,
+		},
+		{
+			name:        "Spots out of range",
+			request:     models.Maze{Coordinates: models.Geographic},
+			outOfRange:  []models.Spot{{XCoordinate: 500}},
+			expectedErr: errors.New("There are spots out of the longitude and latitude ranges"),
+		},
+		{
+			name:        "Rotated origin",
+			request:     models.Maze{Coordinates: models.Geographic},
+			origins:     []models.Origin{{XOrigin: 10, Rotation: 45}},
+			expectedErr: errors.New("Invalid origin: geographic origins can't be rotated nor scaled"),
+		},
+		{
+			name:        "Geographic grid",
+			request:     models.Maze{Coordinates: models.Geographic, Metric: models.Manhattan},
+			expectedErr: errors.New("Invalid metric: geographic mazes measure great-circle distances"),
+		},
+		{
+			name:        "Unknown metric",
+			request:     models.Maze{Metric: "minkowski"},
+			expectedErr: errors.New("Invalid metric: it must be either euclidean, manhattan or chebyshev"),
+		},
+		{
+			name:        "Unknown coordinates",
+			request:     models.Maze{Coordinates: "polar"},
+			expectedErr: errors.New("Invalid coordinates: they must be either cartesian or geographic"),
+		},
+	}
+
+	ctx := context.Background()
+	id := primitive.NewObjectID()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			m := &db.Mock{}
+			m.On("FindOne", ctx, "mazedb", "mazes", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				*args.Get(4).(*models.Maze) = models.Maze{ID: id, Name: "office"}
+			})
+			m.On("FindOrigin", mock.Anything, "mazedb", "origin", mock.Anything).Return(tt.origins, nil)
+			m.On("StreamSpots", mock.Anything, "mazedb", "spots", mock.Anything).Return(tt.outOfRange, nil)
+			m.On("UpdateOne", ctx, mock.Anything, mock.Anything, "mazedb", "mazes").Return(1, nil)
+			s := New(log.NewNopLogger(), db.NewMazeRepository(m, "mazedb", db.MazesCollection), db.NewOriginRepository(m, "mazedb", db.OriginCollection), db.NewSpotRepository(m, "mazedb", db.SpotsCollection), nil, nil, nil, nil)
+
+			resp, err := s.ModifyMaze(ctx, tt.request, id.Hex())
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, tt.expectedResp, resp)
+		})
+	}
+}
+
+func TestDeleteMaze(t *testing.T) {
+
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	spots := db.NewMemorySpotRepository(store)
+	origins := db.NewMemoryOriginRepository(store)
+	s := New(log.NewNopLogger(), db.NewMemoryMazeRepository(store), origins, spots,
+		db.NewMemoryPathRepository(store), db.NewMemoryZoneRepository(store), nil, db.NoTransactions())
+
+	kept, err := s.CreateMaze(ctx, models.Maze{Name: "office"})
+	assert.NilError(t, err)
+	deleted, err := s.CreateMaze(ctx, models.Maze{Name: "warehouse"})
+	assert.NilError(t, err)
+	for _, id := range []string{kept, deleted} {
+		maze, err := primitive.ObjectIDFromHex(id)
+		assert.NilError(t, err)
+		_, err = spots.Create(db.WithMaze(ctx, maze), models.Spot{Name: "lobby"})
+		assert.NilError(t, err)
+		_, err = origins.Create(db.WithMaze(ctx, maze), models.Origin{XOrigin: 1})
+		assert.NilError(t, err)
+	}
+
+	result, err := s.DeleteMaze(ctx, deleted)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, result)
+	_, err = s.GetMaze(ctx, deleted)
+	assert.Error(t, err, "No maze found with ID "+deleted)
+
+	//only the kept maze's spot and origin are left
+	left, err := spots.List(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(left))
+	assert.Equal(t, kept, left[0].MazeID.Hex())
+	count, err := origins.Count(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+//settled has the settings of the single maze there was before mazes
+type settled struct {
+	db.LegacyRepository
+	settings models.Maze
+}
+
+func (s settled) Settings(ctx context.Context) (models.Maze, bool, error) {
+	return s.settings, true, nil
+}
+
+func TestAdopt(t *testing.T) {
+
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	spots := db.NewMemorySpotRepository(store)
+	mazes := db.NewMemoryMazeRepository(store)
+	legacy := db.NewMemoryLegacyRepository(store)
+
+	//with nothing stored before mazes there's nothing to adopt
+	id, err := Adopt(ctx, legacy, mazes, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, "", id)
+
+	_, err = spots.Create(ctx, models.Spot{Name: "lobby"})
+	assert.NilError(t, err)
+	id, err = Adopt(ctx, settled{legacy, models.Maze{Metric: models.Manhattan}}, mazes, nil)
+	assert.NilError(t, err)
+
+	//the default maze keeps the old settings and sees the spot
+	s := New(log.NewNopLogger(), mazes, nil, spots, nil, nil, nil, nil)
+	maze, err := s.GetMaze(ctx, id)
+	assert.NilError(t, err)
+	assert.Equal(t, DefaultName, maze.Name)
+	assert.Equal(t, models.Manhattan, maze.Metric)
+	assert.Equal(t, models.Cartesian, maze.Coordinates)
+	adopted, err := spots.List(db.WithMaze(ctx, maze.ID))
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(adopted))
+
+	//and the next start up finds nothing left
+	id, err = Adopt(ctx, legacy, mazes, nil)
+	assert.NilError(t, err)
+	assert.Equal(t, "", id)
+}
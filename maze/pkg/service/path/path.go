@@ -3,7 +3,10 @@ package path
 import (
 	"context"
 	"github.com/avanticaTest/maze/pkg/db"
+	mazeerrors "github.com/avanticaTest/maze/pkg/errors"
+	"github.com/avanticaTest/maze/pkg/geometry"
 	"github.com/avanticaTest/maze/pkg/models"
+	"github.com/avanticaTest/maze/pkg/service/maze"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"go.mongodb.org/mongo-driver/bson"
@@ -20,13 +23,24 @@ type PathHandler interface {
 }
 
 type stubPathHandler struct {
-	db     db.DBManager
+	paths  db.PathRepository
+	spots  db.SpotRepository
+	mazes  maze.MazeHandler
+	tx     db.Transactor
 	logger log.Logger
 }
 
-func New(logger log.Logger, db db.DBManager) PathHandler {
+//New creates the path service. The maze's settings decide how distances are measured, without a maze service the
+//maze is cartesian
+func New(logger log.Logger, paths db.PathRepository, spots db.SpotRepository, mazes maze.MazeHandler, tx db.Transactor) PathHandler {
+	if tx == nil {
+		tx = db.NoTransactions()
+	}
 	return &stubPathHandler{
-		db:     db,
+		paths:  paths,
+		spots:  spots,
+		mazes:  mazes,
+		tx:     tx,
 		logger: logger,
 	}
 }
@@ -35,30 +49,52 @@ func New(logger log.Logger, db db.DBManager) PathHandler {
 func (s *stubPathHandler) CreatePath(ctx context.Context, request models.CreatePathRequest) (string, error) {
 
 	//first we get the objectIDs from the strings of the request
-	idpa, _ := primitive.ObjectIDFromHex(request.PointA)
-	idpb, _ := primitive.ObjectIDFromHex(request.PointB)
-	var path models.Path
-	var spotA models.Spot
-	var spotB models.Spot
-	//then we get the spots represented by those IDs
-	err := s.db.FindOne(ctx, "mazedb", "spots", models.Spot{ID: idpa}, &spotA)
-	if err != nil {
-		level.Error(s.logger).Log("method", "CreatePath", "error", err)
+	idpa, err := mazeerrors.ParseID("point_a", request.PointA)
+	if err != nil {
 		return "", err
 	}
-	err = s.db.FindOne(ctx, "mazedb", "spots", models.Spot{ID: idpb}, &spotB)
+	idpb, err := mazeerrors.ParseID("point_b", request.PointB)
 	if err != nil {
-		level.Error(s.logger).Log("method", "CreatePath", "error", err)
 		return "", err
 	}
-	//then we calculate the distance between those points and load it into the path
-	path.Distance = Distance(spotA, spotB)
-	path.PointA = spotA.ID
-	path.PointB = spotB.ID
-	//and save the path itself
-	result, err := s.db.InsertOne(ctx, "mazedb", "paths", path)
+	var result string
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.validate(ctx, primitive.NilObjectID, idpa, idpb); err != nil {
+			return err
+		}
+		var path models.Path
+		if err := s.touch(ctx, idpa, idpb); err != nil {
+			level.Error(s.logger).Log("method", "CreatePath", "error", err)
+			return err
+		}
+		//then we get the spots represented by those IDs
+		spotA, err := s.spots.GetByID(ctx, idpa)
+		if err != nil {
+			level.Error(s.logger).Log("method", "CreatePath", "error", err)
+			return mazeerrors.Missing(err, "spot", request.PointA)
+		}
+		spotB, err := s.spots.GetByID(ctx, idpb)
+		if err != nil {
+			level.Error(s.logger).Log("method", "CreatePath", "error", err)
+			return mazeerrors.Missing(err, "spot", request.PointB)
+		}
+		//then we calculate the distance between those points and load it into the path
+		distance, err := s.distance(ctx)
+		if err != nil {
+			level.Error(s.logger).Log("method", "CreatePath", "error", err)
+			return err
+		}
+		path.Distance = distance(spotA, spotB)
+		path.PointA = spotA.ID
+		path.PointB = spotB.ID
+		//and save the path itself
+		result, err = s.paths.Create(ctx, path)
+		if err != nil {
+			level.Error(s.logger).Log("method", "CreatePath", "error", err)
+		}
+		return joined(err)
+	})
 	if err != nil {
-		level.Error(s.logger).Log("method", "CreatePath", "error", err)
 		return "", err
 	}
 
@@ -68,11 +104,41 @@ func (s *stubPathHandler) CreatePath(ctx context.Context, request models.CreateP
 //GetPaths returns all the paths we have
 func (s *stubPathHandler) GetPaths(ctx context.Context) ([]models.Path, error) {
 
-	result, err := s.db.FindPaths(ctx, "mazedb", "paths")
+	result, err := s.paths.List(ctx)
 	if err != nil{
 		level.Error(s.logger).Log("method", "GetPaths", "error", err)
 		return nil, err
 	}
+	if len(result) == 0 {
+		return result, nil
+	}
+
+	//as in GetSinglePath, the distances are measured again, since the spots or the maze's metric may have changed
+	ids := make([]primitive.ObjectID, 0, 2*len(result))
+	for _, p := range result {
+		ids = append(ids, p.PointA, p.PointB)
+	}
+	spots, err := s.spots.GetByIDs(ctx, ids)
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetPaths", "error", err)
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]models.Spot, len(spots))
+	for _, v := range spots {
+		byID[v.ID] = v
+	}
+	distance, err := s.distance(ctx)
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetPaths", "error", err)
+		return nil, err
+	}
+	for i, p := range result {
+		a, okA := byID[p.PointA]
+		b, okB := byID[p.PointB]
+		if okA && okB {
+			result[i].Distance = distance(a, b)
+		}
+	}
 
 	return result, nil
 }
@@ -80,32 +146,40 @@ func (s *stubPathHandler) GetPaths(ctx context.Context) ([]models.Path, error) {
 //GetSinglePath gets one single path given its ID
 func (s *stubPathHandler) GetSinglePath(ctx context.Context, id string)(models.Path, error) {
 
-	var path models.Path
-
-	idp, _ := primitive.ObjectIDFromHex(id)
-
+	idp, err := mazeerrors.ParseID("id", id)
+	if err != nil {
+		return models.Path{}, err
+	}
 
-	err := s.db.FindOne(ctx, "mazedb", "paths", models.Path{ID: idp}, &path)
+	path, err := s.paths.GetByID(ctx, idp)
 	if err != nil {
 		level.Error(s.logger).Log("method", "GetSinglePath", "error", err)
-		return models.Path{},err
+		return models.Path{}, mazeerrors.Missing(err, "path", id)
+	}
+
+	//a path detached from a deleted spot has nothing to be measured against
+	if path.PointA.IsZero() || path.PointB.IsZero() {
+		return path, nil
 	}
 
 	//Here we update the path just in case any of the spots has changed
-	var spotA models.Spot
-	var spotB models.Spot
-	err = s.db.FindOne(ctx, "mazedb", "spots", models.Spot{ID: path.PointA}, &spotA)
+	spotA, err := s.spots.GetByID(ctx, path.PointA)
 	if err != nil {
 		level.Error(s.logger).Log("method", "GetSinglePath", "error", err)
-		return models.Path{},err
+		return models.Path{}, mazeerrors.Missing(err, "spot", path.PointA.Hex())
 	}
-	err = s.db.FindOne(ctx, "mazedb", "spots", models.Spot{ID: path.PointB}, &spotB)
+	spotB, err := s.spots.GetByID(ctx, path.PointB)
 	if err != nil {
 		level.Error(s.logger).Log("method", "GetSinglePath", "error", err)
-		return models.Path{},err
+		return models.Path{}, mazeerrors.Missing(err, "spot", path.PointB.Hex())
 	}
 
-	path.Distance = Distance(spotA, spotB)
+	distance, err := s.distance(ctx)
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetSinglePath", "error", err)
+		return models.Path{},err
+	}
+	path.Distance = distance(spotA, spotB)
 	s.logger.Log("PathA", path.PointA, "PathB", path.PointB)
 	return path, nil
 }
@@ -114,36 +188,58 @@ func (s *stubPathHandler) GetSinglePath(ctx context.Context, id string)(models.P
 //ModifyPath modifies a path changing one or both of the spots that compose it
 func (s *stubPathHandler) ModifyPath(ctx context.Context, request models.CreatePathRequest, id string) (int, error) {
 
-	idp, err := primitive.ObjectIDFromHex(id)
+	idp, err := mazeerrors.ParseID("id", id)
 	if err != nil{
 		level.Error(s.logger).Log("method", "ModifyPath", "error", err)
 		return 0,err
 	}
-	filter := bson.D{{"_id", idp}}
-
 
-	idpa, _ := primitive.ObjectIDFromHex(request.PointA)
-	idpb, _ := primitive.ObjectIDFromHex(request.PointB)
-	var spotA models.Spot
-	var spotB models.Spot
-	err = s.db.FindOne(ctx, "mazedb", "spots", models.Spot{ID: idpa}, &spotA)
+	idpa, err := mazeerrors.ParseID("point_a", request.PointA)
 	if err != nil {
-		level.Error(s.logger).Log("method", "ModifyPath", "error", err)
-		return 0,err
+		return 0, err
 	}
-	err = s.db.FindOne(ctx, "mazedb", "spots", models.Spot{ID: idpb}, &spotB)
+	idpb, err := mazeerrors.ParseID("point_b", request.PointB)
 	if err != nil {
-		level.Error(s.logger).Log("method", "ModifyPath", "error", err)
-		return 0,err
+		return 0, err
 	}
-
-
-	update := bson.D{{"$set", bson.D{{"point_a", idpa},
-		{"point_b", idpb},
-		{"distance", Distance(spotA, spotB)}}}}
-	result, err := s.db.UpdateOne(ctx, filter, update, "mazedb", "paths")
+	var result int
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := s.paths.GetByID(ctx, idp); err != nil {
+			level.Error(s.logger).Log("method", "ModifyPath", "error", err)
+			return mazeerrors.Missing(err, "path", id)
+		}
+		if err := s.validate(ctx, idp, idpa, idpb); err != nil {
+			return err
+		}
+		if err := s.touch(ctx, idpa, idpb); err != nil {
+			level.Error(s.logger).Log("method", "ModifyPath", "error", err)
+			return err
+		}
+		spotA, err := s.spots.GetByID(ctx, idpa)
+		if err != nil {
+			level.Error(s.logger).Log("method", "ModifyPath", "error", err)
+			return mazeerrors.Missing(err, "spot", request.PointA)
+		}
+		spotB, err := s.spots.GetByID(ctx, idpb)
+		if err != nil {
+			level.Error(s.logger).Log("method", "ModifyPath", "error", err)
+			return mazeerrors.Missing(err, "spot", request.PointB)
+		}
+
+		distance, err := s.distance(ctx)
+		if err != nil {
+			level.Error(s.logger).Log("method", "ModifyPath", "error", err)
+			return err
+		}
+
+		path := models.Path{PointA: idpa, PointB: idpb, Distance: distance(spotA, spotB)}
+		result, err = s.paths.Update(ctx, idp, path)
+		if err != nil {
+			level.Error(s.logger).Log("method", "ModifyPath", "error", err)
+		}
+		return joined(err)
+	})
 	if err != nil {
-		level.Error(s.logger).Log("method", "GetSpotsInQuadrant", "error", err)
 		return 0, err
 	}
 	return result, nil
@@ -154,26 +250,110 @@ func (s *stubPathHandler) ModifyPath(ctx context.Context, request models.CreateP
 func (s *stubPathHandler) DeletePath(ctx context.Context, id string) (int, error) {
 
 
-	idp, err := primitive.ObjectIDFromHex(id)
+	idp, err := mazeerrors.ParseID("id", id)
 	if err != nil{
-		level.Error(s.logger).Log("method", "GetSpotsInQuadrant", "error", err)
+		level.Error(s.logger).Log("method", "DeletePath", "error", err)
 		return 0, err
 	}
-	filter := bson.D{{"_id", idp}}
 
-	result, err := s.db.DeleteOne(ctx, filter, "mazedb", "paths")
+	result, err := s.paths.Delete(ctx, idp)
 	if err != nil {
 		level.Error(s.logger).Log("method", "DeletePath", "error", err)
 		return 0, err
 	}
+	if result == 0 {
+		return 0, mazeerrors.NewNotFound("path", id)
+	}
 
 	return result, nil
 }
 
-//Distance calculates the distance between two spots
+//validate rejects the paths joining a spot to itself, and the ones joining two spots another path already joins,
+//whichever way. The path with the given ID, the one being modified, doesn't count. The repositories turn away the
+//duplicates written meanwhile, which joined answers with the same conflict
+func (s *stubPathHandler) validate(ctx context.Context, id, a, b primitive.ObjectID) error {
+	if a == b {
+		return mazeerrors.NewValidation("Invalid path: it must join two different spots")
+	}
+	filter := db.PathBetween(a, b)
+	if !id.IsZero() {
+		filter = bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$ne": id}}}}
+	}
+	joined, err := s.paths.Find(ctx, filter)
+	if err != nil {
+		level.Error(s.logger).Log("method", "validate", "error", err)
+		return err
+	}
+	if len(joined) > 0 {
+		return mazeerrors.NewConflict("There's already a path between those spots")
+	}
+	return nil
+}
+
+//joined answers the paths the repository turned away for joining the same spots as another one with a conflict
+func joined(err error) error {
+	if err == db.ErrPathExists {
+		return mazeerrors.NewConflict("There's already a path between those spots")
+	}
+	return err
+}
+
+//touch writes to the spots the path joins, so a transaction writing the path collides with any deleting them: one of
+//the two is retried, and sees what the other did
+func (s *stubPathHandler) touch(ctx context.Context, ids ...primitive.ObjectID) error {
+	_, err := s.spots.Touch(ctx, ids...)
+	return err
+}
+
+//Distance calculates the distance between two spots. Spots without a z coordinate are on the ground floor, so the
+//distance between two of them stays the planar one
 func Distance(a, b models.Spot) float64 {
 	first := math.Pow(b.XCoordinate-a.XCoordinate, 2)
 	second := math.Pow(b.YCoordinate-a.YCoordinate, 2)
-	return math.Sqrt(first + second)
+	third := math.Pow(b.ZCoordinate-a.ZCoordinate, 2)
+	return math.Sqrt(first + second + third)
+
+}
+
+//GeoDistance calculates the distance in meters between two spots of a geographic maze, their z coordinates being
+//elevations in meters
+func GeoDistance(a, b models.Spot) float64 {
+	surface := geometry.GreatCircle(a.XCoordinate, a.YCoordinate, b.XCoordinate, b.YCoordinate)
+	return math.Hypot(surface, b.ZCoordinate-a.ZCoordinate)
+}
+
+//Manhattan calculates the distance between two spots moving along the axes only
+func Manhattan(a, b models.Spot) float64 {
+	return math.Abs(b.XCoordinate-a.XCoordinate) + math.Abs(b.YCoordinate-a.YCoordinate) +
+		math.Abs(b.ZCoordinate-a.ZCoordinate)
+}
+
+//Chebyshev calculates the distance between two spots when diagonal moves cost as much as straight ones
+func Chebyshev(a, b models.Spot) float64 {
+	return math.Max(math.Abs(b.XCoordinate-a.XCoordinate),
+		math.Max(math.Abs(b.YCoordinate-a.YCoordinate), math.Abs(b.ZCoordinate-a.ZCoordinate)))
+}
+
+//Metric returns the way distances are measured in the maze. Paths and any routing heuristic must measure with it, so
+//they agree with each other
+func Metric(m models.Maze) func(a, b models.Spot) float64 {
+	if m.Coordinates == models.Geographic {
+		return GeoDistance
+	}
+	switch m.Metric {
+	case models.Manhattan:
+		return Manhattan
+	case models.Chebyshev:
+		return Chebyshev
+	}
+	return Distance
+}
 
+//distance returns the way distances are measured in the maze
+func (s *stubPathHandler) distance(ctx context.Context) (func(a, b models.Spot) float64, error) {
+	m, err := maze.Get(ctx, s.mazes)
+	if err != nil {
+		return nil, err
+	}
+	return Metric(m), nil
 }
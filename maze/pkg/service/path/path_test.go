@@ -4,9 +4,16 @@ import (
 	"context"
 	"errors"
 	"github.com/avanticaTest/maze/pkg/db"
+	mazeerrors "github.com/avanticaTest/maze/pkg/errors"
+	"github.com/avanticaTest/maze/pkg/models"
 	"github.com/go-kit/kit/log"
 	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"gotest.tools/v3/assert"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -30,6 +37,15 @@ func TestDeletePath(t *testing.T) {
 			success:      true,
 			mongoOK:      true,
 		},
+		{
+			name:         "Missing",
+			request:      "5fbecff95f80a305742abb10",
+			response:     0,
+			expectedResp: 0,
+			expectedErr:  mazeerrors.NewNotFound("path", "5fbecff95f80a305742abb10"),
+			success:      false,
+			mongoOK:      true,
+		},
 		{
 			name:         "Not OK",
 			request:      "5fbecff95f80a305742abb10",
@@ -47,13 +63,13 @@ func TestDeletePath(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 
 			logger := log.NewNopLogger()
-			db := db.Mock{}
+			m := &db.Mock{}
 			if tt.mongoOK {
-				db.On("DeleteOne", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(123456, nil)
+				m.On("DeleteOne", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(tt.response, nil)
 			} else {
-				db.On("DeleteOne", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(0, errors.New("mongo error"))
+				m.On("DeleteOne", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(0, errors.New("mongo error"))
 			}
-			p := New(logger, db)
+			p := New(logger, db.NewPathRepository(m, "mazedb", db.PathsCollection), db.NewSpotRepository(m, "mazedb", db.SpotsCollection), nil, nil)
 
 			resp, err := p.DeletePath(ctx, tt.request)
 
@@ -61,10 +77,259 @@ func TestDeletePath(t *testing.T) {
 			if tt.success {
 				assert.NilError(t, err)
 			} else {
-				assert.Error(t, err, errors.New("mongo error").Error())
+				assert.Error(t, err, tt.expectedErr.Error())
 			}
 
 		})
 	}
 
 }
+
+func TestDistance(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		a, b     models.Spot
+		expected float64
+	}{
+		{
+			name:     "Same floor",
+			a:        models.Spot{XCoordinate: 1, YCoordinate: 1},
+			b:        models.Spot{XCoordinate: 4, YCoordinate: 5},
+			expected: 5,
+		},
+		{
+			name:     "Between floors",
+			a:        models.Spot{XCoordinate: 1, YCoordinate: 1},
+			b:        models.Spot{XCoordinate: 3, YCoordinate: 3, ZCoordinate: 1},
+			expected: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Distance(tt.a, tt.b))
+			assert.Equal(t, tt.expected, Distance(tt.b, tt.a))
+		})
+	}
+}
+
+func TestMetric(t *testing.T) {
+
+	a := models.Spot{XCoordinate: 1, YCoordinate: 1}
+	b := models.Spot{XCoordinate: 4, YCoordinate: 5, ZCoordinate: 1}
+
+	tests := []struct {
+		name     string
+		maze     models.Maze
+		expected float64
+	}{
+		{name: "Default", maze: models.Maze{}, expected: math.Sqrt(26)},
+		{name: "Manhattan", maze: models.Maze{Metric: models.Manhattan}, expected: 8},
+		{name: "Chebyshev", maze: models.Maze{Metric: models.Chebyshev}, expected: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Metric(tt.maze)(a, b))
+		})
+	}
+}
+
+func TestGetPathsMeasuresAgain(t *testing.T) {
+
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	spots := db.NewMemorySpotRepository(store)
+	s := New(log.NewNopLogger(), db.NewMemoryPathRepository(store), spots, nil, nil)
+
+	a, err := spots.Create(ctx, models.Spot{Name: "a"})
+	assert.NilError(t, err)
+	b, err := spots.Create(ctx, models.Spot{Name: "b", XCoordinate: 3})
+	assert.NilError(t, err)
+	_, err = s.CreatePath(ctx, models.CreatePathRequest{PointA: a, PointB: b})
+	assert.NilError(t, err)
+
+	//the stored distance is stale once a spot moves
+	id, _ := primitive.ObjectIDFromHex(b)
+	_, err = spots.Update(ctx, id, models.Spot{Name: "b", XCoordinate: 3, YCoordinate: 4})
+	assert.NilError(t, err)
+
+	paths, err := s.GetPaths(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(paths))
+	assert.Equal(t, 5.0, paths[0].Distance)
+}
+
+func TestCreatePathIntegrity(t *testing.T) {
+
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	spots := db.NewMemorySpotRepository(store)
+	s := New(log.NewNopLogger(), db.NewMemoryPathRepository(store), spots, nil, nil)
+
+	a, err := spots.Create(ctx, models.Spot{Name: "a"})
+	assert.NilError(t, err)
+	b, err := spots.Create(ctx, models.Spot{Name: "b", XCoordinate: 3})
+	assert.NilError(t, err)
+	c, err := spots.Create(ctx, models.Spot{Name: "c", YCoordinate: 3})
+	assert.NilError(t, err)
+	existing, err := s.CreatePath(ctx, models.CreatePathRequest{PointA: a, PointB: b})
+	assert.NilError(t, err)
+
+	tests := []struct {
+		name        string
+		request     models.CreatePathRequest
+		expectedErr error
+		conflict    bool
+	}{
+		{
+			name:        "Self loop",
+			request:     models.CreatePathRequest{PointA: a, PointB: a},
+			expectedErr: errors.New("Invalid path: it must join two different spots"),
+		},
+		{
+			name:        "Duplicate",
+			request:     models.CreatePathRequest{PointA: a, PointB: b},
+			expectedErr: mazeerrors.NewConflict("There's already a path between those spots"),
+			conflict:    true,
+		},
+		{
+			name:        "Duplicate the other way",
+			request:     models.CreatePathRequest{PointA: b, PointB: a},
+			expectedErr: mazeerrors.NewConflict("There's already a path between those spots"),
+			conflict:    true,
+		},
+		{
+			name:    "OK",
+			request: models.CreatePathRequest{PointA: a, PointB: c},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.CreatePath(ctx, tt.request)
+			if tt.expectedErr != nil {
+				assert.Error(t, err, tt.expectedErr.Error())
+				_, conflict := err.(mazeerrors.Conflict)
+				assert.Equal(t, tt.conflict, conflict)
+				return
+			}
+			assert.NilError(t, err)
+		})
+	}
+
+	//a path can be modified into itself, but not into another one
+	_, err = s.ModifyPath(ctx, models.CreatePathRequest{PointA: b, PointB: a}, existing)
+	assert.NilError(t, err)
+	_, err = s.ModifyPath(ctx, models.CreatePathRequest{PointA: c, PointB: a}, existing)
+	assert.Equal(t, mazeerrors.NewConflict("There's already a path between those spots"), err)
+	missing := primitive.NewObjectID().Hex()
+	_, err = s.ModifyPath(ctx, models.CreatePathRequest{PointA: b, PointB: c}, missing)
+	assert.Equal(t, mazeerrors.NewNotFound("path", missing), err)
+}
+
+//stale finds no path, as when another one is written right after the check
+type stale struct {
+	db.PathRepository
+}
+
+func (stale) Find(ctx context.Context, filter interface{}) ([]models.Path, error) {
+	return nil, nil
+}
+
+func TestCreatePathRace(t *testing.T) {
+
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	spots := db.NewMemorySpotRepository(store)
+	s := New(log.NewNopLogger(), stale{db.NewMemoryPathRepository(store)}, spots, nil, nil)
+
+	a, err := spots.Create(ctx, models.Spot{Name: "a"})
+	assert.NilError(t, err)
+	b, err := spots.Create(ctx, models.Spot{Name: "b", XCoordinate: 3})
+	assert.NilError(t, err)
+	_, err = s.CreatePath(ctx, models.CreatePathRequest{PointA: a, PointB: b})
+	assert.NilError(t, err)
+
+	//the repository turns away the duplicate the check missed
+	_, err = s.CreatePath(ctx, models.CreatePathRequest{PointA: b, PointB: a})
+	assert.Equal(t, mazeerrors.NewConflict("There's already a path between those spots"), err)
+
+	//and writing the path wrote to its spots, so a transaction deleting one of them would have collided with it
+	for _, hex := range []string{a, b} {
+		id, _ := primitive.ObjectIDFromHex(hex)
+		spot, err := spots.GetByID(ctx, id)
+		assert.NilError(t, err)
+		assert.Assert(t, spot.Revision > 0)
+	}
+}
+
+//recorder runs the work as it comes, keeping what each unit of work returned
+type recorder struct {
+	results []error
+}
+
+func (r *recorder) Transaction(ctx context.Context, work func(ctx context.Context) error) error {
+	err := work(ctx)
+	r.results = append(r.results, err)
+	return err
+}
+
+func TestCreatePathTransaction(t *testing.T) {
+
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	spots := db.NewMemorySpotRepository(store)
+	tx := &recorder{}
+	s := New(log.NewNopLogger(), db.NewMemoryPathRepository(store), spots, nil, tx)
+
+	a, err := spots.Create(ctx, models.Spot{Name: "a"})
+	assert.NilError(t, err)
+	b, err := spots.Create(ctx, models.Spot{Name: "b", XCoordinate: 3})
+	assert.NilError(t, err)
+
+	//the spots are read and the path written in a single unit of work
+	_, err = s.CreatePath(ctx, models.CreatePathRequest{PointA: a, PointB: b})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(tx.results))
+	assert.NilError(t, tx.results[0])
+
+	//a missing spot fails the unit of work, so nothing it did would be committed
+	missing := primitive.NewObjectID().Hex()
+	_, err = s.CreatePath(ctx, models.CreatePathRequest{PointA: a, PointB: missing})
+	assert.Equal(t, mazeerrors.NewNotFound("spot", missing), err)
+	assert.Equal(t, 2, len(tx.results))
+	assert.Equal(t, err, tx.results[1])
+}
+
+func TestCreatePathRollback(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "maze")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := db.OpenBoltStore(filepath.Join(dir, "maze.db"))
+	assert.NilError(t, err)
+	defer store.Close()
+
+	ctx := context.Background()
+	spots := db.NewBoltSpotRepository(store)
+	s := New(log.NewNopLogger(), stale{db.NewBoltPathRepository(store)}, spots, nil, db.NewBoltTransactor(store))
+
+	a, err := spots.Create(ctx, models.Spot{Name: "a"})
+	assert.NilError(t, err)
+	b, err := spots.Create(ctx, models.Spot{Name: "b", XCoordinate: 3})
+	assert.NilError(t, err)
+	_, err = s.CreatePath(ctx, models.CreatePathRequest{PointA: a, PointB: b})
+	assert.NilError(t, err)
+
+	//the duplicate is turned away after its spots were written to, and those writes are rolled back with it
+	_, err = s.CreatePath(ctx, models.CreatePathRequest{PointA: b, PointB: a})
+	assert.Equal(t, mazeerrors.NewConflict("There's already a path between those spots"), err)
+	for _, hex := range []string{a, b} {
+		id, _ := primitive.ObjectIDFromHex(hex)
+		spot, err := spots.GetByID(ctx, id)
+		assert.NilError(t, err)
+		assert.Equal(t, 1, spot.Revision)
+	}
+}
@@ -2,12 +2,18 @@ package quadrant
 
 import (
 	"context"
-	"errors"
 	"github.com/avanticaTest/maze/pkg/db"
+	mazeerrors "github.com/avanticaTest/maze/pkg/errors"
+	"github.com/avanticaTest/maze/pkg/geometry"
+	"github.com/avanticaTest/maze/pkg/index"
 	"github.com/avanticaTest/maze/pkg/models"
+	"github.com/avanticaTest/maze/pkg/service/maze"
+	"github.com/avanticaTest/maze/pkg/service/zone"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"go.mongodb.org/mongo-driver/bson"
+	"math"
+	"sort"
 )
 
 type OriginHandler interface {
@@ -16,84 +22,126 @@ type OriginHandler interface {
 	GetOrigin(ctx context.Context) (models.Origin, error)
 	GetSpotsInQuadrant(ctx context.Context, request models.Quadrant) ([]models.Spot, error)
 	DeleteOrigin(ctx context.Context) (int, error)
+	GetLocalSpots(ctx context.Context) ([]models.LocalSpot, error)
+	GetSingleLocalSpot(ctx context.Context, id string) (models.LocalSpot, error)
+	GetSpotsInSector(ctx context.Context, request models.PolarQuery) ([]models.PolarSpot, error)
+	GetQuadrantStats(ctx context.Context) (map[string]models.SpotStats, error)
+	GetHull(ctx context.Context, quadrant string) (models.Hull, error)
+	SuggestOrigin(ctx context.Context, request models.OriginSuggestionQuery) (models.OriginSuggestion, error)
 }
 
 type stubOriginHandler struct {
-	db     db.DBManager
+	origins db.OriginRepository
+	spots   db.SpotRepository
+	zones  zone.ZoneHandler
+	index  *index.Mazes
+	mazes  maze.MazeHandler
+	tx     db.Transactor
 	logger log.Logger
 }
 
-func New(logger log.Logger, db db.DBManager) OriginHandler {
+//New creates the origin service. When an index is given, the spots are looked up in it instead of the database.
+//Without a maze service the maze is cartesian
+func New(logger log.Logger, origins db.OriginRepository, spots db.SpotRepository, zones zone.ZoneHandler, index *index.Mazes, mazes maze.MazeHandler, tx db.Transactor) OriginHandler {
+	if tx == nil {
+		tx = db.NoTransactions()
+	}
 	return stubOriginHandler{
-		db:     db,
-		logger: logger,
+		origins: origins,
+		spots:   spots,
+		zones:   zones,
+		index:   index,
+		mazes:   mazes,
+		tx:      tx,
+		logger:  logger,
 	}
 }
 
-//CreateOrigin creates a Origin using its coordinates. There can only be one origin
+//spotIndex returns the index of the context's maze
+func (s stubOriginHandler) spotIndex(ctx context.Context) *index.SpotIndex {
+	maze, _ := db.MazeFrom(ctx)
+	return s.index.Of(maze)
+}
+
+//CreateOrigin creates a Origin using its coordinates. There can only be one origin per maze
 func (s stubOriginHandler) CreateOrigin(ctx context.Context, request models.Origin) (string, error) {
 
-	e, err := s.db.EstimatedDocumentCount(ctx, "mazedb", "origin")
-	if err != nil {
-		level.Error(s.logger).Log("method", "CreateOrigin", "error", err)
-		return "", err
-	}
-	if e > 0 {
-		return "", errors.New("There can be only one Origin")
-	}
+	var result string
+	err := s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.validate(ctx, request); err != nil {
+			return err
+		}
 
-	result, err := s.db.InsertOne(ctx, "mazedb", "origin", request)
+		//the repository only creates the origin when there's none, even if another request is creating it meanwhile
+		var err error
+		result, err = s.origins.Create(ctx, request)
+		if err == db.ErrOriginExists {
+			return mazeerrors.NewConflict("There can be only one Origin")
+		}
+		if err != nil {
+			level.Error(s.logger).Log("method", "CreateOrigin", "error", err)
+		}
+		return err
+	})
 	if err != nil {
-		level.Error(s.logger).Log("method", "CreateOrigin", "error", err)
 		return "", err
 	}
 
 	return result, nil
 }
 
-//GetSpotsInQuadrant gets all spots in a given quadrant, using the origin as reference
+//GetSpotsInQuadrant gets all spots in a given quadrant, or sector of another partition, using the origin as reference.
+//The quadrants are the built-in zones, so a quadrant sharing its boundaries is looked up in them
 func (s stubOriginHandler) GetSpotsInQuadrant(ctx context.Context, request models.Quadrant) ([]models.Spot, error) {
 
-	//first we get all the spots
-	resultS, err := s.db.FindSpots(ctx, "mazedb", "spots")
+	n, sector, err := partition(request)
 	if err != nil {
-		level.Error(s.logger).Log("method", "CreateSpot", "error", err)
 		return nil, err
 	}
+	if n != 4 || (request.Boundary != "" && request.Boundary != geometry.Shared) {
+		return s.spotsInSector(ctx, n, sector, request.Boundary, request.Floor)
+	}
 
-	//then we get the origin
-
-	resultO, err := s.db.FindOrigin(ctx, "mazedb", "origin")
+	result, err := s.zones.GetSpotsInZone(ctx, geometry.QuadrantName(sector), request.Floor)
 	if err != nil {
 		level.Error(s.logger).Log("method", "GetSpotsInQuadrant", "error", err)
 		return nil, err
 	}
-	if len(resultO) < 1 {
-		return nil, errors.New("Inexistant Origin")
+	return result, nil
+}
+
+//spotsInSector gets the spots in a sector of a partition in n sectors. The database only narrows the spots down to the
+//closed sector, the tie-breaking is done here
+func (s stubOriginHandler) spotsInSector(ctx context.Context, n, sector int, boundary string, floor *float64) ([]models.Spot, error) {
+
+	origin, err := s.GetOrigin(ctx)
+	if err != nil {
+		return nil, err
 	}
-	origin := resultO[0]
-	var result []models.Spot
-	for _, v := range resultS {
 
-		switch request.Quadrant {
-		case "upper_left":
-			if (origin.XOrigin >= v.XCoordinate) && (origin.YOrigin <= v.YCoordinate) {
-				result = append(result, v)
-			}
-		case "upper_right":
-			if (origin.XOrigin <= v.XCoordinate) && (origin.YOrigin <= v.YCoordinate) {
-				result = append(result, v)
-			}
-		case "bottom_left":
-			if (origin.XOrigin >= v.XCoordinate) && (origin.YOrigin >= v.YCoordinate) {
-				result = append(result, v)
-			}
-		case "bottom_right":
-			if (origin.XOrigin <= v.XCoordinate) && (origin.YOrigin >= v.YCoordinate) {
+	var result []models.Spot
+	add := func(v models.Spot) error {
+		if floor != nil && v.ZCoordinate != *floor {
+			return nil
+		}
+		x, y := geometry.ToLocal(origin, v.XCoordinate, v.YCoordinate)
+		for _, i := range geometry.PartitionSectors(x, y, n, boundary) {
+			if i == sector {
 				result = append(result, v)
 			}
 		}
+		return nil
+	}
 
+	if s.index != nil {
+		for _, v := range s.spotIndex(ctx).All() {
+			add(v)
+		}
+		return result, nil
+	}
+	if err = s.spots.Stream(ctx, db.OnFloor(db.SectorFilter(origin, n, sector), floor), add); err != nil {
+		level.Error(s.logger).Log("method", "GetSpotsInQuadrant", "error", err)
+		return nil, err
 	}
 	return result, nil
 }
@@ -101,30 +149,50 @@ func (s stubOriginHandler) GetSpotsInQuadrant(ctx context.Context, request model
 //GetOrigin returns the origin's coordinates
 func (s stubOriginHandler) GetOrigin(ctx context.Context) (models.Origin, error) {
 
-	result, err := s.db.FindOrigin(ctx, "mazedb", "origin")
+	result, err := s.origins.List(ctx)
 	if err != nil {
 		level.Error(s.logger).Log("method", "GetOrigin", "error", err)
 		return models.Origin{}, err
 	}
 	if len(result) > 0 {
+		m, err := maze.Get(ctx, s.mazes)
+		if err != nil {
+			level.Error(s.logger).Log("method", "GetOrigin", "error", err)
+			return models.Origin{}, err
+		}
+		result[0].Geographic = m.Coordinates == models.Geographic
 		return result[0], nil
 	} else {
 		level.Error(s.logger).Log("method", "GetOrigin", "error", err)
-		return models.Origin{}, errors.New("No origin found")
+		return models.Origin{}, mazeerrors.NewNotFound("origin", "")
 	}
 
 }
 
-//ModifyOrigin modifies the origin's coordinates
+//ModifyOrigin modifies the origin's coordinates, rotation and scale
 func (s stubOriginHandler) ModifyOrigin(ctx context.Context, request models.Origin) (int, error) {
 
-	filter := bson.D{}
+	var result int
+	err := s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.validate(ctx, request); err != nil {
+			return err
+		}
+		current, err := s.origins.List(ctx)
+		if err != nil {
+			level.Error(s.logger).Log("method", "ModifyOrigin", "error", err)
+			return err
+		}
+		if len(current) == 0 {
+			return mazeerrors.NewNotFound("origin", "")
+		}
 
-	update := bson.D{{"$set", bson.D{{"x_origin", request.XOrigin},
-		{"y_origin", request.YOrigin}}}}
-	result, err := s.db.UpdateOne(ctx, filter, update, "mazedb", "origin")
+		result, err = s.origins.Update(ctx, request)
+		if err != nil {
+			level.Error(s.logger).Log("method", "ModifyOrigin", "error", err)
+		}
+		return err
+	})
 	if err != nil {
-		level.Error(s.logger).Log("method", "ModifyOrigin", "error", err)
 		return 0, err
 	}
 	return result, nil
@@ -133,13 +201,371 @@ func (s stubOriginHandler) ModifyOrigin(ctx context.Context, request models.Orig
 //DeleteOrigin deletes the Origin
 func (s stubOriginHandler) DeleteOrigin(ctx context.Context) (int, error) {
 
-	filter := bson.D{}
-
-	result, err := s.db.DeleteMany(ctx, filter, "mazedb", "origin")
+	result, err := s.origins.Delete(ctx)
 	if err != nil {
 		level.Error(s.logger).Log("method", "DeleteOrigin", "error", err)
 		return 0, err
 	}
+	if result == 0 {
+		return 0, mazeerrors.NewNotFound("origin", "")
+	}
+
+	return result, nil
+}
+
+//GetLocalSpots returns every spot along with its coordinates in the origin's frame
+func (s stubOriginHandler) GetLocalSpots(ctx context.Context) ([]models.LocalSpot, error) {
+
+	origin, err := s.GetOrigin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var resultS []models.Spot
+	if s.index != nil {
+		resultS = s.spotIndex(ctx).All()
+	} else if resultS, err = s.spots.List(ctx); err != nil {
+		level.Error(s.logger).Log("method", "GetLocalSpots", "error", err)
+		return nil, err
+	}
+
+	result := make([]models.LocalSpot, 0, len(resultS))
+	for _, v := range resultS {
+		result = append(result, toLocal(origin, v))
+	}
+	return result, nil
+}
+
+//GetSingleLocalSpot returns one single spot, given its ID, along with its coordinates in the origin's frame
+func (s stubOriginHandler) GetSingleLocalSpot(ctx context.Context, id string) (models.LocalSpot, error) {
+
+	idp, err := mazeerrors.ParseID("id", id)
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetSingleLocalSpot", "error", err)
+		return models.LocalSpot{}, err
+	}
+
+	origin, err := s.GetOrigin(ctx)
+	if err != nil {
+		return models.LocalSpot{}, err
+	}
+
+	spot, err := s.spots.GetByID(ctx, idp)
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetSingleLocalSpot", "error", err)
+		return models.LocalSpot{}, mazeerrors.Missing(err, "spot", id)
+	}
+
+	return toLocal(origin, spot), nil
+}
+
+//GetSpotsInSector gets the spots inside a sector of a ring around the origin, measured in the origin's frame, or in
+//meters along great circles around a geographic origin. They're sorted counterclockwise starting from the sector's
+//first angle, the closest to the origin first on ties
+func (s stubOriginHandler) GetSpotsInSector(ctx context.Context, request models.PolarQuery) ([]models.PolarSpot, error) {
+
+	if request.MinRadius < 0 || request.MaxRadius < 0 {
+		return nil, mazeerrors.NewValidation("Invalid radius: it must not be negative")
+	}
+	if request.MaxRadius > 0 && request.MaxRadius < request.MinRadius {
+		return nil, mazeerrors.NewValidation("Invalid radius: the maximum must not be lower than the minimum")
+	}
+
+	origin, err := s.GetOrigin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	//a bounded ring lets the database discard whatever lies outside the box enclosing it
+	minX, minY, maxX, maxY := math.Inf(-1), math.Inf(-1), math.Inf(1), math.Inf(1)
+	switch {
+	case request.MaxRadius > 0 && origin.Geographic:
+		minX, minY, maxX, maxY = geometry.GeoBox(origin.XOrigin, origin.YOrigin, request.MaxRadius)
+	case request.MaxRadius > 0:
+		xScale, yScale := geometry.Scales(origin)
+		reach := request.MaxRadius * math.Max(xScale, yScale)
+		minX, minY, maxX, maxY = origin.XOrigin-reach, origin.YOrigin-reach, origin.XOrigin+reach, origin.YOrigin+reach
+	}
+	var resultS []models.Spot
+	switch {
+	case s.index != nil:
+		resultS = s.spotIndex(ctx).InBox(minX, minY, maxX, maxY)
+	case request.MaxRadius > 0:
+		resultS, err = s.spots.Find(ctx, db.BoxFilter(minX, minY, maxX, maxY))
+	default:
+		resultS, err = s.spots.List(ctx)
+	}
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetSpotsInSector", "error", err)
+		return nil, err
+	}
+
+	var result []models.PolarSpot
+	for _, v := range resultS {
+		angle, radius := polar(origin, v)
+		if radius < request.MinRadius || (request.MaxRadius > 0 && radius > request.MaxRadius) {
+			continue
+		}
+		if !geometry.InSector(angle, request.FromAngle, request.ToAngle) {
+			continue
+		}
+		result = append(result, models.PolarSpot{Spot: v, Angle: angle, Radius: radius})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		ai := geometry.NormalizeAngle(result[i].Angle - request.FromAngle)
+		aj := geometry.NormalizeAngle(result[j].Angle - request.FromAngle)
+		if ai != aj {
+			return ai < aj
+		}
+		return result[i].Radius < result[j].Radius
+	})
+	return result, nil
+}
+
+//GetQuadrantStats summarizes the spots of each quadrant, and of the axes, in a single pass over the spots. As
+//everywhere else, the spots lying on an axis belong to the quadrants sharing it too
+func (s stubOriginHandler) GetQuadrantStats(ctx context.Context) (map[string]models.SpotStats, error) {
+
+	origin, err := s.GetOrigin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	quadrants := geometry.Quadrants()
+	groups := map[string]*accumulator{Axes: {}}
+	for _, q := range quadrants {
+		groups[q.Name] = &accumulator{}
+	}
+	add := func(v models.Spot) error {
+		x, y := geometry.ToLocal(origin, v.XCoordinate, v.YCoordinate)
+		if x == 0 || y == 0 {
+			groups[Axes].add(v)
+		}
+		for _, q := range quadrants {
+			xSign, ySign := geometry.QuadrantSigns(q.Name)
+			if x*xSign >= 0 && y*ySign >= 0 {
+				groups[q.Name].add(v)
+			}
+		}
+		return nil
+	}
+
+	if s.index != nil {
+		for _, v := range s.spotIndex(ctx).All() {
+			add(v)
+		}
+	} else if err = s.spots.Stream(ctx, bson.M{}, add); err != nil {
+		level.Error(s.logger).Log("method", "GetQuadrantStats", "error", err)
+		return nil, err
+	}
 
+	result := make(map[string]models.SpotStats, len(groups))
+	for name, acc := range groups {
+		result[name] = acc.result()
+	}
 	return result, nil
 }
+
+//GetHull returns the convex hull and extent of every spot or, when a quadrant is given, of the spots in it
+func (s stubOriginHandler) GetHull(ctx context.Context, quadrant string) (models.Hull, error) {
+
+	var spots []models.Spot
+	var err error
+	switch {
+	case quadrant != "":
+		if spots, err = s.GetSpotsInQuadrant(ctx, models.Quadrant{Quadrant: quadrant}); err != nil {
+			return models.Hull{}, err
+		}
+	case s.index != nil:
+		spots = s.spotIndex(ctx).All()
+	default:
+		if spots, err = s.spots.List(ctx); err != nil {
+			level.Error(s.logger).Log("method", "GetHull", "error", err)
+			return models.Hull{}, err
+		}
+	}
+
+	points := make([]models.Point, len(spots))
+	for i, v := range spots {
+		points[i] = models.Point{X: v.XCoordinate, Y: v.YCoordinate}
+	}
+	hull := geometry.ConvexHull(points)
+	if hull == nil {
+		hull = []models.Point{}
+	}
+	result := models.Hull{
+		Count:     len(spots),
+		Polygon:   hull,
+		Area:      geometry.Area(hull),
+		Perimeter: geometry.Perimeter(hull),
+	}
+	if len(hull) > 0 {
+		//the hull's vertices are the extreme points, so they share the spots' extent
+		var e models.Extent
+		e.MinX, e.MinY, e.MaxX, e.MaxY = geometry.Bounds(hull)
+		result.Extent = &e
+	}
+	return result, nil
+}
+
+//SuggestOrigin proposes an origin splitting the spots evenly among the quadrants, keeping the current rotation and
+//scales. The positions are computed in the current origin's frame, so a rotated origin is balanced along its own axes.
+//When applied, the origin is read and written in a single unit of work, and only replaced if it didn't change meanwhile
+func (s stubOriginHandler) SuggestOrigin(ctx context.Context, request models.OriginSuggestionQuery) (models.OriginSuggestion, error) {
+
+	switch request.Strategy {
+	case "", MedianStrategy, BalancedStrategy:
+	default:
+		return models.OriginSuggestion{}, mazeerrors.NewValidation("Invalid strategy: it must be either median or balanced")
+	}
+	switch request.Weight {
+	case "", CountWeight, NumberWeight:
+	default:
+		return models.OriginSuggestion{}, mazeerrors.NewValidation("Invalid weight: it must be either count or number")
+	}
+
+	origins, err := s.origins.List(ctx)
+	if err != nil {
+		level.Error(s.logger).Log("method", "SuggestOrigin", "error", err)
+		return models.OriginSuggestion{}, err
+	}
+	m, err := maze.Get(ctx, s.mazes)
+	if err != nil {
+		level.Error(s.logger).Log("method", "SuggestOrigin", "error", err)
+		return models.OriginSuggestion{}, err
+	}
+	var current models.Origin
+	if len(origins) > 0 {
+		current = origins[0]
+	}
+	current.Geographic = m.Coordinates == models.Geographic
+
+	var spots []models.Spot
+	if s.index != nil {
+		spots = s.spotIndex(ctx).All()
+	} else if spots, err = s.spots.List(ctx); err != nil {
+		level.Error(s.logger).Log("method", "SuggestOrigin", "error", err)
+		return models.OriginSuggestion{}, err
+	}
+	if len(spots) == 0 {
+		return models.OriginSuggestion{}, mazeerrors.NewConflict("There are no spots to suggest an origin for")
+	}
+
+	xs, ys, weights := make([]float64, len(spots)), make([]float64, len(spots)), make([]float64, len(spots))
+	for i, v := range spots {
+		xs[i], ys[i] = geometry.ToLocal(current, v.XCoordinate, v.YCoordinate)
+		weights[i] = weigh(v, request.Weight)
+	}
+	x, y := median(xs), median(ys)
+	if request.Strategy == BalancedStrategy {
+		x, y = balance(xs, ys, weights, x, y)
+	}
+
+	suggested := current
+	suggested.XOrigin, suggested.YOrigin = geometry.ToWorld(current, x, y)
+	quadrants := quadrantWeights(suggested, spots, request.Weight)
+	result := models.OriginSuggestion{
+		Origin:    suggested,
+		Quadrants: quadrants,
+		Imbalance: imbalance(quadrants[geometry.UpperRight], quadrants[geometry.UpperLeft], quadrants[geometry.BottomLeft], quadrants[geometry.BottomRight]),
+	}
+	if !request.Apply {
+		return result, nil
+	}
+
+	//the origin is read again in the unit of work writing it, so the suggestion only replaces the one it was computed from
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.validate(ctx, suggested); err != nil {
+			return err
+		}
+		stored, err := s.origins.List(ctx)
+		if err != nil {
+			level.Error(s.logger).Log("method", "SuggestOrigin", "error", err)
+			return err
+		}
+		switch {
+		case len(stored) != len(origins):
+			return mazeerrors.NewConflict("The origin changed while the suggestion was computed")
+		case len(stored) == 0:
+			_, err = s.CreateOrigin(ctx, suggested)
+			return err
+		case suggested.XOrigin == current.XOrigin && suggested.YOrigin == current.YOrigin:
+			return nil
+		}
+		//without transactions the update still only goes through when the stored origin is the one read at first
+		modified, err := s.origins.Replace(ctx, origins[0], suggested)
+		if err != nil {
+			level.Error(s.logger).Log("method", "SuggestOrigin", "error", err)
+			return err
+		}
+		if modified == 0 {
+			return mazeerrors.NewConflict("The origin changed while the suggestion was computed")
+		}
+		return nil
+	})
+	if err != nil {
+		return models.OriginSuggestion{}, err
+	}
+	result.Applied = true
+	return result, nil
+}
+
+//partition returns the amount of sectors of the request's scheme and the index of the one requested
+func partition(request models.Quadrant) (n, sector int, err error) {
+	if !geometry.IsBoundaryRule(request.Boundary) {
+		return 0, 0, mazeerrors.NewValidation("Invalid boundary: it must be either shared, counterclockwise or clockwise")
+	}
+	switch request.Scheme {
+	case "", geometry.QuadrantScheme:
+		n = 4
+		if request.Sector == nil {
+			if !geometry.IsQuadrant(request.Quadrant) {
+				return 0, 0, mazeerrors.NewNotFound("quadrant", request.Quadrant)
+			}
+			return n, geometry.QuadrantSector(request.Quadrant), nil
+		}
+	case geometry.OctantScheme:
+		n = 8
+	case geometry.SectorScheme:
+		if request.Sectors < 1 {
+			return 0, 0, mazeerrors.NewValidation("Invalid sectors: there must be at least one")
+		}
+		n = request.Sectors
+	default:
+		return 0, 0, mazeerrors.NewValidation("Invalid scheme: it must be either quadrants, octants or sectors")
+	}
+	if request.Sector == nil || *request.Sector < 0 || *request.Sector >= n {
+		return 0, 0, mazeerrors.NewValidation("Invalid sector: it must be between zero and the amount of sectors minus one")
+	}
+	return n, *request.Sector, nil
+}
+
+//toLocal attaches to the spot its coordinates in the origin's frame
+//polar returns the angle and radius of the spot around the origin. Around a geographic origin the radius is the
+//great-circle distance in meters, and the angle the one the great circle to the spot leaves the origin at
+func polar(origin models.Origin, spot models.Spot) (angle, radius float64) {
+	if origin.Geographic {
+		return geometry.GeoAngle(origin.XOrigin, origin.YOrigin, spot.XCoordinate, spot.YCoordinate),
+			geometry.GreatCircle(origin.XOrigin, origin.YOrigin, spot.XCoordinate, spot.YCoordinate)
+	}
+	local := toLocal(origin, spot)
+	return geometry.Polar(local.LocalX, local.LocalY)
+}
+
+func toLocal(origin models.Origin, spot models.Spot) models.LocalSpot {
+	x, y := geometry.ToLocal(origin, spot.XCoordinate, spot.YCoordinate)
+	return models.LocalSpot{Spot: spot, LocalX: x, LocalY: y}
+}
+
+//validate checks that the origin's axes can be scaled, and that the origin makes sense in the maze
+func (s stubOriginHandler) validate(ctx context.Context, origin models.Origin) error {
+	if origin.XScale < 0 || origin.YScale < 0 {
+		return mazeerrors.NewValidation("The origin's scales must be positive")
+	}
+	m, err := maze.Get(ctx, s.mazes)
+	if err != nil {
+		return err
+	}
+	return maze.ValidateOrigin(m, origin)
+}
@@ -0,0 +1,361 @@
+package quadrant
+
+import (
+	"context"
+	"github.com/avanticaTest/maze/pkg/db"
+	mazeerrors "github.com/avanticaTest/maze/pkg/errors"
+	"github.com/avanticaTest/maze/pkg/index"
+	"github.com/avanticaTest/maze/pkg/models"
+	"github.com/avanticaTest/maze/pkg/service/maze"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gotest.tools/v3/assert"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestGetQuadrantStats(t *testing.T) {
+
+	ctx := context.Background()
+	m := &db.Mock{}
+	m.On("FindOrigin", ctx, "mazedb", "origin", mock.Anything).Return([]models.Origin{{XOrigin: 1, YOrigin: 1}}, nil)
+	m.On("StreamSpots", ctx, "mazedb", "spots", mock.Anything).Return([]models.Spot{
+		{XCoordinate: -3, YCoordinate: 5, Number: 600},
+		{XCoordinate: 0, YCoordinate: 3, Number: 300},
+		{XCoordinate: 1, YCoordinate: 4, Number: 100},
+		{XCoordinate: 5, YCoordinate: -2, Number: 50},
+	}, nil)
+	s := New(log.NewNopLogger(), db.NewOriginRepository(m, "mazedb", db.OriginCollection), db.NewSpotRepository(m, "mazedb", db.SpotsCollection), nil, nil, nil, nil)
+
+	resp, err := s.GetQuadrantStats(ctx)
+
+	assert.NilError(t, err)
+	assert.DeepEqual(t, models.SpotStats{
+		Count:       3,
+		NumberSum:   1000,
+		NumberAvg:   1000.0 / 3,
+		NumberMin:   100,
+		NumberMax:   600,
+		Centroid:    &models.Point{X: -2.0 / 3, Y: 4},
+		BoundingBox: &models.Extent{MinX: -3, MinY: 3, MaxX: 1, MaxY: 5},
+	}, resp["upper_left"])
+	assert.Equal(t, 1, resp["upper_right"].Count)
+	assert.Equal(t, 1, resp["bottom_right"].Count)
+	assert.DeepEqual(t, models.SpotStats{}, resp["bottom_left"])
+	assert.Equal(t, 100, resp[Axes].NumberSum)
+}
+
+func TestGetSpotsInSector(t *testing.T) {
+
+	stored := []models.Spot{
+		{Name: "inside", XCoordinate: 3, YCoordinate: 2},
+		{Name: "diagonal", XCoordinate: 2, YCoordinate: 2},
+		{Name: "axis", XCoordinate: 3, YCoordinate: 0},
+		{Name: "upstairs", XCoordinate: 3, YCoordinate: 1, ZCoordinate: 1},
+	}
+	zero, one, ground := 0, 1, 0.0
+
+	tests := []struct {
+		name          string
+		request       models.Quadrant
+		expectedNames []string
+		expectedErr   string
+	}{
+		{
+			name:          "Shared boundaries",
+			request:       models.Quadrant{Scheme: "octants", Sector: &zero},
+			expectedNames: []string{"inside", "diagonal", "axis", "upstairs"},
+		},
+		{
+			name:          "Counterclockwise on the ground floor",
+			request:       models.Quadrant{Scheme: "octants", Sector: &zero, Boundary: "counterclockwise", Floor: &ground},
+			expectedNames: []string{"inside", "axis"},
+		},
+		{
+			name:          "Clockwise",
+			request:       models.Quadrant{Scheme: "octants", Sector: &one, Boundary: "clockwise"},
+			expectedNames: nil,
+		},
+		{
+			name:          "Quadrant by name, clockwise",
+			request:       models.Quadrant{Quadrant: "bottom_right", Boundary: "clockwise"},
+			expectedNames: []string{"axis"},
+		},
+		{
+			name:        "Sector out of range",
+			request:     models.Quadrant{Scheme: "sectors", Sectors: 1, Sector: &one},
+			expectedErr: "Invalid sector: it must be between zero and the amount of sectors minus one",
+		},
+		{
+			name:        "Unknown boundary",
+			request:     models.Quadrant{Quadrant: "upper_left", Boundary: "nearest"},
+			expectedErr: "Invalid boundary: it must be either shared, counterclockwise or clockwise",
+		},
+	}
+
+	ctx := context.Background()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			m := &db.Mock{}
+			m.On("FindOrigin", ctx, "mazedb", "origin", mock.Anything).Return([]models.Origin{{}}, nil)
+			m.On("StreamSpots", ctx, "mazedb", "spots", mock.Anything).Return(stored, nil)
+			s := New(log.NewNopLogger(), db.NewOriginRepository(m, "mazedb", db.OriginCollection), db.NewSpotRepository(m, "mazedb", db.SpotsCollection), nil, nil, nil, nil)
+
+			resp, err := s.GetSpotsInQuadrant(ctx, tt.request)
+
+			if tt.expectedErr != "" {
+				assert.Error(t, err, tt.expectedErr)
+				return
+			}
+			assert.NilError(t, err)
+			var names []string
+			for _, v := range resp {
+				names = append(names, v.Name)
+			}
+			assert.DeepEqual(t, tt.expectedNames, names)
+		})
+	}
+}
+
+func TestGeographicSector(t *testing.T) {
+
+	//the origin lies a degree from the antimeridian, so the spot across it is east of the origin and closer than the
+	//one past the ring though its longitude is the farthest from the origin's
+	stored := []models.Spot{
+		{Name: "across", XCoordinate: -179.5},
+		{Name: "west", XCoordinate: 178.5},
+		{Name: "beyond", XCoordinate: -170},
+	}
+
+	for _, indexed := range []bool{false, true} {
+		store := db.NewMemoryStore()
+		mazes := maze.New(log.NewNopLogger(), db.NewMemoryMazeRepository(store), nil, nil, nil, nil, nil, nil)
+		id, err := mazes.CreateMaze(context.Background(), models.Maze{Name: "earth", Coordinates: models.Geographic})
+		assert.NilError(t, err)
+		mazeID, err := primitive.ObjectIDFromHex(id)
+		assert.NilError(t, err)
+		ctx := db.WithMaze(context.Background(), mazeID)
+
+		spots := db.NewMemorySpotRepository(store)
+		for _, v := range stored {
+			_, err := spots.Create(ctx, v)
+			assert.NilError(t, err)
+		}
+		var ix *index.Mazes
+		mode := "Database"
+		if indexed {
+			all, err := spots.List(ctx)
+			assert.NilError(t, err)
+			ix = index.NewMazes()
+			ix.Load(all)
+			mode = "Index"
+		}
+		s := New(log.NewNopLogger(), db.NewMemoryOriginRepository(store), spots, nil, ix, mazes, nil)
+		_, err = s.CreateOrigin(ctx, models.Origin{XOrigin: 179})
+		assert.NilError(t, err)
+
+		t.Run(mode+"/Ring in meters", func(t *testing.T) {
+			resp, err := s.GetSpotsInSector(ctx, models.PolarQuery{ToAngle: 360, MaxRadius: 200000})
+			assert.NilError(t, err)
+			assert.Equal(t, 2, len(resp))
+			assert.Equal(t, "across", resp[0].Name)
+			assert.Assert(t, math.Abs(resp[0].Angle) < 1e-9)
+			assert.Assert(t, math.Abs(resp[0].Radius-166792) < 1)
+			assert.Equal(t, "west", resp[1].Name)
+			assert.Assert(t, math.Abs(resp[1].Angle-180) < 1e-9)
+			assert.Assert(t, math.Abs(resp[1].Radius-55597) < 1)
+		})
+		t.Run(mode+"/Sector across the antimeridian", func(t *testing.T) {
+			resp, err := s.GetSpotsInSector(ctx, models.PolarQuery{FromAngle: 350, ToAngle: 10, MinRadius: 100000})
+			assert.NilError(t, err)
+			var names []string
+			for _, v := range resp {
+				names = append(names, v.Name)
+			}
+			assert.DeepEqual(t, []string{"across", "beyond"}, names)
+		})
+	}
+}
+
+func TestMissingOrigin(t *testing.T) {
+
+	ctx := db.WithMaze(context.Background(), primitive.NewObjectID())
+	s := New(log.NewNopLogger(), db.NewMemoryOriginRepository(db.NewMemoryStore()), nil, nil, nil, nil, nil)
+
+	_, err := s.ModifyOrigin(ctx, models.Origin{XOrigin: 1})
+	assert.Equal(t, mazeerrors.NewNotFound("origin", ""), err)
+	_, err = s.DeleteOrigin(ctx)
+	assert.Equal(t, mazeerrors.NewNotFound("origin", ""), err)
+
+	_, err = s.CreateOrigin(ctx, models.Origin{XOrigin: 1})
+	assert.NilError(t, err)
+	modified, err := s.ModifyOrigin(ctx, models.Origin{XOrigin: 1})
+	assert.NilError(t, err)
+	assert.Equal(t, 0, modified)
+	deleted, err := s.DeleteOrigin(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, deleted)
+}
+
+func TestSuggestOrigin(t *testing.T) {
+
+	stored := []models.Spot{
+		{XCoordinate: 10, Number: 6},
+		{XCoordinate: 11, Number: 1},
+		{XCoordinate: 12, Number: 1},
+		{XCoordinate: 13, Number: 1},
+	}
+
+	tests := []struct {
+		name              string
+		request           models.OriginSuggestionQuery
+		origins           []models.Origin
+		modified          int
+		expectedX         float64
+		expectedImbalance float64
+		expectedApplied   bool
+		expectedErr       string
+	}{
+		{
+			name:      "Median",
+			request:   models.OriginSuggestionQuery{},
+			expectedX: 11.5,
+		},
+		{
+			name:      "Balanced counts",
+			request:   models.OriginSuggestionQuery{Strategy: "balanced"},
+			origins:   []models.Origin{{XOrigin: 1, YOrigin: 0}},
+			expectedX: 11.5,
+		},
+		{
+			name:              "Balanced numbers",
+			request:           models.OriginSuggestionQuery{Strategy: "balanced", Weight: "number"},
+			expectedX:         10.5,
+			expectedImbalance: 3,
+		},
+		{
+			name:            "Applied",
+			request:         models.OriginSuggestionQuery{Apply: true},
+			origins:         []models.Origin{{XOrigin: 1, YOrigin: 0}},
+			modified:        1,
+			expectedX:       11.5,
+			expectedApplied: true,
+		},
+		{
+			name:        "Origin changed meanwhile",
+			request:     models.OriginSuggestionQuery{Apply: true},
+			origins:     []models.Origin{{XOrigin: 1, YOrigin: 0}},
+			expectedErr: "The origin changed while the suggestion was computed",
+		},
+		{
+			name:        "Unknown strategy",
+			request:     models.OriginSuggestionQuery{Strategy: "mean"},
+			expectedErr: "Invalid strategy: it must be either median or balanced",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+
+			ctx := context.Background()
+			m := &db.Mock{}
+			m.On("FindOrigin", ctx, "mazedb", "origin", mock.Anything).Return(tc.origins, nil)
+			m.On("FindSpotsByFilter", ctx, "mazedb", "spots", mock.Anything).Return(stored, nil)
+			m.On("UpdateOne", ctx, mock.Anything, mock.Anything, "mazedb", "origin").Return(tc.modified, nil)
+			s := New(log.NewNopLogger(), db.NewOriginRepository(m, "mazedb", db.OriginCollection), db.NewSpotRepository(m, "mazedb", db.SpotsCollection), nil, nil, nil, nil)
+
+			resp, err := s.SuggestOrigin(ctx, tc.request)
+
+			if tc.expectedErr != "" {
+				assert.Error(t, err, tc.expectedErr)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, tc.expectedX, resp.Origin.XOrigin)
+			assert.Equal(t, 0.0, resp.Origin.YOrigin)
+			assert.Equal(t, tc.expectedImbalance, resp.Imbalance)
+			assert.Equal(t, tc.expectedApplied, resp.Applied)
+		})
+	}
+}
+
+func TestCreateOriginConcurrently(t *testing.T) {
+
+	ctx := db.WithMaze(context.Background(), primitive.NewObjectID())
+	origins := db.NewMemoryOriginRepository(db.NewMemoryStore())
+	s := New(log.NewNopLogger(), origins, nil, nil, nil, nil, nil)
+
+	const creates = 20
+	errs := make(chan error, creates)
+	for i := 0; i < creates; i++ {
+		go func(i int) {
+			_, err := s.CreateOrigin(ctx, models.Origin{XOrigin: float64(i + 1)})
+			errs <- err
+		}(i)
+	}
+
+	created := 0
+	for i := 0; i < creates; i++ {
+		err := <-errs
+		if err == nil {
+			created++
+			continue
+		}
+		assert.Equal(t, mazeerrors.NewConflict("There can be only one Origin"), err)
+	}
+	assert.Equal(t, 1, created)
+
+	result, err := origins.List(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(result))
+}
+
+//scattered returns the coordinates of n spots of distinct coordinates, bunched on the lower left
+func scattered(n int) (xs, ys, weights []float64) {
+	r := rand.New(rand.NewSource(1))
+	xs, ys, weights = make([]float64, n), make([]float64, n), make([]float64, n)
+	for i := range xs {
+		xs[i], ys[i], weights[i] = math.Pow(r.Float64(), 2)*1000, math.Pow(r.Float64(), 2)*1000, 1
+	}
+	return xs, ys, weights
+}
+
+func TestBalanceLargeMaze(t *testing.T) {
+
+	xs, ys, weights := scattered(20000)
+	x, y := median(xs), median(ys)
+	spread := func(x, y float64) float64 {
+		var ul, ur, bl, br float64
+		for i := range xs {
+			switch {
+			case xs[i] < x && ys[i] >= y:
+				ul += weights[i]
+			case xs[i] >= x && ys[i] >= y:
+				ur += weights[i]
+			case xs[i] < x:
+				bl += weights[i]
+			default:
+				br += weights[i]
+			}
+		}
+		return imbalance(ul, ur, bl, br)
+	}
+
+	//only some of the candidates are tried, the median being one of them, so the suggestion is never worse than it
+	bx, by := balance(xs, ys, weights, x, y)
+	assert.Assert(t, spread(bx, by) <= spread(x, y))
+}
+
+func BenchmarkBalance(b *testing.B) {
+
+	xs, ys, weights := scattered(200000)
+	x, y := median(xs), median(ys)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		balance(xs, ys, weights, x, y)
+	}
+}
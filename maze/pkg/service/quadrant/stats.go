@@ -0,0 +1,49 @@
+package quadrant
+
+import (
+	"github.com/avanticaTest/maze/pkg/models"
+	"math"
+)
+
+//Axes is the name of the group of spots lying on the origin's axes in the quadrant statistics
+const Axes = "axes"
+
+//accumulator builds the statistics of a group of spots in a single pass
+type accumulator struct {
+	stats  models.SpotStats
+	sumX   float64
+	sumY   float64
+	extent models.Extent
+}
+
+func (a *accumulator) add(spot models.Spot) {
+	if a.stats.Count == 0 {
+		a.stats.NumberMin, a.stats.NumberMax = spot.Number, spot.Number
+		a.extent = models.Extent{MinX: spot.XCoordinate, MinY: spot.YCoordinate, MaxX: spot.XCoordinate, MaxY: spot.YCoordinate}
+	}
+	a.stats.Count++
+	a.stats.NumberSum += spot.Number
+	if spot.Number < a.stats.NumberMin {
+		a.stats.NumberMin = spot.Number
+	}
+	if spot.Number > a.stats.NumberMax {
+		a.stats.NumberMax = spot.Number
+	}
+	a.sumX += spot.XCoordinate
+	a.sumY += spot.YCoordinate
+	a.extent.MinX, a.extent.MaxX = math.Min(a.extent.MinX, spot.XCoordinate), math.Max(a.extent.MaxX, spot.XCoordinate)
+	a.extent.MinY, a.extent.MaxY = math.Min(a.extent.MinY, spot.YCoordinate), math.Max(a.extent.MaxY, spot.YCoordinate)
+}
+
+func (a *accumulator) result() models.SpotStats {
+	stats := a.stats
+	if stats.Count == 0 {
+		return stats
+	}
+	n := float64(stats.Count)
+	extent := a.extent
+	stats.NumberAvg = float64(stats.NumberSum) / n
+	stats.Centroid = &models.Point{X: a.sumX / n, Y: a.sumY / n}
+	stats.BoundingBox = &extent
+	return stats
+}
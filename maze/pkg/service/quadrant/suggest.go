@@ -0,0 +1,205 @@
+package quadrant
+
+import (
+	"github.com/avanticaTest/maze/pkg/geometry"
+	"github.com/avanticaTest/maze/pkg/models"
+	"math"
+	"sort"
+)
+
+//Strategies to suggest an origin with
+const (
+	MedianStrategy   = "median"
+	BalancedStrategy = "balanced"
+)
+
+//What the quadrants weigh when balancing them
+const (
+	CountWeight  = "count"
+	NumberWeight = "number"
+)
+
+//weigh returns what the spot adds to the weight of its quadrants
+func weigh(spot models.Spot, weight string) float64 {
+	if weight == NumberWeight {
+		return float64(spot.Number)
+	}
+	return 1
+}
+
+//quadrantWeights returns the weight of each quadrant around the origin. Spots on an axis weigh in both quadrants
+//sharing it, as everywhere else
+func quadrantWeights(origin models.Origin, spots []models.Spot, weight string) map[string]float64 {
+	quadrants := geometry.Quadrants()
+	result := make(map[string]float64, len(quadrants))
+	for _, q := range quadrants {
+		result[q.Name] = 0
+	}
+	for _, v := range spots {
+		x, y := geometry.ToLocal(origin, v.XCoordinate, v.YCoordinate)
+		for _, q := range quadrants {
+			xSign, ySign := geometry.QuadrantSigns(q.Name)
+			if x*xSign >= 0 && y*ySign >= 0 {
+				result[q.Name] += weigh(v, weight)
+			}
+		}
+	}
+	return result
+}
+
+//imbalance is the spread between the heaviest and the lightest quadrant
+func imbalance(weights ...float64) float64 {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, w := range weights {
+		min, max = math.Min(min, w), math.Max(max, w)
+	}
+	return max - min
+}
+
+//median returns the median of the values, the mean of the two middle ones when there's an even amount of them
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+//split is a candidate position for an axis: the values ranked up to hi lie below it and the ones ranked from lo on lie
+//above it. Between two values hi is lo minus one; on the only value there is, both sides take it
+type split struct {
+	at     float64
+	lo, hi int
+}
+
+//splits returns the candidate positions for an axis over the distinct values: halfway between every two consecutive
+//ones, or the value itself when there's only one. Every other position splits the values like one of these
+func splits(distinct []float64) []split {
+	if len(distinct) == 1 {
+		return []split{{at: distinct[0], lo: 0, hi: 0}}
+	}
+	result := make([]split, len(distinct)-1)
+	for i := range result {
+		result[i] = split{at: (distinct[i] + distinct[i+1]) / 2, lo: i + 1, hi: i}
+	}
+	return result
+}
+
+//maxSplits caps the candidate positions tried for each axis, so balancing stays fast on the largest mazes
+const maxSplits = 256
+
+//sample keeps at most maxSplits of the axis' splits, at the quantiles of the values so the candidates are the
+//densest where the spots are, along with the split closest to tie. The splits kept stay sorted
+func sample(all []split, ranks []int, tie float64) []split {
+	if len(all) <= maxSplits {
+		return all
+	}
+	//cumulative[i] is the amount of values ranked up to i, which is what lies below the split with hi i
+	cumulative := make([]int, len(all)+1)
+	for _, r := range ranks {
+		cumulative[r]++
+	}
+	for i := 1; i < len(cumulative); i++ {
+		cumulative[i] += cumulative[i-1]
+	}
+
+	keep := make(map[int]bool, maxSplits)
+	for q := 1; q < maxSplits; q++ {
+		target := q * len(ranks) / maxSplits
+		i := sort.SearchInts(cumulative[:len(all)], target)
+		if i == len(all) {
+			i--
+		}
+		keep[i] = true
+	}
+	closest := sort.Search(len(all), func(i int) bool { return all[i].at >= tie })
+	if closest == len(all) || (closest > 0 && tie-all[closest-1].at < all[closest].at-tie) {
+		closest--
+	}
+	keep[closest] = true
+
+	result := make([]split, 0, len(keep))
+	for i, v := range all {
+		if keep[i] {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+//rank returns the distinct values, sorted, and the rank of each value among them
+func rank(values []float64) (distinct []float64, ranks []int) {
+	distinct = append([]float64(nil), values...)
+	sort.Float64s(distinct)
+	n := 0
+	for i, v := range distinct {
+		if i == 0 || v != distinct[n-1] {
+			distinct[n] = v
+			n++
+		}
+	}
+	distinct = distinct[:n]
+	ranks = make([]int, len(values))
+	for i, v := range values {
+		ranks[i] = sort.SearchFloat64s(distinct, v)
+	}
+	return distinct, ranks
+}
+
+//balance returns the point, given like the coordinates, minimizing the spread between the heaviest and lightest
+//quadrant. Ties go to the candidate closest to (tieX, tieY). Only the positions splitting the spots differently are
+//tried, and on large mazes only the ones at the quantiles of each axis and the ones closest to the tie, so it takes
+//time proportional to maxSplits times the amount of distinct y coordinates at most
+func balance(xs, ys, weights []float64, tieX, tieY float64) (x, y float64) {
+	distinctX, rankX := rank(xs)
+	distinctY, rankY := rank(ys)
+
+	//the spots are swept from left to right, the weight of each row of the ones already swept being kept in left
+	order := make([]int, len(xs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return rankX[order[i]] < rankX[order[j]] })
+	row := make([]float64, len(distinctY))
+	for i, w := range weights {
+		row[rankY[i]] += w
+	}
+	left := make([]float64, len(distinctY))
+	leftBelow := make([]float64, len(distinctY))
+	rightBelow := make([]float64, len(distinctY))
+
+	best, bestDistance := math.Inf(1), math.Inf(1)
+	next := 0
+	ySplits := sample(splits(distinctY), rankY, tieY)
+	for _, sx := range sample(splits(distinctX), rankX, tieX) {
+		for ; next < len(order) && rankX[order[next]] <= sx.hi; next++ {
+			left[rankY[order[next]]] += weights[order[next]]
+		}
+		var leftSum, rightSum float64
+		for j := range row {
+			right := row[j]
+			if sx.lo > 0 {
+				right -= left[j]
+			}
+			leftSum += left[j]
+			rightSum += right
+			leftBelow[j], rightBelow[j] = leftSum, rightSum
+		}
+
+		for _, sy := range ySplits {
+			var leftUnder, rightUnder float64
+			if sy.lo > 0 {
+				leftUnder, rightUnder = leftBelow[sy.lo-1], rightBelow[sy.lo-1]
+			}
+			spread := imbalance(leftBelow[sy.hi], rightBelow[sy.hi], leftSum-leftUnder, rightSum-rightUnder)
+			distance := math.Hypot(sx.at-tieX, sy.at-tieY)
+			if spread < best || (spread == best && distance < bestDistance) {
+				best, bestDistance = spread, distance
+				x, y = sx.at, sy.at
+			}
+		}
+	}
+	return x, y
+}
@@ -0,0 +1,149 @@
+package spot
+
+import (
+	mazeerrors "github.com/avanticaTest/maze/pkg/errors"
+	"github.com/avanticaTest/maze/pkg/models"
+	"math"
+	"sort"
+)
+
+//MaxGridCells caps the size of a grid, so a tiny cell size can't exhaust the memory
+const MaxGridCells = 1 << 20
+
+type cellKey struct {
+	column, row int
+}
+
+//binner counts the spots falling in each cell of a grid, one spot at a time
+type binner struct {
+	query models.GridQuery
+	//anchor is the lower left corner of the cell (0, 0)
+	anchorX, anchorY float64
+	columns, rows    int
+	cells            map[cellKey]*models.GridCell
+}
+
+func newBinner(query models.GridQuery) (*binner, error) {
+	if !(query.CellSize > 0) || math.IsInf(query.CellSize, 0) {
+		return nil, mazeerrors.NewValidation("Invalid cell size: it must be positive")
+	}
+	b := &binner{query: query, cells: make(map[cellKey]*models.GridCell)}
+	if query.Bounds == nil {
+		return b, nil
+	}
+	bounds := *query.Bounds
+	if bounds.MinX > bounds.MaxX || bounds.MinY > bounds.MaxY {
+		return nil, mazeerrors.NewValidation("Invalid bounds: min coordinates must not exceed max coordinates")
+	}
+	b.anchorX, b.anchorY = bounds.MinX, bounds.MinY
+	b.columns = int(math.Max(1, math.Ceil((bounds.MaxX-bounds.MinX)/query.CellSize)))
+	b.rows = int(math.Max(1, math.Ceil((bounds.MaxY-bounds.MinY)/query.CellSize)))
+	if float64(b.columns)*float64(b.rows) > MaxGridCells {
+		return nil, errTooManyCells
+	}
+	return b, nil
+}
+
+//errTooManyCells rejects the grids too big to be counted
+var errTooManyCells = mazeerrors.NewValidation("Too many cells: use a bigger cell size or smaller bounds")
+
+//add counts the spot in its cell. Spots so far apart their cells can't be indexed are rejected, as the grid holding
+//them would be too big anyway
+func (b *binner) add(spot models.Spot) error {
+	if b.query.Bounds != nil {
+		bounds := b.query.Bounds
+		if spot.XCoordinate < bounds.MinX || spot.XCoordinate > bounds.MaxX ||
+			spot.YCoordinate < bounds.MinY || spot.YCoordinate > bounds.MaxY {
+			return nil
+		}
+	}
+	column, err := b.index(spot.XCoordinate, b.anchorX)
+	if err != nil {
+		return err
+	}
+	row, err := b.index(spot.YCoordinate, b.anchorY)
+	if err != nil {
+		return err
+	}
+	if b.query.Bounds != nil {
+		//spots on the max borders belong to the last cells
+		column, row = minInt(column, b.columns-1), minInt(row, b.rows-1)
+	}
+	key := cellKey{column, row}
+	cell, ok := b.cells[key]
+	if !ok {
+		cell = &models.GridCell{Column: column, Row: row}
+		b.cells[key] = cell
+	}
+	cell.Count++
+	cell.NumberSum += spot.Number
+	return nil
+}
+
+//index returns the index of the cell the coordinate falls in, counted from the anchor's. It's kept within the int32
+//range, so the extent of the grid can't overflow
+func (b *binner) index(coordinate, anchor float64) (int, error) {
+	i := math.Floor((coordinate - anchor) / b.query.CellSize)
+	if !(i >= math.MinInt32 && i <= math.MaxInt32) {
+		return 0, errTooManyCells
+	}
+	return int(i), nil
+}
+
+//grid returns the binned cells. Without bounds, the grid is shrunk to the cells holding spots
+func (b *binner) grid() (models.Grid, error) {
+	minColumn, minRow := 0, 0
+	columns, rows := b.columns, b.rows
+	if b.query.Bounds == nil && len(b.cells) > 0 {
+		minColumn, minRow = math.MaxInt32, math.MaxInt32
+		maxColumn, maxRow := math.MinInt32, math.MinInt32
+		for k := range b.cells {
+			minColumn, maxColumn = minInt(minColumn, k.column), maxInt(maxColumn, k.column)
+			minRow, maxRow = minInt(minRow, k.row), maxInt(maxRow, k.row)
+		}
+		columns, rows = maxColumn-minColumn+1, maxRow-minRow+1
+		if float64(columns)*float64(rows) > MaxGridCells {
+			return models.Grid{}, errTooManyCells
+		}
+	}
+
+	size := b.query.CellSize
+	minX := b.anchorX + float64(minColumn)*size
+	minY := b.anchorY + float64(minRow)*size
+	grid := models.Grid{
+		CellSize: size,
+		Bounds:   models.Extent{MinX: minX, MinY: minY, MaxX: minX + float64(columns)*size, MaxY: minY + float64(rows)*size},
+		Columns:  columns,
+		Rows:     rows,
+		Cells:    make([]models.GridCell, 0, len(b.cells)),
+	}
+	for _, c := range b.cells {
+		cell := *c
+		cell.Column -= minColumn
+		cell.Row -= minRow
+		cell.MinX = minX + float64(cell.Column)*size
+		cell.MinY = minY + float64(cell.Row)*size
+		grid.Cells = append(grid.Cells, cell)
+	}
+	sort.Slice(grid.Cells, func(i, j int) bool {
+		if grid.Cells[i].Row != grid.Cells[j].Row {
+			return grid.Cells[i].Row < grid.Cells[j].Row
+		}
+		return grid.Cells[i].Column < grid.Cells[j].Column
+	})
+	return grid, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,67 @@
+package spot
+
+import (
+	mazeerrors "github.com/avanticaTest/maze/pkg/errors"
+	"github.com/avanticaTest/maze/pkg/models"
+	"gotest.tools/v3/assert"
+	"testing"
+)
+
+func TestBinner(t *testing.T) {
+
+	spots := []models.Spot{
+		{XCoordinate: -5, YCoordinate: 1, Number: 10},
+		{XCoordinate: -1, YCoordinate: 9, Number: 20},
+		{XCoordinate: 12, YCoordinate: 3, Number: 30},
+		{XCoordinate: 20, YCoordinate: 20, Number: 40},
+	}
+
+	t.Run("Aligned on the cell size", func(t *testing.T) {
+		b, err := newBinner(models.GridQuery{CellSize: 10})
+		assert.NilError(t, err)
+		for _, v := range spots {
+			assert.NilError(t, b.add(v))
+		}
+		grid, err := b.grid()
+		assert.NilError(t, err)
+		assert.DeepEqual(t, models.Extent{MinX: -10, MinY: 0, MaxX: 30, MaxY: 30}, grid.Bounds)
+		assert.Equal(t, 4, grid.Columns)
+		assert.Equal(t, 3, grid.Rows)
+		assert.DeepEqual(t, []models.GridCell{
+			{Column: 0, Row: 0, MinX: -10, MinY: 0, Count: 2, NumberSum: 30},
+			{Column: 2, Row: 0, MinX: 10, MinY: 0, Count: 1, NumberSum: 30},
+			{Column: 3, Row: 2, MinX: 20, MinY: 20, Count: 1, NumberSum: 40},
+		}, grid.Cells)
+	})
+
+	t.Run("Bounded", func(t *testing.T) {
+		b, err := newBinner(models.GridQuery{CellSize: 10, Bounds: &models.Extent{MinX: -5, MinY: 0, MaxX: 15, MaxY: 20}})
+		assert.NilError(t, err)
+		for _, v := range spots {
+			assert.NilError(t, b.add(v))
+		}
+		grid, err := b.grid()
+		assert.NilError(t, err)
+		assert.Equal(t, 2, grid.Columns)
+		assert.Equal(t, 2, grid.Rows)
+		assert.DeepEqual(t, []models.GridCell{
+			{Column: 0, Row: 0, MinX: -5, MinY: 0, Count: 2, NumberSum: 30},
+			{Column: 1, Row: 0, MinX: 5, MinY: 0, Count: 1, NumberSum: 30},
+		}, grid.Cells)
+	})
+
+	t.Run("Too many cells", func(t *testing.T) {
+		_, err := newBinner(models.GridQuery{CellSize: 0.001, Bounds: &models.Extent{MaxX: 100, MaxY: 100}})
+		assert.Error(t, err, "Too many cells: use a bigger cell size or smaller bounds")
+	})
+
+	t.Run("Cells too far apart", func(t *testing.T) {
+		b, err := newBinner(models.GridQuery{CellSize: 1})
+		assert.NilError(t, err)
+		assert.NilError(t, b.add(models.Spot{}))
+		err = b.add(models.Spot{XCoordinate: 1e20})
+		assert.Error(t, err, "Too many cells: use a bigger cell size or smaller bounds")
+		_, validation := err.(mazeerrors.Validation)
+		assert.Assert(t, validation)
+	})
+}
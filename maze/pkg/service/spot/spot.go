@@ -2,12 +2,20 @@ package spot
 
 import (
 	"context"
+	"github.com/avanticaTest/maze/pkg/cluster"
 	"github.com/avanticaTest/maze/pkg/db"
+	mazeerrors "github.com/avanticaTest/maze/pkg/errors"
+	"github.com/avanticaTest/maze/pkg/geometry"
+	"github.com/avanticaTest/maze/pkg/index"
 	"github.com/avanticaTest/maze/pkg/models"
+	"github.com/avanticaTest/maze/pkg/service/maze"
+	"github.com/avanticaTest/maze/pkg/service/path"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"sort"
 )
 
 type SpotHandler interface {
@@ -16,36 +24,90 @@ type SpotHandler interface {
 	ModifySpot(ctx context.Context, request models.Spot, id string) (int, error)
 	GetSpots(ctx context.Context) ([]models.Spot, error)
 	DeleteSpot(ctx context.Context, id string) (int, error)
+	GetSpotsInBox(ctx context.Context, request models.BoxQuery) ([]models.Spot, error)
+	GetSpotsInRadius(ctx context.Context, request models.RadiusQuery) ([]models.Spot, error)
+	GetNearestSpots(ctx context.Context, request models.NearestQuery) ([]models.Spot, error)
+	GetSpotGrid(ctx context.Context, request models.GridQuery) (models.Grid, error)
+	ClusterSpots(ctx context.Context, request models.ClusterQuery) (models.Clustering, error)
+	GetSpotsInCluster(ctx context.Context, label int) ([]models.Spot, error)
+}
+
+//ZoneLocator finds the zones a spot lies in
+type ZoneLocator interface {
+	GetZonesContainingSpot(ctx context.Context, spot models.Spot) ([]string, error)
 }
 
 type stubSpotHandler struct {
-	db     db.DBManager
-	logger log.Logger
+	spots    db.SpotRepository
+	paths    db.PathRepository
+	deletion string
+	zones    ZoneLocator
+	index    *index.Mazes
+	mazes    maze.MazeHandler
+	tx       db.Transactor
+	logger   log.Logger
 }
 
-func New(logger log.Logger, db db.DBManager) SpotHandler {
+//New creates the spot service. The index is optional: when given, it's kept in sync with every committed change made to
+//the spots and it serves the spatial queries of each maze instead of the database. Without a maze service the maze is
+//cartesian. Deletion decides what happens to the paths of a deleted spot, restrict by default
+func New(logger log.Logger, spots db.SpotRepository, paths db.PathRepository, deletion string, zones ZoneLocator, index *index.Mazes, mazes maze.MazeHandler, tx db.Transactor) SpotHandler {
+	if tx == nil {
+		tx = db.NoTransactions()
+	}
 	return stubSpotHandler{
-		db:     db,
-		logger: logger,
+		spots:    spots,
+		paths:    paths,
+		deletion: deletion,
+		zones:    zones,
+		index:    index,
+		mazes:    mazes,
+		tx:       tx,
+		logger:   logger,
 	}
 }
 
+//spotIndex returns the index of the context's maze
+func (s stubSpotHandler) spotIndex(ctx context.Context) *index.SpotIndex {
+	maze, _ := db.MazeFrom(ctx)
+	return s.index.Of(maze)
+}
+
 //CreateSpot creates a spot given its name, number, and coordinates. It returns the ID of the Spot Created
 func (s stubSpotHandler) CreateSpot(ctx context.Context, request models.Spot) (string, error) {
 
-	result, err := s.db.InsertOne(ctx, "mazedb", "spots", request)
+	var result string
+	err := s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.validate(ctx, request); err != nil {
+			return err
+		}
+
+		var err error
+		result, err = s.spots.Create(ctx, request)
+		if err != nil {
+			level.Error(s.logger).Log("method", "CreateSpot", "error", err)
+		}
+		return err
+	})
 	if err != nil {
-		level.Error(s.logger).Log("method", "CreateSpot", "error", err)
 		return "", err
 	}
 
+	if s.index != nil {
+		request.ID, _ = primitive.ObjectIDFromHex(result)
+		request.MazeID, _ = db.MazeFrom(ctx)
+		request.Zones = nil
+		request.Cluster = nil
+		s.spotIndex(ctx).Upsert(request)
+	}
+
 	return result, nil
 }
 
 //GetSpots returns all the spots
 func (s stubSpotHandler) GetSpots(ctx context.Context) ([]models.Spot, error) {
 
-	result, err := s.db.FindSpots(ctx, "mazedb", "spots")
+	result, err := s.spots.List(ctx)
 	if err != nil {
 		level.Error(s.logger).Log("method", "GetSpots", "error", err)
 		return nil, err
@@ -53,16 +115,25 @@ func (s stubSpotHandler) GetSpots(ctx context.Context) ([]models.Spot, error) {
 	return result, nil
 }
 
-//GetSingleSpot returns one single Spot, given its ID
+//GetSingleSpot returns one single Spot, given its ID, along with the zones it lies in
 func (s stubSpotHandler) GetSingleSpot(ctx context.Context, id string) (models.Spot, error) {
-	var spot models.Spot
-	idp, _ := primitive.ObjectIDFromHex(id)
-
+	idp, err := mazeerrors.ParseID("id", id)
+	if err != nil {
+		return models.Spot{}, err
+	}
 
-	err := s.db.FindOne(ctx, "mazedb", "spots", models.Spot{ID: idp}, &spot)
+	spot, err := s.spots.GetByID(ctx, idp)
 	if err != nil {
 		level.Error(s.logger).Log("method", "GetSingleSpot", "error", err)
-		return models.Spot{}, err
+		return models.Spot{}, mazeerrors.Missing(err, "spot", id)
+	}
+
+	if s.zones != nil {
+		spot.Zones, err = s.zones.GetZonesContainingSpot(ctx, spot)
+		if err != nil {
+			level.Error(s.logger).Log("method", "GetSingleSpot", "error", err)
+			return models.Spot{}, err
+		}
 	}
 
 	return spot, nil
@@ -71,40 +142,456 @@ func (s stubSpotHandler) GetSingleSpot(ctx context.Context, id string) (models.S
 //ModifySpot modifies one single spot
 func (s stubSpotHandler) ModifySpot(ctx context.Context, request models.Spot, id string) (int, error) {
 
-	idp, err := primitive.ObjectIDFromHex(id)
+	idp, err := mazeerrors.ParseID("id", id)
 	if err != nil {
 		level.Error(s.logger).Log("method", "ModifySpot", "error", err)
 		return 0, err
 	}
-	filter := bson.D{{"_id", idp}}
+	var result int
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.validate(ctx, request); err != nil {
+			return err
+		}
+		//the update only counts the spots it changed, so one already looking like this would pass for a missing one
+		if _, err := s.spots.GetByID(ctx, idp); err != nil {
+			level.Error(s.logger).Log("method", "ModifySpot", "error", err)
+			return mazeerrors.Missing(err, "spot", id)
+		}
+
+		//the cluster label is left alone, it's only set by ClusterSpots
+		var err error
+		result, err = s.spots.Update(ctx, idp, request)
+		if err != nil {
+			level.Error(s.logger).Log("method", "ModifySpot", "error", err)
+			return err
+		}
 
-	update := bson.D{{"$set", bson.D{{"x_coordinate", request.XCoordinate},
-		{"y_coordinate", request.YCoordinate},
-		{"name", request.Name},
-		{"number", request.Number}}}}
-	result, err := s.db.UpdateOne(ctx, filter, update, "mazedb", "spots")
+		if s.paths != nil && result > 0 {
+			request.ID = idp
+			if err := s.refreshPaths(ctx, request); err != nil {
+				level.Error(s.logger).Log("method", "ModifySpot", "error", err)
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		level.Error(s.logger).Log("method", "ModifySpot", "error", err)
 		return 0, err
 	}
+
+	//nothing is modified when the spot already looked like this
+	if s.index != nil && result > 0 {
+		current, _ := s.spotIndex(ctx).Get(idp)
+		request.ID = idp
+		request.MazeID = current.MazeID
+		request.Zones = nil
+		request.Cluster = current.Cluster
+		s.spotIndex(ctx).Upsert(request)
+	}
 	return result, nil
 }
 
-//DeleteSpot deletes a spot, given its ID
+//refreshPaths measures the paths of a spot that moved again, so the distances stored stay those of their spots
+func (s stubSpotHandler) refreshPaths(ctx context.Context, spot models.Spot) error {
+
+	joined, err := s.paths.Find(ctx, db.PathsOf(spot.ID))
+	if err != nil || len(joined) == 0 {
+		return err
+	}
+	ids := make([]primitive.ObjectID, 0, len(joined))
+	for _, p := range joined {
+		ids = append(ids, p.PointA, p.PointB)
+	}
+	ends, err := s.spots.GetByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byID := map[primitive.ObjectID]models.Spot{spot.ID: spot}
+	for _, v := range ends {
+		if v.ID != spot.ID {
+			byID[v.ID] = v
+		}
+	}
+	m, err := maze.Get(ctx, s.mazes)
+	if err != nil {
+		return err
+	}
+	distance := path.Metric(m)
+
+	distances := make(map[primitive.ObjectID]float64, len(joined))
+	for _, p := range joined {
+		a, okA := byID[p.PointA]
+		b, okB := byID[p.PointB]
+		if okA && okB {
+			distances[p.ID] = distance(a, b)
+		}
+	}
+	_, err = s.paths.SetDistances(ctx, distances)
+	return err
+}
+
+//DeleteSpot deletes a spot, given its ID. Its paths are dealt with as the service's deletion says, so none is left
+//pointing to nothing
 func (s stubSpotHandler) DeleteSpot(ctx context.Context, id string) (int, error) {
 
-	idp, err := primitive.ObjectIDFromHex(id)
+	idp, err := mazeerrors.ParseID("id", id)
 	if err != nil {
 		level.Error(s.logger).Log("method", "DeleteSpot", "error", err)
 		return 0, err
 	}
-	filter := bson.D{{"_id", idp}}
 
-	result, err := s.db.DeleteOne(ctx, filter, "mazedb", "spots")
+	var result int
+	err = s.tx.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		if s.paths != nil {
+			switch s.deletion {
+			case models.CascadeDeletion:
+				_, err = s.paths.DeleteMany(ctx, db.PathsOf(idp))
+			case models.DetachDeletion:
+				_, err = s.paths.Detach(ctx, idp)
+			default:
+				var joined []models.Path
+				joined, err = s.paths.Find(ctx, db.PathsOf(idp))
+				if err == nil && len(joined) > 0 {
+					err = mazeerrors.NewConflict("The spot can't be deleted while paths join it")
+				}
+			}
+			if err != nil {
+				level.Error(s.logger).Log("method", "DeleteSpot", "error", err)
+				return err
+			}
+		}
+
+		result, err = s.spots.Delete(ctx, idp)
+		if err != nil {
+			level.Error(s.logger).Log("method", "DeleteSpot", "error", err)
+			return err
+		}
+		if result == 0 {
+			return mazeerrors.NewNotFound("spot", id)
+		}
+		return nil
+	})
 	if err != nil {
-		level.Error(s.logger).Log("method", "DeleteSpot", "error", err)
 		return 0, err
 	}
 
+	if s.index != nil {
+		s.spotIndex(ctx).Remove(idp)
+	}
+
+	return result, nil
+}
+
+//GetSpotsInBox returns the spots lying inside an axis-aligned rectangle, borders included.
+//Distance sorting is measured from the center of the rectangle
+func (s stubSpotHandler) GetSpotsInBox(ctx context.Context, request models.BoxQuery) ([]models.Spot, error) {
+
+	if request.MinX > request.MaxX || request.MinY > request.MaxY {
+		return nil, mazeerrors.NewValidation("Invalid box: min coordinates must not exceed max coordinates")
+	}
+	if err := validateSort(request.SortBy); err != nil {
+		return nil, err
+	}
+	distance, _, err := s.measure(ctx)
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetSpotsInBox", "error", err)
+		return nil, err
+	}
+
+	var result []models.Spot
+	if s.index != nil {
+		result = s.spotIndex(ctx).InBox(request.MinX, request.MinY, request.MaxX, request.MaxY)
+	} else {
+		filter := db.BoxFilter(request.MinX, request.MinY, request.MaxX, request.MaxY)
+		if result, err = s.spots.Find(ctx, filter); err != nil {
+			level.Error(s.logger).Log("method", "GetSpotsInBox", "error", err)
+			return nil, err
+		}
+	}
+
+	sortSpots(result, request.SortBy, (request.MinX+request.MaxX)/2, (request.MinY+request.MaxY)/2, distance)
+	return result, nil
+}
+
+//GetSpotsInRadius returns the spots lying inside a circle, border included. In geographic mazes the radius is in
+//meters and the circle is measured on the sphere
+func (s stubSpotHandler) GetSpotsInRadius(ctx context.Context, request models.RadiusQuery) ([]models.Spot, error) {
+
+	if request.Radius < 0 {
+		return nil, mazeerrors.NewValidation("Invalid radius: it must not be negative")
+	}
+	if err := validateSort(request.SortBy); err != nil {
+		return nil, err
+	}
+	distance, geographic, err := s.measure(ctx)
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetSpotsInRadius", "error", err)
+		return nil, err
+	}
+
+	var result []models.Spot
+	switch {
+	case geographic:
+		//the longitudes and latitudes bounding the circle select the candidates, the great-circle distance trims them
+		minX, minY, maxX, maxY := geometry.GeoBox(request.X, request.Y, request.Radius)
+		var candidates []models.Spot
+		if s.index != nil {
+			candidates = s.spotIndex(ctx).InBox(minX, minY, maxX, maxY)
+		} else if candidates, err = s.spots.Find(ctx, db.BoxFilter(minX, minY, maxX, maxY)); err != nil {
+			level.Error(s.logger).Log("method", "GetSpotsInRadius", "error", err)
+			return nil, err
+		}
+		for _, v := range candidates {
+			if distance(request.X, request.Y, v.XCoordinate, v.YCoordinate) <= request.Radius {
+				result = append(result, v)
+			}
+		}
+	case s.index != nil:
+		result = s.spotIndex(ctx).InRadius(request.X, request.Y, request.Radius)
+	default:
+		//the bounding box lets the database use the coordinate indexes, the expression trims its corners
+		filter := bson.M{"$and": bson.A{
+			db.BoxFilter(request.X-request.Radius, request.Y-request.Radius, request.X+request.Radius, request.Y+request.Radius),
+			bson.M{"$expr": bson.M{"$lte": bson.A{
+				bson.M{"$add": bson.A{
+					bson.M{"$pow": bson.A{bson.M{"$subtract": bson.A{db.CoordinateExpr("x_coordinate"), request.X}}, 2}},
+					bson.M{"$pow": bson.A{bson.M{"$subtract": bson.A{db.CoordinateExpr("y_coordinate"), request.Y}}, 2}},
+				}},
+				request.Radius * request.Radius,
+			}}},
+		}}
+		if result, err = s.spots.Find(ctx, filter); err != nil {
+			level.Error(s.logger).Log("method", "GetSpotsInRadius", "error", err)
+			return nil, err
+		}
+	}
+
+	sortSpots(result, request.SortBy, request.X, request.Y, distance)
+	return result, nil
+}
+
+//GetNearestSpots returns the spots closest to a point, the closest first. In geographic mazes they're the closest
+//along the sphere, which the planar index can't tell, so every spot is measured
+func (s stubSpotHandler) GetNearestSpots(ctx context.Context, request models.NearestQuery) ([]models.Spot, error) {
+
+	if request.K < 1 {
+		return nil, mazeerrors.NewValidation("Invalid amount of spots: it must be at least one")
+	}
+	distance, geographic, err := s.measure(ctx)
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetNearestSpots", "error", err)
+		return nil, err
+	}
+
+	var result []models.Spot
+	switch {
+	case s.index != nil && !geographic:
+		return s.spotIndex(ctx).Nearest(request.X, request.Y, request.K), nil
+	case s.index != nil:
+		result = s.spotIndex(ctx).All()
+	default:
+		if result, err = s.spots.List(ctx); err != nil {
+			level.Error(s.logger).Log("method", "GetNearestSpots", "error", err)
+			return nil, err
+		}
+	}
+	sortSpots(result, models.SortByDistance, request.X, request.Y, distance)
+	if len(result) > request.K {
+		result = result[:request.K]
+	}
+	return result, nil
+}
+
+//GetSpotGrid bins the spots into a grid of square cells, counting them and adding their numbers up per cell. The
+//spots are streamed from the database, only the non-empty cells are kept in memory
+func (s stubSpotHandler) GetSpotGrid(ctx context.Context, request models.GridQuery) (models.Grid, error) {
+
+	b, err := newBinner(request)
+	if err != nil {
+		return models.Grid{}, err
+	}
+
+	switch {
+	case s.index != nil && request.Bounds != nil:
+		for _, v := range s.spotIndex(ctx).InBox(request.Bounds.MinX, request.Bounds.MinY, request.Bounds.MaxX, request.Bounds.MaxY) {
+			if err := b.add(v); err != nil {
+				return models.Grid{}, err
+			}
+		}
+	case s.index != nil:
+		for _, v := range s.spotIndex(ctx).All() {
+			if err := b.add(v); err != nil {
+				return models.Grid{}, err
+			}
+		}
+	default:
+		filter := bson.M{}
+		if request.Bounds != nil {
+			filter = db.BoxFilter(request.Bounds.MinX, request.Bounds.MinY, request.Bounds.MaxX, request.Bounds.MaxY)
+		}
+		err = s.spots.Stream(ctx, filter, func(v models.Spot) error {
+			if err := b.add(v); err != nil {
+				return err
+			}
+			if len(b.cells) > MaxGridCells {
+				return errTooManyCells
+			}
+			return nil
+		})
+		if err != nil {
+			level.Error(s.logger).Log("method", "GetSpotGrid", "error", err)
+			return models.Grid{}, err
+		}
+	}
+
+	return b.grid()
+}
+
+//ClusterSpots groups the spots with k-means or DBSCAN. When asked to, it saves every spot's label, replacing the
+//labels of any previous clustering
+func (s stubSpotHandler) ClusterSpots(ctx context.Context, request models.ClusterQuery) (models.Clustering, error) {
+
+	var spots []models.Spot
+	if s.index != nil {
+		spots = s.spotIndex(ctx).All()
+	} else {
+		var err error
+		if spots, err = s.spots.List(ctx); err != nil {
+			level.Error(s.logger).Log("method", "ClusterSpots", "error", err)
+			return models.Clustering{}, err
+		}
+	}
+
+	var labels []int
+	var centroids []models.Point
+	var err error
+	switch request.Algorithm {
+	case models.KMeans:
+		labels, centroids, err = cluster.KMeans(spots, request.K)
+	case models.DBSCAN:
+		if labels, err = cluster.DBSCAN(spots, request.Eps, request.MinPoints); err == nil {
+			centroids = cluster.Centroids(spots, labels)
+		}
+	default:
+		err = mazeerrors.NewValidation("Invalid algorithm: it must be either kmeans or dbscan")
+	}
+	if err != nil {
+		return models.Clustering{}, err
+	}
+
+	result := models.Clustering{Clusters: []models.Cluster{}, Noise: []primitive.ObjectID{}, Labels: make(map[string]int, len(spots))}
+	for label, c := range centroids {
+		result.Clusters = append(result.Clusters, models.Cluster{Label: label, Centroid: c, Spots: []primitive.ObjectID{}})
+	}
+	for i, v := range spots {
+		result.Labels[v.ID.Hex()] = labels[i]
+		if labels[i] == cluster.Noise {
+			result.Noise = append(result.Noise, v.ID)
+			continue
+		}
+		result.Clusters[labels[i]].Spots = append(result.Clusters[labels[i]].Spots, v.ID)
+	}
+
+	if request.Persist {
+		err := s.tx.Transaction(ctx, func(ctx context.Context) error {
+			return s.persistClusters(ctx, result)
+		})
+		if err != nil {
+			level.Error(s.logger).Log("method", "ClusterSpots", "error", err)
+			return models.Clustering{}, err
+		}
+		if s.index != nil {
+			for i := range spots {
+				label := labels[i]
+				spots[i].Cluster = &label
+				s.spotIndex(ctx).Upsert(spots[i])
+			}
+		}
+	}
+
 	return result, nil
 }
+
+//persistClusters clears the labels of the previous clustering and saves the new ones, a single update per cluster
+func (s stubSpotHandler) persistClusters(ctx context.Context, clustering models.Clustering) error {
+
+	if err := s.spots.ClearClusters(ctx); err != nil {
+		return err
+	}
+
+	groups := map[int][]primitive.ObjectID{cluster.Noise: clustering.Noise}
+	for _, c := range clustering.Clusters {
+		groups[c.Label] = c.Spots
+	}
+	for label, ids := range groups {
+		if len(ids) == 0 {
+			continue
+		}
+		if err := s.spots.SetCluster(ctx, ids, label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//GetSpotsInCluster returns the spots labelled with the given cluster by the last persisted clustering
+func (s stubSpotHandler) GetSpotsInCluster(ctx context.Context, label int) ([]models.Spot, error) {
+
+	result, err := s.spots.Find(ctx, bson.M{"cluster": label})
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetSpotsInCluster", "error", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+//validate checks the spot's coordinates against the maze's settings
+func (s stubSpotHandler) validate(ctx context.Context, spot models.Spot) error {
+	m, err := maze.Get(ctx, s.mazes)
+	if err != nil {
+		return err
+	}
+	return maze.ValidateSpot(m, spot)
+}
+
+//validateSort checks the requested sort order, an empty one means sorting by distance
+func validateSort(sortBy string) error {
+	switch sortBy {
+	case "", models.SortByDistance, models.SortByName:
+		return nil
+	}
+	return mazeerrors.NewValidation("Invalid sort: it must be either distance or name")
+}
+
+//sortSpots sorts the spots by name or by their distance to the given point, the closest first
+func sortSpots(spots []models.Spot, sortBy string, x, y float64, distance func(x1, y1, x2, y2 float64) float64) {
+	if sortBy == models.SortByName {
+		sort.SliceStable(spots, func(i, j int) bool {
+			return spots[i].Name < spots[j].Name
+		})
+		return
+	}
+	sort.SliceStable(spots, func(i, j int) bool {
+		return distance(x, y, spots[i].XCoordinate, spots[i].YCoordinate) <
+			distance(x, y, spots[j].XCoordinate, spots[j].YCoordinate)
+	})
+}
+
+//planar measures the distance between two points of the plane
+func planar(x1, y1, x2, y2 float64) float64 {
+	return math.Hypot(x2-x1, y2-y1)
+}
+
+//measure returns how the spatial queries measure distances in the maze, leaving the floors out: great-circle meters
+//between longitudes and latitudes in geographic mazes, planar distances otherwise
+func (s stubSpotHandler) measure(ctx context.Context) (distance func(x1, y1, x2, y2 float64) float64, geographic bool, err error) {
+	m, err := maze.Get(ctx, s.mazes)
+	if err != nil {
+		return nil, false, err
+	}
+	if m.Coordinates == models.Geographic {
+		return geometry.GreatCircle, true, nil
+	}
+	return planar, false, nil
+}
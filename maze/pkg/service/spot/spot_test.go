@@ -0,0 +1,330 @@
+package spot
+
+import (
+	"context"
+	"errors"
+	"github.com/avanticaTest/maze/pkg/db"
+	"github.com/avanticaTest/maze/pkg/index"
+	mazeerrors "github.com/avanticaTest/maze/pkg/errors"
+	"github.com/avanticaTest/maze/pkg/models"
+	"github.com/avanticaTest/maze/pkg/service/maze"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gotest.tools/v3/assert"
+	"math"
+	"testing"
+)
+
+func TestGetSpotsInBox(t *testing.T) {
+
+	stored := []models.Spot{
+		{Name: "far", XCoordinate: 4, YCoordinate: 4},
+		{Name: "center", XCoordinate: 1, YCoordinate: 1},
+		{Name: "border", XCoordinate: 0, YCoordinate: 2},
+	}
+
+	tests := []struct {
+		name          string
+		request       models.BoxQuery
+		expectedNames []string
+		expectedErr   error
+	}{
+		{
+			name:          "Sorted by distance",
+			request:       models.BoxQuery{MinX: -2, MinY: -2, MaxX: 4, MaxY: 4},
+			expectedNames: []string{"center", "border", "far"},
+		},
+		{
+			name:          "Sorted by name",
+			request:       models.BoxQuery{MinX: -2, MinY: -2, MaxX: 4, MaxY: 4, SortBy: models.SortByName},
+			expectedNames: []string{"border", "center", "far"},
+		},
+		{
+			name:        "Inverted box",
+			request:     models.BoxQuery{MinX: 4, MinY: -2, MaxX: -2, MaxY: 4},
+			expectedErr: errors.New("Invalid box: min coordinates must not exceed max coordinates"),
+		},
+		{
+			name:        "Unknown sort",
+			request:     models.BoxQuery{MinX: -2, MinY: -2, MaxX: 4, MaxY: 4, SortBy: "number"},
+			expectedErr: errors.New("Invalid sort: it must be either distance or name"),
+		},
+	}
+
+	ctx := context.Background()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			spots := make([]models.Spot, len(stored))
+			copy(spots, stored)
+			m := &db.Mock{}
+			m.On("FindSpotsByFilter", ctx, "mazedb", "spots", mock.Anything).Return(spots, nil)
+			s := New(log.NewNopLogger(), db.NewSpotRepository(m, "mazedb", db.SpotsCollection), nil, "", nil, nil, nil, nil)
+
+			resp, err := s.GetSpotsInBox(ctx, tt.request)
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NilError(t, err)
+			var names []string
+			for _, v := range resp {
+				names = append(names, v.Name)
+			}
+			assert.DeepEqual(t, tt.expectedNames, names)
+		})
+	}
+}
+
+func TestGeographicDistances(t *testing.T) {
+
+	//at 60 degrees of latitude a degree of longitude is half as long as one of latitude, so the spot to the east is
+	//the closest along the sphere though it's the farthest on the plane
+	stored := []models.Spot{
+		{Name: "far", XCoordinate: 3, YCoordinate: 60},
+		{Name: "north", XCoordinate: 0, YCoordinate: 60.7},
+		{Name: "east", XCoordinate: 1, YCoordinate: 60},
+	}
+
+	for _, indexed := range []bool{false, true} {
+		store := db.NewMemoryStore()
+		mazes := maze.New(log.NewNopLogger(), db.NewMemoryMazeRepository(store), nil, nil, nil, nil, nil, nil)
+		id, err := mazes.CreateMaze(context.Background(), models.Maze{Name: "earth", Coordinates: models.Geographic})
+		assert.NilError(t, err)
+		mazeID, err := primitive.ObjectIDFromHex(id)
+		assert.NilError(t, err)
+		ctx := db.WithMaze(context.Background(), mazeID)
+
+		spots := db.NewMemorySpotRepository(store)
+		for _, v := range stored {
+			_, err := spots.Create(ctx, v)
+			assert.NilError(t, err)
+		}
+		var ix *index.Mazes
+		mode := "Database"
+		if indexed {
+			all, err := spots.List(ctx)
+			assert.NilError(t, err)
+			ix = index.NewMazes()
+			ix.Load(all)
+			mode = "Index"
+		}
+		s := New(log.NewNopLogger(), spots, nil, "", nil, ix, mazes, nil)
+
+		t.Run(mode+"/Radius in meters", func(t *testing.T) {
+			resp, err := s.GetSpotsInRadius(ctx, models.RadiusQuery{X: 0, Y: 60, Radius: 80000})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, []string{"east", "north"}, spotNames(resp))
+		})
+		t.Run(mode+"/Nearest", func(t *testing.T) {
+			resp, err := s.GetNearestSpots(ctx, models.NearestQuery{X: 0, Y: 60, K: 2})
+			assert.NilError(t, err)
+			assert.DeepEqual(t, []string{"east", "north"}, spotNames(resp))
+		})
+	}
+}
+
+//spotNames returns the names of the spots, in their order
+func spotNames(spots []models.Spot) []string {
+	var result []string
+	for _, v := range spots {
+		result = append(result, v.Name)
+	}
+	return result
+}
+
+func TestClusterSpots(t *testing.T) {
+
+	stored := []models.Spot{
+		{ID: primitive.NewObjectID(), XCoordinate: 1, YCoordinate: 1},
+		{ID: primitive.NewObjectID(), XCoordinate: 2, YCoordinate: 1},
+		{ID: primitive.NewObjectID(), XCoordinate: 1, YCoordinate: 2},
+		{ID: primitive.NewObjectID(), XCoordinate: 50, YCoordinate: 50},
+	}
+
+	tests := []struct {
+		name          string
+		request       models.ClusterQuery
+		expectedSizes []int
+		expectedNoise int
+		expectedErr   error
+	}{
+		{
+			name:          "KMeans",
+			request:       models.ClusterQuery{Algorithm: models.KMeans, K: 2},
+			expectedSizes: []int{3, 1},
+		},
+		{
+			name:          "DBSCAN persisted",
+			request:       models.ClusterQuery{Algorithm: models.DBSCAN, Eps: 1.5, MinPoints: 3, Persist: true},
+			expectedSizes: []int{3},
+			expectedNoise: 1,
+		},
+		{
+			name:        "Unknown algorithm",
+			request:     models.ClusterQuery{Algorithm: "hierarchical"},
+			expectedErr: errors.New("Invalid algorithm: it must be either kmeans or dbscan"),
+		},
+	}
+
+	ctx := context.Background()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			m := &db.Mock{}
+			m.On("FindSpotsByFilter", ctx, "mazedb", "spots", mock.Anything).Return(stored, nil)
+			//the mock can't record calls, so the updates are collected as they happen
+			var updates []interface{}
+			m.On("UpdateMany", ctx, mock.Anything, mock.Anything, "mazedb", "spots").Return(1, nil).Run(func(args mock.Arguments) {
+				updates = append(updates, args.Get(2))
+			})
+			s := New(log.NewNopLogger(), db.NewSpotRepository(m, "mazedb", db.SpotsCollection), nil, "", nil, nil, nil, nil)
+
+			resp, err := s.ClusterSpots(ctx, tt.request)
+
+			if tt.expectedErr != nil {
+				assert.Error(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NilError(t, err)
+			var sizes []int
+			for _, c := range resp.Clusters {
+				sizes = append(sizes, len(c.Spots))
+			}
+			assert.DeepEqual(t, tt.expectedSizes, sizes)
+			assert.Equal(t, tt.expectedNoise, len(resp.Noise))
+			assert.Equal(t, len(stored), len(resp.Labels))
+			if !tt.request.Persist {
+				assert.Equal(t, 0, len(updates))
+				return
+			}
+			//the previous labels are cleared first, then the cluster and the noise are saved
+			assert.Equal(t, 3, len(updates))
+			assert.DeepEqual(t, bson.M{"$unset": bson.M{"cluster": ""}}, updates[0])
+		})
+	}
+}
+
+func TestDeleteSpot(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		deletion      string
+		expectedErr   error
+		expectedPaths []models.Path
+	}{
+		{
+			name:        "Restrict",
+			deletion:    models.RestrictDeletion,
+			expectedErr: mazeerrors.NewConflict("The spot can't be deleted while paths join it"),
+		},
+		{
+			name:     "Cascade",
+			deletion: models.CascadeDeletion,
+		},
+		{
+			name:          "Detach",
+			deletion:      models.DetachDeletion,
+			expectedPaths: []models.Path{{}},
+		},
+	}
+
+	ctx := context.Background()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			store := db.NewMemoryStore()
+			spots := db.NewMemorySpotRepository(store)
+			paths := db.NewMemoryPathRepository(store)
+			s := New(log.NewNopLogger(), spots, paths, tt.deletion, nil, nil, nil, nil)
+			a, err := s.CreateSpot(ctx, models.Spot{Name: "a"})
+			assert.NilError(t, err)
+			b, err := s.CreateSpot(ctx, models.Spot{Name: "b", XCoordinate: 1})
+			assert.NilError(t, err)
+			ida, _ := primitive.ObjectIDFromHex(a)
+			idb, _ := primitive.ObjectIDFromHex(b)
+			path, err := paths.Create(ctx, models.Path{PointA: ida, PointB: idb, Distance: 1})
+			assert.NilError(t, err)
+
+			_, err = s.DeleteSpot(ctx, a)
+
+			if tt.expectedErr != nil {
+				assert.Equal(t, tt.expectedErr, err)
+				_, err = spots.GetByID(ctx, ida)
+				assert.NilError(t, err)
+				return
+			}
+			assert.NilError(t, err)
+			left, err := paths.List(ctx)
+			assert.NilError(t, err)
+			//a detached path keeps its other end only
+			for i := range tt.expectedPaths {
+				tt.expectedPaths[i].ID, _ = primitive.ObjectIDFromHex(path)
+				tt.expectedPaths[i].PointB = idb
+			}
+			assert.DeepEqual(t, tt.expectedPaths, left)
+		})
+	}
+}
+
+func TestModifySpotRefreshesPaths(t *testing.T) {
+
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	paths := db.NewMemoryPathRepository(store)
+	s := New(log.NewNopLogger(), db.NewMemorySpotRepository(store), paths, "", nil, nil, nil, nil)
+	a, err := s.CreateSpot(ctx, models.Spot{Name: "a"})
+	assert.NilError(t, err)
+	b, err := s.CreateSpot(ctx, models.Spot{Name: "b", XCoordinate: 3})
+	assert.NilError(t, err)
+	c, err := s.CreateSpot(ctx, models.Spot{Name: "c", YCoordinate: 3})
+	assert.NilError(t, err)
+	ida, _ := primitive.ObjectIDFromHex(a)
+	idb, _ := primitive.ObjectIDFromHex(b)
+	idc, _ := primitive.ObjectIDFromHex(c)
+	for _, v := range []models.Path{
+		{PointA: ida, PointB: idb, Distance: 3},
+		{PointA: idc, PointB: idb, Distance: math.Sqrt(18)},
+		{PointA: ida, PointB: idc, Distance: 3},
+	} {
+		_, err := paths.Create(ctx, v)
+		assert.NilError(t, err)
+	}
+
+	_, err = s.ModifySpot(ctx, models.Spot{Name: "b", XCoordinate: 3, YCoordinate: 4}, b)
+	assert.NilError(t, err)
+
+	//only the paths of the spot that moved change
+	result, err := paths.List(ctx)
+	assert.NilError(t, err)
+	var distances []float64
+	for _, v := range result {
+		distances = append(distances, v.Distance)
+	}
+	assert.DeepEqual(t, []float64{5, math.Sqrt(10), 3}, distances)
+}
+
+func TestMissingSpot(t *testing.T) {
+
+	ctx := context.Background()
+	s := New(log.NewNopLogger(), db.NewMemorySpotRepository(db.NewMemoryStore()), nil, "", nil, nil, nil, nil)
+	id, err := s.CreateSpot(ctx, models.Spot{Name: "a"})
+	assert.NilError(t, err)
+
+	//a spot given back its own values modifies nothing, but it's there
+	modified, err := s.ModifySpot(ctx, models.Spot{Name: "a"}, id)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, modified)
+
+	missing := primitive.NewObjectID().Hex()
+	_, err = s.ModifySpot(ctx, models.Spot{Name: "b"}, missing)
+	assert.Equal(t, mazeerrors.NewNotFound("spot", missing), err)
+	_, err = s.DeleteSpot(ctx, missing)
+	assert.Equal(t, mazeerrors.NewNotFound("spot", missing), err)
+}
@@ -0,0 +1,324 @@
+package zone
+
+import (
+	"context"
+	"errors"
+	"github.com/avanticaTest/maze/pkg/db"
+	mazeerrors "github.com/avanticaTest/maze/pkg/errors"
+	"github.com/avanticaTest/maze/pkg/geometry"
+	"github.com/avanticaTest/maze/pkg/index"
+	"github.com/avanticaTest/maze/pkg/models"
+	"github.com/avanticaTest/maze/pkg/service/maze"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"math"
+)
+
+type ZoneHandler interface {
+	CreateZone(ctx context.Context, request models.Zone) (string, error)
+	GetSingleZone(ctx context.Context, id string) (models.Zone, error)
+	ModifyZone(ctx context.Context, request models.Zone, id string) (int, error)
+	GetZones(ctx context.Context) ([]models.Zone, error)
+	DeleteZone(ctx context.Context, id string) (int, error)
+	GetSpotsInZone(ctx context.Context, id string, floor *float64) ([]models.Spot, error)
+	GetZonesContainingSpot(ctx context.Context, spot models.Spot) ([]string, error)
+}
+
+type stubZoneHandler struct {
+	zones   db.ZoneRepository
+	spots   db.SpotRepository
+	origins db.OriginRepository
+	index   *index.Mazes
+	mazes   maze.MazeHandler
+	logger  log.Logger
+}
+
+//New creates the zone service. When an index is given, the spots are looked up in it instead of the database. Without
+//a maze service the maze is cartesian
+func New(logger log.Logger, zones db.ZoneRepository, spots db.SpotRepository, origins db.OriginRepository, index *index.Mazes, mazes maze.MazeHandler) ZoneHandler {
+	return stubZoneHandler{
+		zones:   zones,
+		spots:   spots,
+		origins: origins,
+		index:   index,
+		mazes:   mazes,
+		logger:  logger,
+	}
+}
+
+//spotIndex returns the index of the context's maze
+func (s stubZoneHandler) spotIndex(ctx context.Context) *index.SpotIndex {
+	maze, _ := db.MazeFrom(ctx)
+	return s.index.Of(maze)
+}
+
+//CreateZone creates a zone given its name and polygon. It returns the ID of the Zone Created
+func (s stubZoneHandler) CreateZone(ctx context.Context, request models.Zone) (string, error) {
+
+	if err := validate(request); err != nil {
+		return "", err
+	}
+
+	result, err := s.zones.Create(ctx, request)
+	if err != nil {
+		level.Error(s.logger).Log("method", "CreateZone", "error", err)
+		return "", err
+	}
+
+	return result, nil
+}
+
+//GetSingleZone returns one single Zone, given its ID. The quadrants are found by their name
+func (s stubZoneHandler) GetSingleZone(ctx context.Context, id string) (models.Zone, error) {
+
+	if geometry.IsQuadrant(id) {
+		return builtIn(id), nil
+	}
+
+	idp, err := mazeerrors.ParseID("id", id)
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetSingleZone", "error", err)
+		return models.Zone{}, err
+	}
+
+	zone, err := s.zones.GetByID(ctx, idp)
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetSingleZone", "error", err)
+		return models.Zone{}, mazeerrors.Missing(err, "zone", id)
+	}
+
+	return zone, nil
+}
+
+//GetZones returns the quadrants followed by every stored zone
+func (s stubZoneHandler) GetZones(ctx context.Context) ([]models.Zone, error) {
+
+	result, err := s.zones.List(ctx)
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetZones", "error", err)
+		return nil, err
+	}
+
+	return append(geometry.Quadrants(), result...), nil
+}
+
+//ModifyZone modifies one single zone. The quadrants can't be modified
+func (s stubZoneHandler) ModifyZone(ctx context.Context, request models.Zone, id string) (int, error) {
+
+	if geometry.IsQuadrant(id) {
+		return 0, mazeerrors.NewConflict("Built-in zones can't be modified")
+	}
+	if err := validate(request); err != nil {
+		return 0, err
+	}
+
+	idp, err := mazeerrors.ParseID("id", id)
+	if err != nil {
+		level.Error(s.logger).Log("method", "ModifyZone", "error", err)
+		return 0, err
+	}
+
+	if _, err := s.zones.GetByID(ctx, idp); err != nil {
+		level.Error(s.logger).Log("method", "ModifyZone", "error", err)
+		return 0, mazeerrors.Missing(err, "zone", id)
+	}
+	result, err := s.zones.Update(ctx, idp, request)
+	if err != nil {
+		level.Error(s.logger).Log("method", "ModifyZone", "error", err)
+		return 0, err
+	}
+	return result, nil
+}
+
+//DeleteZone deletes a zone, given its ID. The quadrants can't be deleted
+func (s stubZoneHandler) DeleteZone(ctx context.Context, id string) (int, error) {
+
+	if geometry.IsQuadrant(id) {
+		return 0, mazeerrors.NewConflict("Built-in zones can't be deleted")
+	}
+
+	idp, err := mazeerrors.ParseID("id", id)
+	if err != nil {
+		level.Error(s.logger).Log("method", "DeleteZone", "error", err)
+		return 0, err
+	}
+
+	result, err := s.zones.Delete(ctx, idp)
+	if err != nil {
+		level.Error(s.logger).Log("method", "DeleteZone", "error", err)
+		return 0, err
+	}
+	if result == 0 {
+		return 0, mazeerrors.NewNotFound("zone", id)
+	}
+
+	return result, nil
+}
+
+//GetSpotsInZone gets all spots inside a zone, borders included. The database evaluates the quadrants by itself. For
+//the other zones it only selects the spots inside their bounding box (or the index does, when there's one), the
+//exact point in polygon test is done here as the spots are streamed. Zones span every floor unless one is given
+func (s stubZoneHandler) GetSpotsInZone(ctx context.Context, id string, floor *float64) ([]models.Spot, error) {
+
+	zone, err := s.GetSingleZone(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var origin models.Origin
+	if zone.OriginRelative {
+		if origin, err = s.origin(ctx); err != nil {
+			level.Error(s.logger).Log("method", "GetSpotsInZone", "error", err)
+			return nil, err
+		}
+	}
+
+	minX, minY, maxX, maxY := worldBounds(zone, origin)
+	if s.index != nil {
+		var result []models.Spot
+		for _, v := range s.spotIndex(ctx).InBox(minX, minY, maxX, maxY) {
+			if onFloor(v, floor) && contains(zone, origin, v) {
+				result = append(result, v)
+			}
+		}
+		return result, nil
+	}
+
+	var result []models.Spot
+	if zone.BuiltIn {
+		err = s.spots.Stream(ctx, db.OnFloor(db.QuadrantFilter(origin, zone.Name), floor), func(v models.Spot) error {
+			result = append(result, v)
+			return nil
+		})
+	} else {
+		err = s.spots.Stream(ctx, db.OnFloor(db.BoxFilter(minX, minY, maxX, maxY), floor), func(v models.Spot) error {
+			if contains(zone, origin, v) {
+				result = append(result, v)
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		level.Error(s.logger).Log("method", "GetSpotsInZone", "error", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+//GetZonesContainingSpot returns the IDs of the zones the spot lies in, the quadrants being identified by their name.
+//Origin relative zones are skipped while there's no origin
+func (s stubZoneHandler) GetZonesContainingSpot(ctx context.Context, spot models.Spot) ([]string, error) {
+
+	zones, err := s.GetZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	origin, err := s.origin(ctx)
+	var missing mazeerrors.NotFound
+	noOrigin := errors.As(err, &missing) && missing.Resource == "origin"
+	if err != nil && !noOrigin {
+		level.Error(s.logger).Log("method", "GetZonesContainingSpot", "error", err)
+		return nil, err
+	}
+
+	var result []string
+	for _, z := range zones {
+		if z.OriginRelative && noOrigin {
+			continue
+		}
+		var frame models.Origin
+		if z.OriginRelative {
+			frame = origin
+		}
+		if contains(z, frame, spot) {
+			result = append(result, key(z))
+		}
+	}
+	return result, nil
+}
+
+//origin returns the stored origin, telling whether it's geographic. It's NotFound while there's none
+func (s stubZoneHandler) origin(ctx context.Context) (models.Origin, error) {
+
+	resultO, err := s.origins.List(ctx)
+	if err != nil {
+		return models.Origin{}, err
+	}
+	if len(resultO) < 1 {
+		return models.Origin{}, mazeerrors.NewNotFound("origin", "")
+	}
+	m, err := maze.Get(ctx, s.mazes)
+	if err != nil {
+		return models.Origin{}, err
+	}
+	resultO[0].Geographic = m.Coordinates == models.Geographic
+	return resultO[0], nil
+}
+
+//validate checks that the zone has a name and a proper polygon, and doesn't take a quadrant's name
+func validate(zone models.Zone) error {
+	if zone.Name == "" {
+		return mazeerrors.NewValidation("A zone needs a name")
+	}
+	if geometry.IsQuadrant(zone.Name) {
+		return mazeerrors.NewValidation("Zone names can't match a quadrant's")
+	}
+	if len(zone.Polygon) < 3 {
+		return mazeerrors.NewValidation("A zone's polygon needs at least three points")
+	}
+	for _, p := range zone.Polygon {
+		if math.IsNaN(p.X) || math.IsNaN(p.Y) || math.IsInf(p.X, 0) || math.IsInf(p.Y, 0) {
+			return mazeerrors.NewValidation("A zone's polygon needs finite coordinates")
+		}
+	}
+	return nil
+}
+
+//builtIn returns the quadrant with the given name
+func builtIn(name string) models.Zone {
+	for _, q := range geometry.Quadrants() {
+		if q.Name == name {
+			return q
+		}
+	}
+	return models.Zone{}
+}
+
+//key returns the identifier used to reach the zone through the API
+func key(zone models.Zone) string {
+	if zone.BuiltIn {
+		return zone.Name
+	}
+	return zone.ID.Hex()
+}
+
+//contains tells whether the spot lies inside the zone. Origin relative zones are evaluated in the origin's frame
+func contains(zone models.Zone, origin models.Origin, spot models.Spot) bool {
+	x, y := spot.XCoordinate, spot.YCoordinate
+	if zone.OriginRelative {
+		x, y = geometry.ToLocal(origin, x, y)
+	}
+	return geometry.InPolygon(x, y, zone.Polygon)
+}
+
+//onFloor tells whether the spot is on the given floor, every spot being on it when there's none
+func onFloor(spot models.Spot, floor *float64) bool {
+	return floor == nil || spot.ZCoordinate == *floor
+}
+
+//worldBounds returns the zone's bounding box in world coordinates. Geographic origin relative zones may wrap around
+//the antimeridian, so they're bounded by the whole globe
+func worldBounds(zone models.Zone, origin models.Origin) (minX, minY, maxX, maxY float64) {
+	if !zone.OriginRelative {
+		return geometry.Bounds(zone.Polygon)
+	}
+	if origin.Geographic {
+		return -180, -90, 180, 90
+	}
+	world := make([]models.Point, len(zone.Polygon))
+	for i, p := range zone.Polygon {
+		world[i].X, world[i].Y = geometry.ToWorld(origin, p.X, p.Y)
+	}
+	return geometry.Bounds(world)
+}
@@ -0,0 +1,263 @@
+package zone
+
+import (
+	"context"
+	"errors"
+	"github.com/avanticaTest/maze/pkg/db"
+	mazeerrors "github.com/avanticaTest/maze/pkg/errors"
+	"github.com/avanticaTest/maze/pkg/geometry"
+	"github.com/avanticaTest/maze/pkg/index"
+	"github.com/avanticaTest/maze/pkg/models"
+	"github.com/avanticaTest/maze/pkg/service/spot"
+	"github.com/go-kit/kit/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gotest.tools/v3/assert"
+	"sort"
+	"testing"
+)
+
+//square returns the polygon of the square of the given side, its lower left corner at x, y
+func square(x, y, side float64) []models.Point {
+	return []models.Point{{X: x, Y: y}, {X: x + side, Y: y}, {X: x + side, Y: y + side}, {X: x, Y: y + side}}
+}
+
+func names(spots []models.Spot) []string {
+	result := make([]string, 0, len(spots))
+	for _, v := range spots {
+		result = append(result, v.Name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestZoneCRUD(t *testing.T) {
+
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	s := New(log.NewNopLogger(), db.NewMemoryZoneRepository(store), nil, nil, nil, nil)
+
+	id, err := s.CreateZone(ctx, models.Zone{Name: "hall", Polygon: square(0, 0, 2)})
+	assert.NilError(t, err)
+	zone, err := s.GetSingleZone(ctx, id)
+	assert.NilError(t, err)
+	assert.Equal(t, "hall", zone.Name)
+
+	modified, err := s.ModifyZone(ctx, models.Zone{Name: "lobby", Polygon: square(1, 1, 2)}, id)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, modified)
+	modified, err = s.ModifyZone(ctx, models.Zone{Name: "lobby", Polygon: square(1, 1, 2)}, id)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, modified)
+
+	//the quadrants come first
+	zones, err := s.GetZones(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, 5, len(zones))
+	assert.Equal(t, "lobby", zones[4].Name)
+	assert.DeepEqual(t, square(1, 1, 2), zones[4].Polygon)
+
+	deleted, err := s.DeleteZone(ctx, id)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, deleted)
+	_, err = s.GetSingleZone(ctx, id)
+	assert.Equal(t, mazeerrors.NewNotFound("zone", id), err)
+	_, err = s.ModifyZone(ctx, models.Zone{Name: "lobby", Polygon: square(1, 1, 2)}, id)
+	assert.Equal(t, mazeerrors.NewNotFound("zone", id), err)
+	_, err = s.DeleteZone(ctx, id)
+	assert.Equal(t, mazeerrors.NewNotFound("zone", id), err)
+}
+
+func TestInvalidZones(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		zone        models.Zone
+		expectedErr error
+	}{
+		{
+			name:        "No name",
+			zone:        models.Zone{Polygon: square(0, 0, 1)},
+			expectedErr: errors.New("A zone needs a name"),
+		},
+		{
+			name:        "Quadrant name",
+			zone:        models.Zone{Name: geometry.UpperLeft, Polygon: square(0, 0, 1)},
+			expectedErr: errors.New("Zone names can't match a quadrant's"),
+		},
+		{
+			name:        "Two points",
+			zone:        models.Zone{Name: "line", Polygon: []models.Point{{X: 0, Y: 0}, {X: 1, Y: 1}}},
+			expectedErr: errors.New("A zone's polygon needs at least three points"),
+		},
+	}
+
+	ctx := context.Background()
+	s := New(log.NewNopLogger(), db.NewMemoryZoneRepository(db.NewMemoryStore()), nil, nil, nil, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.CreateZone(ctx, tt.zone)
+			assert.Error(t, err, tt.expectedErr.Error())
+		})
+	}
+}
+
+func TestBuiltInZones(t *testing.T) {
+
+	ctx := context.Background()
+	s := New(log.NewNopLogger(), db.NewMemoryZoneRepository(db.NewMemoryStore()), nil, nil, nil, nil)
+
+	zone, err := s.GetSingleZone(ctx, geometry.BottomLeft)
+	assert.NilError(t, err)
+	assert.Assert(t, zone.BuiltIn)
+
+	_, err = s.ModifyZone(ctx, models.Zone{Name: "mine", Polygon: square(0, 0, 1)}, geometry.BottomLeft)
+	assert.Equal(t, mazeerrors.NewConflict("Built-in zones can't be modified"), err)
+	_, err = s.DeleteZone(ctx, geometry.BottomLeft)
+	assert.Equal(t, mazeerrors.NewConflict("Built-in zones can't be deleted"), err)
+}
+
+func TestGetSpotsInZone(t *testing.T) {
+
+	ground, upstairs := 0.0, 1.0
+	stored := []models.Spot{
+		{Name: "inside", XCoordinate: 1, YCoordinate: 1},
+		{Name: "upstairs", XCoordinate: 1, YCoordinate: 1, ZCoordinate: 1},
+		{Name: "border", XCoordinate: 2, YCoordinate: 0},
+		//within the triangle's bounding box, but not in the triangle
+		{Name: "corner", XCoordinate: 3.5, YCoordinate: 3.5},
+		{Name: "outside", XCoordinate: 10.5, YCoordinate: 10.5},
+		{Name: "behind", XCoordinate: -10, YCoordinate: -1},
+	}
+	triangle := models.Zone{Name: "triangle", Polygon: []models.Point{{X: 0, Y: 0}, {X: 4, Y: 0}, {X: 0, Y: 4}}}
+	//around the origin, which is at 10, 10
+	near := models.Zone{Name: "near", OriginRelative: true, Polygon: square(-1, -1, 2)}
+
+	tests := []struct {
+		name     string
+		zone     string
+		floor    *float64
+		expected []string
+	}{
+		{
+			name:     "Polygon",
+			zone:     "triangle",
+			expected: []string{"border", "inside", "upstairs"},
+		},
+		{
+			name:     "Polygon on a floor",
+			zone:     "triangle",
+			floor:    &ground,
+			expected: []string{"border", "inside"},
+		},
+		{
+			name:     "Upstairs",
+			zone:     "triangle",
+			floor:    &upstairs,
+			expected: []string{"upstairs"},
+		},
+		{
+			name:     "Origin relative",
+			zone:     "near",
+			expected: []string{"outside"},
+		},
+		{
+			name:     "Quadrant",
+			zone:     geometry.BottomLeft,
+			expected: []string{"behind", "border", "corner", "inside", "upstairs"},
+		},
+	}
+
+	for _, indexed := range []bool{false, true} {
+		ctx := context.Background()
+		store := db.NewMemoryStore()
+		spots := db.NewMemorySpotRepository(store)
+		for _, v := range stored {
+			_, err := spots.Create(ctx, v)
+			assert.NilError(t, err)
+		}
+		_, err := db.NewMemoryOriginRepository(store).Create(ctx, models.Origin{XOrigin: 10, YOrigin: 10})
+		assert.NilError(t, err)
+
+		var ix *index.Mazes
+		mode := "Database"
+		if indexed {
+			all, err := spots.List(ctx)
+			assert.NilError(t, err)
+			ix = index.NewMazes()
+			ix.Load(all)
+			mode = "Index"
+		}
+		s := New(log.NewNopLogger(), db.NewMemoryZoneRepository(store), spots, db.NewMemoryOriginRepository(store), ix, nil)
+		ids := map[string]string{geometry.BottomLeft: geometry.BottomLeft}
+		for _, z := range []models.Zone{triangle, near} {
+			id, err := s.CreateZone(ctx, z)
+			assert.NilError(t, err)
+			ids[z.Name] = id
+		}
+
+		for _, tt := range tests {
+			t.Run(mode+"/"+tt.name, func(t *testing.T) {
+				result, err := s.GetSpotsInZone(ctx, ids[tt.zone], tt.floor)
+				assert.NilError(t, err)
+				assert.DeepEqual(t, tt.expected, names(result))
+			})
+		}
+	}
+}
+
+func TestGetSingleSpotZones(t *testing.T) {
+
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	spots := db.NewMemorySpotRepository(store)
+	origins := db.NewMemoryOriginRepository(store)
+	zones := New(log.NewNopLogger(), db.NewMemoryZoneRepository(store), spots, origins, nil, nil)
+	spotService := spot.New(log.NewNopLogger(), spots, nil, "", zones, nil, nil, nil)
+
+	hall, err := zones.CreateZone(ctx, models.Zone{Name: "hall", Polygon: square(0, 0, 2)})
+	assert.NilError(t, err)
+	_, err = zones.CreateZone(ctx, models.Zone{Name: "far", Polygon: square(5, 5, 2)})
+	assert.NilError(t, err)
+	id, err := spotService.CreateSpot(ctx, models.Spot{Name: "a", XCoordinate: 1, YCoordinate: 1})
+	assert.NilError(t, err)
+
+	//without an origin only the zones that don't depend on it are looked at
+	result, err := spotService.GetSingleSpot(ctx, id)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{hall}, result.Zones)
+
+	_, err = origins.Create(ctx, models.Origin{})
+	assert.NilError(t, err)
+	result, err = spotService.GetSingleSpot(ctx, id)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{geometry.UpperRight, hall}, result.Zones)
+
+	//and a spot that doesn't exist is answered with its 404
+	missing := primitive.NewObjectID().Hex()
+	_, err = spotService.GetSingleSpot(ctx, missing)
+	assert.Equal(t, mazeerrors.NewNotFound("spot", missing), err)
+}
+
+func TestWithoutOrigin(t *testing.T) {
+
+	ctx := context.Background()
+	store := db.NewMemoryStore()
+	spots := db.NewMemorySpotRepository(store)
+	s := New(log.NewNopLogger(), db.NewMemoryZoneRepository(store), spots, db.NewMemoryOriginRepository(store), nil, nil)
+
+	relative, err := s.CreateZone(ctx, models.Zone{Name: "around", OriginRelative: true,
+		Polygon: []models.Point{{X: -1, Y: -1}, {X: 1, Y: -1}, {X: 1, Y: 1}, {X: -1, Y: 1}}})
+	assert.NilError(t, err)
+
+	//the quadrants and origin relative zones are answered with the origin's 404
+	for _, id := range []string{geometry.UpperRight, relative} {
+		_, err = s.GetSpotsInZone(ctx, id, nil)
+		assert.Equal(t, mazeerrors.NewNotFound("origin", ""), err)
+	}
+
+	//while the zones that don't depend on it are skipped
+	zones, err := s.GetZonesContainingSpot(ctx, models.Spot{Name: "a"})
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(zones))
+}